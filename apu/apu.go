@@ -0,0 +1,202 @@
+package apu
+
+import (
+	"fmt"
+	"github.com/tjarjoura/nes-emulator/region"
+	"github.com/tjarjoura/nes-emulator/types"
+)
+
+const (
+	APU_STATUS        uint16 = 0x4015
+	APU_FRAME_COUNTER uint16 = 0x4017
+)
+
+const (
+	STATUS_PULSE1   byte = 0x01
+	STATUS_PULSE2   byte = 0x02
+	STATUS_TRIANGLE byte = 0x04
+	STATUS_NOISE    byte = 0x08
+	STATUS_DMC      byte = 0x10
+	STATUS_DMC_IRQ  byte = 0x80
+)
+
+// Apu is the 2A03's audio processing unit. It's clocked once per CPU cycle
+// and holds the current level of every channel, ready to be mixed.
+type Apu struct {
+	pulse1, pulse2 pulse
+	triangle       triangle
+	noise          noise
+	dmc            dmc
+	expansion      types.ExpansionAudio
+
+	// Access to the CPU bus for the DMC's sample fetches
+	read  func(address uint16) byte
+	stall func(cycles int)
+
+	frame   frameCounter
+	sampler sampler
+	stems   []sampler // One per channel while stems are being recorded
+	muted   []bool
+
+	timing *region.Timing
+	cycles uint64
+}
+
+func NewApu() *Apu {
+	apu := &Apu{}
+	apu.pulse1.onesComplement = true
+	apu.noise.shift = 1
+	apu.dmc.bitsRemaining = 8
+	apu.dmc.bufferEmpty = true
+	apu.dmc.silence = true
+	apu.resizeChannels()
+	apu.SetRegion(region.NTSC)
+	return apu
+}
+
+// SetRegion selects the frame counter and noise timings of the NTSC, PAL or
+// Dendy APU.
+func (apu *Apu) SetRegion(region region.Region) {
+	apu.timing = region.Timing()
+	apu.noise.periods = &apu.timing.NoisePeriods
+	if apu.noise.timerPeriod == 0 {
+		apu.noise.timerPeriod = apu.noise.periods[0]
+	}
+
+	// Keep the sample rate, even if it's now above the CPU clock
+	apu.startSampling(apu.sampler.rate)
+
+	apu.dmc.rates = &apu.timing.DmcRates
+	if apu.dmc.timerPeriod == 0 {
+		apu.dmc.timerPeriod = apu.dmc.rates[0]
+	}
+}
+
+// ConnectMemory gives the DMC the CPU bus: read fetches sample bytes and
+// stall halts the CPU for the cycles each fetch takes.
+func (apu *Apu) ConnectMemory(read func(address uint16) byte, stall func(cycles int)) {
+	apu.read = read
+	apu.stall = stall
+}
+
+// SetExpansion mixes in a cartridge's sound chip, clocking it along with the
+// APU. nil removes it.
+func (apu *Apu) SetExpansion(expansion types.ExpansionAudio) {
+	apu.expansion = expansion
+	apu.resizeChannels()
+	if apu.stems != nil {
+		apu.SetStems(true)
+	}
+}
+
+// Irq reports whether the APU is holding the CPU's IRQ line low.
+func (apu *Apu) Irq() bool {
+	return apu.dmc.irq || apu.frame.irq
+}
+
+// ReadByte handles CPU reads of $4015, which acknowledges the frame
+// counter's IRQ. The other APU registers are write only.
+func (apu *Apu) ReadByte(address uint16) (byte, error) {
+	if address != APU_STATUS {
+		return 0x00, fmt.Errorf("Apu.ReadByte(): Unmapped memory address 0x%x.", address)
+	}
+
+	var status byte
+	if apu.pulse1.length.value > 0 {
+		status |= STATUS_PULSE1
+	}
+	if apu.pulse2.length.value > 0 {
+		status |= STATUS_PULSE2
+	}
+	if apu.triangle.length.value > 0 {
+		status |= STATUS_TRIANGLE
+	}
+	if apu.noise.length.value > 0 {
+		status |= STATUS_NOISE
+	}
+	if apu.dmc.bytesRemaining > 0 {
+		status |= STATUS_DMC
+	}
+	if apu.frame.irq {
+		status |= STATUS_FRAME_IRQ
+	}
+	if apu.dmc.irq {
+		status |= STATUS_DMC_IRQ
+	}
+
+	apu.frame.irq = false
+
+	return status, nil
+}
+
+// WriteByte handles CPU writes to $4000-$4013, $4015 and $4017.
+func (apu *Apu) WriteByte(address uint16, data byte) error {
+	switch {
+	case address >= 0x4000 && address <= 0x4003:
+		apu.pulse1.write(address-0x4000, data)
+	case address >= 0x4004 && address <= 0x4007:
+		apu.pulse2.write(address-0x4004, data)
+	case address >= 0x4008 && address <= 0x400B:
+		apu.triangle.write(address-0x4008, data)
+	case address >= 0x400C && address <= 0x400F:
+		apu.noise.write(address-0x400C, data)
+	case address >= 0x4010 && address <= 0x4013:
+		apu.dmc.write(address-0x4010, data)
+	case address == APU_STATUS:
+		apu.pulse1.length.setEnabled(data&STATUS_PULSE1 > 0)
+		apu.pulse2.length.setEnabled(data&STATUS_PULSE2 > 0)
+		apu.triangle.length.setEnabled(data&STATUS_TRIANGLE > 0)
+		apu.noise.length.setEnabled(data&STATUS_NOISE > 0)
+		apu.dmc.setEnabled(data&STATUS_DMC > 0)
+	case address == APU_FRAME_COUNTER:
+		apu.writeFrameCounter(data)
+	default:
+		return fmt.Errorf("Apu.WriteByte(): Unmapped memory address 0x%x.", address)
+	}
+
+	return nil
+}
+
+// Quarter frames clock the envelopes and the triangle's linear counter.
+func (apu *Apu) clockQuarterFrame() {
+	apu.pulse1.envelope.clock()
+	apu.pulse2.envelope.clock()
+	apu.noise.envelope.clock()
+	apu.triangle.clockLinearCounter()
+}
+
+// Half frames clock the length counters and sweeps.
+func (apu *Apu) clockHalfFrame() {
+	apu.pulse1.length.clock()
+	apu.pulse2.length.clock()
+	apu.triangle.length.clock()
+	apu.noise.length.clock()
+	apu.pulse1.clockSweep()
+	apu.pulse2.clockSweep()
+}
+
+// Clock advances the APU by a single CPU cycle.
+func (apu *Apu) Clock() {
+	apu.clockFrameCounter()
+
+	apu.triangle.clockTimer()
+	apu.noise.clockTimer()
+	apu.dmc.clockTimer()
+	apu.dmc.fetch(apu.read, apu.stall)
+	if apu.cycles%2 == 1 {
+		apu.pulse1.clockTimer()
+		apu.pulse2.clockTimer()
+	}
+	if apu.expansion != nil {
+		apu.expansion.Clock()
+	}
+
+	if apu.sampler.rate > 0 {
+		apu.sampler.add(apu.Output())
+		for channel := range apu.stems {
+			apu.stems[channel].add(apu.mix(channel))
+		}
+	}
+
+	apu.cycles++
+}
@@ -0,0 +1,39 @@
+package apu
+
+// BenchFrame retunes every channel, as a music driver would once a frame, so
+// that the resampler sees a busy signal, then runs the APU for a frame. vrc7
+// may be nil, and otherwise has to be the APU's expansion.
+func BenchFrame(apu *Apu, vrc7 *Vrc7, frame int) error {
+	writes := [][2]int{
+		{0x4015, 0x0F},
+		{0x4000, 0xBF}, {0x4002, frame * 7}, {0x4003, 0x01},
+		{0x4004, 0x7F}, {0x4005, 0x89}, {0x4006, frame * 13}, {0x4007, 0x00},
+		{0x4008, 0xFF}, {0x400A, frame * 5}, {0x400B, 0x00},
+		{0x400C, 0x3F}, {0x400E, frame & 0x8F}, {0x400F, 0x00},
+	}
+	for _, write := range writes {
+		err := apu.WriteByte(uint16(write[0]), byte(write[1]))
+		if err != nil {
+			return err
+		}
+	}
+
+	if vrc7 != nil {
+		for channel := 0; channel < VRC7_CHANNELS; channel++ {
+			registers := [][2]int{
+				{0x30 + channel, (channel + 1) << 4},
+				{0x10 + channel, frame*3 + channel*16},
+				{0x20 + channel, int(VRC7_KEY_ON) | 4<<1},
+			}
+			for _, register := range registers {
+				vrc7.WriteByte(VRC7_ADDRESS, byte(register[0]))
+				vrc7.WriteByte(VRC7_DATA, byte(register[1]))
+			}
+		}
+	}
+
+	for cycle := 0; cycle < apu.timing.FrameCycles(); cycle++ {
+		apu.Clock()
+	}
+	return nil
+}
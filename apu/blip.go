@@ -0,0 +1,89 @@
+package apu
+
+import "math"
+
+const (
+	BLIP_PHASES int     = 64 // Sub-sample positions a step can start at
+	BLIP_TAPS   int     = 16 // Output samples each step is spread over
+	BLIP_CUTOFF float64 = 0.45
+)
+
+// blipKernel holds, for each phase, a windowed sinc impulse sampled at the
+// output rate. Integrating it gives a band-limited step.
+var blipKernel = makeBlipKernel()
+
+func makeBlipKernel() [BLIP_PHASES + 1][BLIP_TAPS]float64 {
+	var kernel [BLIP_PHASES + 1][BLIP_TAPS]float64
+
+	for phase := 0; phase <= BLIP_PHASES; phase++ {
+		offset := float64(phase) / float64(BLIP_PHASES)
+
+		var sum float64
+		for tap := 0; tap < BLIP_TAPS; tap++ {
+			t := float64(tap-BLIP_TAPS/2+1) - offset
+			x := 2 * BLIP_CUTOFF * t
+
+			sinc := 1.0
+			if x != 0 {
+				sinc = math.Sin(math.Pi*x) / (math.Pi * x)
+			}
+
+			// Blackman window over the width of the kernel
+			w := (t + float64(BLIP_TAPS)/2) / float64(BLIP_TAPS)
+			window := 0.42 - 0.5*math.Cos(2*math.Pi*w) + 0.08*math.Cos(4*math.Pi*w)
+			if w < 0 || w > 1 {
+				window = 0
+			}
+
+			kernel[phase][tap] = sinc * window
+			sum += kernel[phase][tap]
+		}
+
+		// Every step must add up to exactly its height
+		for tap := range kernel[phase] {
+			kernel[phase][tap] /= sum
+		}
+	}
+
+	return kernel
+}
+
+// blipBuffer resamples a signal described by the times and sizes of its
+// steps, in the manner of Shay Green's blip_buf. Each step is drawn as a
+// band-limited impulse into a buffer of differences which is integrated as
+// samples are taken out, so there's no aliasing however fast the input is
+// clocked.
+type blipBuffer struct {
+	deltas     [BLIP_TAPS * 2]float64
+	time       float64 // Position of the next step, in output samples
+	integrator float64
+}
+
+func (blip *blipBuffer) addDelta(delta float64) {
+	whole := int(blip.time)
+	phase := int((blip.time-float64(whole))*float64(BLIP_PHASES) + 0.5)
+
+	for tap, weight := range blipKernel[phase] {
+		blip.deltas[whole+tap] += delta * weight
+	}
+}
+
+func (blip *blipBuffer) advance(samples float64) {
+	blip.time += samples
+}
+
+// ready returns whether an output sample is finished.
+func (blip *blipBuffer) ready() bool {
+	return blip.time >= 1
+}
+
+// readSample takes the next finished sample out of the buffer.
+func (blip *blipBuffer) readSample() float64 {
+	blip.integrator += blip.deltas[0]
+
+	copy(blip.deltas[:], blip.deltas[1:])
+	blip.deltas[len(blip.deltas)-1] = 0
+	blip.time--
+
+	return blip.integrator
+}
@@ -0,0 +1,124 @@
+package apu
+
+// CPU cycles lost to each DMC sample fetch. The real figure is 2-4 depending
+// on what the CPU was doing; 4 is the common case.
+const DMC_FETCH_STALL int = 4
+
+// dmc plays 1 bit delta encoded samples from CPU memory, nudging a 7 bit
+// output level up or down by two for every bit.
+type dmc struct {
+	rates      *[16]uint16 // In CPU cycles, from the region's timing
+	irqEnabled bool
+	loop       bool
+	irq        bool
+
+	timer, timerPeriod uint16
+	level              byte
+
+	sampleAddress, sampleLength uint16
+	address, bytesRemaining     uint16
+
+	// Memory reader
+	buffer      byte
+	bufferEmpty bool
+
+	// Output unit
+	shift, bitsRemaining byte
+	silence              bool
+}
+
+func (dmc *dmc) write(register uint16, data byte) {
+	switch register {
+	case 0:
+		dmc.irqEnabled = data&0x80 > 0
+		if !dmc.irqEnabled {
+			dmc.irq = false
+		}
+		dmc.loop = data&0x40 > 0
+		dmc.timerPeriod = dmc.rates[data&0x0F]
+	case 1:
+		dmc.level = data & 0x7F
+	case 2:
+		dmc.sampleAddress = 0xC000 + uint16(data)*64
+	case 3:
+		dmc.sampleLength = uint16(data)*16 + 1
+	}
+}
+
+func (dmc *dmc) restart() {
+	dmc.address = dmc.sampleAddress
+	dmc.bytesRemaining = dmc.sampleLength
+}
+
+// setEnabled handles bit 4 of $4015, which starts the sample if it has
+// finished or cuts it short.
+func (dmc *dmc) setEnabled(enabled bool) {
+	dmc.irq = false
+
+	if !enabled {
+		dmc.bytesRemaining = 0
+	} else if dmc.bytesRemaining == 0 {
+		dmc.restart()
+	}
+}
+
+// clockTimer runs every CPU cycle.
+func (dmc *dmc) clockTimer() {
+	if dmc.timer > 0 {
+		dmc.timer--
+		return
+	}
+	dmc.timer = dmc.timerPeriod - 1
+
+	if !dmc.silence {
+		if dmc.shift&0x01 > 0 {
+			if dmc.level <= 125 {
+				dmc.level += 2
+			}
+		} else if dmc.level >= 2 {
+			dmc.level -= 2
+		}
+	}
+	dmc.shift >>= 1
+
+	dmc.bitsRemaining--
+	if dmc.bitsRemaining == 0 {
+		dmc.bitsRemaining = 8
+		dmc.silence = dmc.bufferEmpty
+		if !dmc.bufferEmpty {
+			dmc.shift = dmc.buffer
+			dmc.bufferEmpty = true
+		}
+	}
+}
+
+// fetch refills the sample buffer over the CPU bus once it has been emptied.
+func (dmc *dmc) fetch(read func(address uint16) byte, stall func(cycles int)) {
+	if !dmc.bufferEmpty || dmc.bytesRemaining == 0 || read == nil {
+		return
+	}
+
+	stall(DMC_FETCH_STALL)
+	dmc.buffer = read(dmc.address)
+	dmc.bufferEmpty = false
+
+	// Samples wrap from the top of memory back to $8000
+	if dmc.address == 0xFFFF {
+		dmc.address = 0x8000
+	} else {
+		dmc.address++
+	}
+
+	dmc.bytesRemaining--
+	if dmc.bytesRemaining == 0 {
+		if dmc.loop {
+			dmc.restart()
+		} else if dmc.irqEnabled {
+			dmc.irq = true
+		}
+	}
+}
+
+func (dmc *dmc) output() byte {
+	return dmc.level
+}
@@ -0,0 +1,253 @@
+package apu
+
+import (
+	"fmt"
+	"math"
+)
+
+// The FDS at full volume is roughly 2.4 times as loud as an APU pulse, peak
+// to peak, before the master volume
+const FDS_LEVEL float64 = 2.4 * PULSE_LEVEL / float64(63*FDS_MAX_GAIN)
+
+const (
+	FDS_MAX_GAIN       int     = 32
+	FDS_LOW_PASS_HZ    float64 = 2000 // The RAM adapter's output filter
+	FDS_ENVELOPE_CLOCK int     = 8    // CPU cycles per envelope tick, before the speeds
+)
+
+// Register bits
+const (
+	FDS_ENVELOPE_OFF      byte = 0x80 // $4080 and $4084: set the gain directly
+	FDS_ENVELOPE_INCREASE byte = 0x40
+	FDS_WAVE_HALT         byte = 0x80 // $4083
+	FDS_ENVELOPES_HALT    byte = 0x40
+	FDS_MOD_HALT          byte = 0x80 // $4087
+	FDS_WAVE_WRITE        byte = 0x80 // $4089
+)
+
+// Master volume is 2/2, 2/3, 2/4 or 2/5, in 30ths
+var fdsMasterVolumes = [4]int{30, 20, 15, 12}
+
+// Modulation table entries move the modulation counter by these amounts, or
+// reset it for FDS_MOD_RESET
+var fdsModSteps = [8]int{0, 1, 2, 4, 0, -4, -2, -1}
+
+const FDS_MOD_RESET byte = 4
+
+type fdsEnvelope struct {
+	off, increase bool
+	speed         byte
+	gain          int
+	counter       int
+}
+
+func (env *fdsEnvelope) write(data byte) {
+	env.off = data&FDS_ENVELOPE_OFF > 0
+	env.increase = data&FDS_ENVELOPE_INCREASE > 0
+	env.speed = data & 0x3F
+	if env.off {
+		env.gain = int(data & 0x3F)
+	}
+}
+
+// clock moves the gain one step towards 0 or 32 every 8 * master speed *
+// (speed + 1) cycles.
+func (env *fdsEnvelope) clock(masterSpeed byte) {
+	if env.off || masterSpeed == 0 {
+		return
+	}
+
+	env.counter++
+	if env.counter < FDS_ENVELOPE_CLOCK*int(masterSpeed)*(int(env.speed)+1) {
+		return
+	}
+	env.counter = 0
+
+	if env.increase && env.gain < FDS_MAX_GAIN {
+		env.gain++
+	} else if !env.increase && env.gain > 0 {
+		env.gain--
+	}
+}
+
+// Fds is the sound half of the Famicom Disk System's RAM adapter: a single
+// channel playing a 64 step, 6 bit wavetable, with its pitch bent by a second
+// table through a modulator. Registers are at $4040-$4092.
+type Fds struct {
+	wave      [64]byte
+	waveWrite bool
+	waveHalt  bool
+	envHalt   bool
+
+	frequency    uint16
+	waveAccum    uint32 // Position in the wave in the top 6 of 22 bits
+	volume, mod  fdsEnvelope
+	masterVolume byte
+	masterSpeed  byte
+
+	modTable     [64]byte
+	modPosition  byte
+	modCounter   int // 7 bit signed
+	modFrequency uint16
+	modHalt      bool
+	modAccum     uint16
+
+	level   float64 // Output after the low pass filter
+	sample  int     // Last sample taken from the wave, held while writing it
+	lowPass float64 // Filter coefficient per CPU cycle
+}
+
+func NewFds(cpuClockRate float64) *Fds {
+	return &Fds{
+		masterSpeed: 0xE8,
+		waveHalt:    true,
+		modHalt:     true,
+		lowPass:     1 - math.Exp(-2*math.Pi*FDS_LOW_PASS_HZ/cpuClockRate),
+	}
+}
+
+func (fds *Fds) ReadByte(address uint16) (byte, error) {
+	switch {
+	case address >= 0x4040 && address <= 0x407F:
+		return fds.wave[address-0x4040], nil
+	case address == 0x4090:
+		return byte(fds.volume.gain), nil
+	case address == 0x4092:
+		return byte(fds.mod.gain), nil
+	default:
+		return 0x00, fmt.Errorf("Fds.ReadByte(): Unmapped memory address 0x%x.", address)
+	}
+}
+
+func (fds *Fds) WriteByte(address uint16, data byte) error {
+	switch {
+	case address >= 0x4040 && address <= 0x407F:
+		if fds.waveWrite {
+			fds.wave[address-0x4040] = data & 0x3F
+		}
+	case address == 0x4080:
+		fds.volume.write(data)
+	case address == 0x4082:
+		fds.frequency = fds.frequency&0x0F00 | uint16(data)
+	case address == 0x4083:
+		fds.frequency = fds.frequency&0x00FF | uint16(data&0x0F)<<8
+		fds.waveHalt = data&FDS_WAVE_HALT > 0
+		fds.envHalt = data&FDS_ENVELOPES_HALT > 0
+		if fds.waveHalt {
+			fds.waveAccum = 0
+		}
+	case address == 0x4084:
+		fds.mod.write(data)
+	case address == 0x4085:
+		fds.modCounter = int(int8(data<<1)) >> 1
+	case address == 0x4086:
+		fds.modFrequency = fds.modFrequency&0x0F00 | uint16(data)
+	case address == 0x4087:
+		fds.modFrequency = fds.modFrequency&0x00FF | uint16(data&0x0F)<<8
+		fds.modHalt = data&FDS_MOD_HALT > 0
+		if fds.modHalt {
+			fds.modAccum = 0
+		}
+	case address == 0x4088:
+		// The table is written two entries at a time, only while halted
+		if fds.modHalt {
+			fds.modTable[fds.modPosition] = data & 0x07
+			fds.modTable[fds.modPosition+1] = data & 0x07
+			fds.modPosition = (fds.modPosition + 2) & 0x3F
+		}
+	case address == 0x4089:
+		fds.waveWrite = data&FDS_WAVE_WRITE > 0
+		fds.masterVolume = data & 0x03
+	case address == 0x408A:
+		fds.masterSpeed = data
+	}
+
+	return nil
+}
+
+// pitch bends the wave's frequency by the modulation counter times the
+// modulator's gain, with the rounding the hardware does.
+func (fds *Fds) pitch() int {
+	temp := fds.modCounter * fds.mod.gain
+	remainder := temp & 0x0F
+	temp >>= 4
+	if remainder > 0 && temp&0x80 == 0 {
+		if fds.modCounter < 0 {
+			temp--
+		} else {
+			temp += 2
+		}
+	}
+
+	if temp >= 192 {
+		temp -= 256
+	} else if temp < -64 {
+		temp += 256
+	}
+
+	temp *= int(fds.frequency)
+	remainder = temp & 0x3F
+	temp >>= 6
+	if remainder >= 32 {
+		temp++
+	}
+
+	return int(fds.frequency) + temp
+}
+
+func (fds *Fds) clockModulator() {
+	if fds.modHalt || fds.modFrequency == 0 {
+		return
+	}
+
+	accum := uint32(fds.modAccum) + uint32(fds.modFrequency)
+	fds.modAccum = uint16(accum)
+	if accum <= 0xFFFF {
+		return
+	}
+
+	step := fds.modTable[fds.modPosition]
+	if step == FDS_MOD_RESET {
+		fds.modCounter = 0
+	} else {
+		fds.modCounter += fdsModSteps[step]
+		fds.modCounter = (fds.modCounter+64)&0x7F - 64
+	}
+	fds.modPosition = (fds.modPosition + 1) & 0x3F
+}
+
+func (fds *Fds) Clock() {
+	if !fds.envHalt && !fds.waveHalt {
+		fds.volume.clock(fds.masterSpeed)
+		fds.mod.clock(fds.masterSpeed)
+	}
+
+	fds.clockModulator()
+
+	if !fds.waveHalt {
+		if pitch := fds.pitch(); pitch > 0 {
+			fds.waveAccum = (fds.waveAccum + uint32(pitch)) & 0x3FFFFF
+		}
+	}
+
+	// The DAC holds its last sample while the wave is being written
+	if !fds.waveWrite {
+		gain := fds.volume.gain
+		if gain > FDS_MAX_GAIN {
+			gain = FDS_MAX_GAIN
+		}
+		fds.sample = int(fds.wave[fds.waveAccum>>16]) * gain * fdsMasterVolumes[fds.masterVolume] / 30
+	}
+
+	fds.level += (float64(fds.sample) - fds.level) * fds.lowPass
+}
+
+var fdsChannels = []string{"fds"}
+
+func (fds *Fds) Channels() []string {
+	return fdsChannels
+}
+
+func (fds *Fds) ChannelOutput(channel int) float64 {
+	return fds.level * FDS_LEVEL
+}
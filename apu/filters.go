@@ -0,0 +1,44 @@
+package apu
+
+import "math"
+
+// The NES's output stage: two first-order high-pass filters, which take out
+// the mixer's DC offset, followed by a first-order low-pass.
+const (
+	HIGH_PASS_1_HZ float64 = 90
+	HIGH_PASS_2_HZ float64 = 440
+	LOW_PASS_HZ    float64 = 14000
+)
+
+type highPass struct {
+	alpha           float64
+	lastIn, lastOut float64
+}
+
+func newHighPass(cutoff float64, sampleRate int) highPass {
+	rc := 1 / (2 * math.Pi * cutoff)
+	dt := 1 / float64(sampleRate)
+	return highPass{alpha: rc / (rc + dt)}
+}
+
+func (filter *highPass) apply(in float64) float64 {
+	filter.lastOut = filter.alpha * (filter.lastOut + in - filter.lastIn)
+	filter.lastIn = in
+	return filter.lastOut
+}
+
+type lowPass struct {
+	alpha   float64
+	lastOut float64
+}
+
+func newLowPass(cutoff float64, sampleRate int) lowPass {
+	rc := 1 / (2 * math.Pi * cutoff)
+	dt := 1 / float64(sampleRate)
+	return lowPass{alpha: dt / (rc + dt)}
+}
+
+func (filter *lowPass) apply(in float64) float64 {
+	filter.lastOut += filter.alpha * (in - filter.lastOut)
+	return filter.lastOut
+}
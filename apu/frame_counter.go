@@ -0,0 +1,76 @@
+package apu
+
+const (
+	FRAME_COUNTER_IRQ_INHIBIT byte = 0x40
+	FRAME_COUNTER_FIVE_STEP   byte = 0x80
+	STATUS_FRAME_IRQ          byte = 0x40
+)
+
+// frameCounter is the sequencer behind the quarter and half frame clocks,
+// stepping roughly four times per video frame. In 4-step mode it also raises
+// an IRQ at the end of each sequence.
+type frameCounter struct {
+	cycle      int // CPU cycles into the sequence
+	fiveStep   bool
+	irqInhibit bool
+	irq        bool
+
+	// A $4017 write restarts the sequence 3 or 4 CPU cycles later,
+	// depending on whether it lands on an APU cycle
+	resetDelay int
+}
+
+func (apu *Apu) writeFrameCounter(data byte) {
+	apu.frame.fiveStep = data&FRAME_COUNTER_FIVE_STEP > 0
+	apu.frame.irqInhibit = data&FRAME_COUNTER_IRQ_INHIBIT > 0
+	if apu.frame.irqInhibit {
+		apu.frame.irq = false
+	}
+
+	if apu.cycles%2 == 0 {
+		apu.frame.resetDelay = 3
+	} else {
+		apu.frame.resetDelay = 4
+	}
+}
+
+// clockFrameCounter runs every CPU cycle.
+func (apu *Apu) clockFrameCounter() {
+	if apu.frame.resetDelay > 0 {
+		apu.frame.resetDelay--
+		if apu.frame.resetDelay == 0 {
+			apu.frame.cycle = 0
+
+			// Switching to 5-step mode clocks everything straight away
+			if apu.frame.fiveStep {
+				apu.clockQuarterFrame()
+				apu.clockHalfFrame()
+			}
+		}
+	}
+
+	mode := 0
+	if apu.frame.fiveStep {
+		mode = 1
+	}
+	steps := apu.timing.FrameCounterSteps[mode]
+
+	apu.frame.cycle++
+	switch apu.frame.cycle {
+	case steps[0], steps[2]:
+		apu.clockQuarterFrame()
+	case steps[1], steps[4]:
+		apu.clockQuarterFrame()
+		apu.clockHalfFrame()
+	}
+
+	// The IRQ flag is set on the three cycles around the end of the 4-step
+	// sequence, so clearing it on the first still leaves it set
+	if !apu.frame.fiveStep && !apu.frame.irqInhibit && apu.frame.cycle >= steps[3] && apu.frame.cycle <= steps[5] {
+		apu.frame.irq = true
+	}
+
+	if apu.frame.cycle == steps[5] {
+		apu.frame.cycle = 0
+	}
+}
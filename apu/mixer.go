@@ -0,0 +1,139 @@
+package apu
+
+import "fmt"
+
+// PULSE_LEVEL is the mixer output of a single pulse channel at full volume.
+// Expansion chips are levelled against it.
+const PULSE_LEVEL float64 = 95.88 / (8128.0/15 + 100)
+
+// The APU's own channels come first, followed by any expansion chip's
+const (
+	CHANNEL_PULSE1 int = iota
+	CHANNEL_PULSE2
+	CHANNEL_TRIANGLE
+	CHANNEL_NOISE
+	CHANNEL_DMC
+	APU_CHANNELS
+)
+
+var apuChannels = []string{"pulse1", "pulse2", "triangle", "noise", "dmc"}
+
+// Channels names every channel that can be muted or written to a stem: the
+// APU's five, then the expansion chip's.
+func (apu *Apu) Channels() []string {
+	channels := append([]string{}, apuChannels...)
+	if apu.expansion != nil {
+		channels = append(channels, apu.expansion.Channels()...)
+	}
+	return channels
+}
+
+func (apu *Apu) channelIndex(name string) (int, bool) {
+	for i, channel := range apu.Channels() {
+		if channel == name {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// SetMuted takes a channel out of the mixed output, or puts it back.
+func (apu *Apu) SetMuted(name string, muted bool) error {
+	channel, ok := apu.channelIndex(name)
+	if !ok {
+		return fmt.Errorf("Apu.SetMuted(): Unknown channel %q.", name)
+	}
+
+	apu.muted[channel] = muted
+	return nil
+}
+
+func (apu *Apu) Muted(name string) bool {
+	channel, ok := apu.channelIndex(name)
+	return ok && apu.muted[channel]
+}
+
+// Solo mutes every channel except the named ones.
+func (apu *Apu) Solo(names ...string) error {
+	soloed := make(map[int]bool)
+	for _, name := range names {
+		channel, ok := apu.channelIndex(name)
+		if !ok {
+			return fmt.Errorf("Apu.Solo(): Unknown channel %q.", name)
+		}
+		soloed[channel] = true
+	}
+
+	for channel := range apu.muted {
+		apu.muted[channel] = !soloed[channel]
+	}
+	return nil
+}
+
+// resizeChannels keeps the mute settings of the APU's channels when an
+// expansion chip comes or goes, and unmutes the chip's.
+func (apu *Apu) resizeChannels() {
+	muted := make([]bool, len(apu.Channels()))
+	copy(muted[:APU_CHANNELS], apu.muted)
+	apu.muted = muted
+}
+
+// Output mixes the unmuted channels.
+func (apu *Apu) Output() float64 {
+	return apu.mix(-1)
+}
+
+// mixLevels mixes the DAC levels of the APU's channels the way the 2A03's
+// resistor network does, which isn't linear: loud channels take the edge off
+// each other. pulses is the sum of both pulses. The result is between 0 and
+// about 1.
+func mixLevels(pulses float64, triangle float64, noise float64, dmc float64) float64 {
+	var output float64
+	if pulses > 0 {
+		output += 95.88 / (8128/pulses + 100)
+	}
+	if tnd := triangle/8227 + noise/12241 + dmc/22638; tnd > 0 {
+		output += 159.79 / (1/tnd + 100)
+	}
+	return output
+}
+
+// mix mixes the channels through mixLevels, plus whatever an expansion chip
+// adds. With only set to a channel, that channel is mixed on its own, mute
+// or not; otherwise every unmuted channel is.
+func (apu *Apu) mix(only int) float64 {
+	audible := func(channel int) bool {
+		if only >= 0 {
+			return channel == only
+		}
+		return !apu.muted[channel]
+	}
+
+	var pulses, triangle, noise, dmc float64
+	if audible(CHANNEL_PULSE1) {
+		pulses += float64(apu.pulse1.output())
+	}
+	if audible(CHANNEL_PULSE2) {
+		pulses += float64(apu.pulse2.output())
+	}
+	if audible(CHANNEL_TRIANGLE) {
+		triangle = float64(apu.triangle.output())
+	}
+	if audible(CHANNEL_NOISE) {
+		noise = float64(apu.noise.output())
+	}
+	if audible(CHANNEL_DMC) {
+		dmc = float64(apu.dmc.output())
+	}
+
+	output := mixLevels(pulses, triangle, noise, dmc)
+
+	// Expansion chips are mixed linearly
+	for channel := APU_CHANNELS; channel < len(apu.muted); channel++ {
+		if audible(channel) {
+			output += apu.expansion.ChannelOutput(channel - APU_CHANNELS)
+		}
+	}
+
+	return output
+}
@@ -0,0 +1,131 @@
+package apu
+
+import (
+	"math"
+	"testing"
+)
+
+func TestMixLevels(t *testing.T) {
+	tests := []struct {
+		pulses, triangle, noise, dmc float64
+		want                         float64
+	}{
+		{0, 0, 0, 0, 0},
+		{1, 0, 0, 0, 0.01165},
+		{15, 0, 0, 0, 0.14938},
+		{30, 0, 0, 0, 0.25848}, // Two pulses at full volume come to less than twice one
+		{0, 15, 0, 0, 0.24641},
+		{0, 0, 15, 0, 0.17443},
+		{0, 0, 0, 127, 0.57426},
+		{0, 15, 15, 127, 0.74152},
+		{30, 15, 15, 127, 1.0},
+	}
+
+	for _, test := range tests {
+		got := mixLevels(test.pulses, test.triangle, test.noise, test.dmc)
+		if math.Abs(got-test.want) > 0.00001 {
+			t.Errorf("mixLevels(%g, %g, %g, %g) = %.5f, want %.5f", test.pulses, test.triangle, test.noise, test.dmc, got, test.want)
+		}
+	}
+
+	if got := mixLevels(15, 0, 0, 0); got != PULSE_LEVEL {
+		t.Errorf("A pulse at full volume mixes to %g, want PULSE_LEVEL %g", got, PULSE_LEVEL)
+	}
+}
+
+// runFrames plays frames of BenchFrame and returns the mixed samples and
+// each channel's stem, checking that every call hands back as many samples
+// for the stems as for the mix.
+func runFrames(t *testing.T, apu *Apu, vrc7 *Vrc7, frames int) ([]int16, [][]int16) {
+	var mixed []int16
+	stems := make([][]int16, len(apu.Channels()))
+
+	for frame := 0; frame < frames; frame++ {
+		BenchFrame(apu, vrc7, frame)
+
+		samples := apu.Samples()
+		mixed = append(mixed, samples...)
+		for channel, stem := range apu.StemSamples() {
+			if len(stem) != len(samples) {
+				t.Fatalf("Frame %d: %d samples for %s, %d mixed", frame, len(stem), apu.Channels()[channel], len(samples))
+			}
+			stems[channel] = append(stems[channel], stem...)
+		}
+	}
+
+	return mixed, stems
+}
+
+func TestStemsAligned(t *testing.T) {
+	// Stems started partway through keep in step with the mix
+	apu := NewApu()
+	vrc7 := NewVrc7()
+	apu.SetExpansion(vrc7)
+	apu.SetSampleRate(44100)
+	runFrames(t, apu, vrc7, 3)
+	apu.SetStems(true)
+	runFrames(t, apu, vrc7, 10)
+
+	// A soloed channel mixes to exactly its own stem
+	for _, channel := range []string{"pulse1", "triangle", "vrc7.1"} {
+		apu := NewApu()
+		vrc7 := NewVrc7()
+		apu.SetExpansion(vrc7)
+		apu.SetSampleRate(44100)
+		apu.SetStems(true)
+		apu.Solo(channel)
+
+		mixed, stems := runFrames(t, apu, vrc7, 10)
+		index, _ := apu.channelIndex(channel)
+		for i := range mixed {
+			if mixed[i] != stems[index][i] {
+				t.Errorf("Sample %d mixed with %s soloed = %d, stem = %d", i, channel, mixed[i], stems[index][i])
+				break
+			}
+		}
+	}
+}
+
+func TestMuteAndSolo(t *testing.T) {
+	apu := NewApu()
+	BenchFrame(apu, nil, 1)
+	pulse1, triangle := apu.mix(CHANNEL_PULSE1), apu.mix(CHANNEL_TRIANGLE)
+	if pulse1 == 0 || triangle == 0 {
+		t.Fatalf("Pulse 1 at %g and triangle at %g, want both playing", pulse1, triangle)
+	}
+
+	all := apu.Output()
+	apu.SetMuted("pulse1", true)
+	if got := apu.Output(); got == all {
+		t.Errorf("Muting pulse1 left the mix at %g", got)
+	}
+	if !apu.Muted("pulse1") || apu.Muted("triangle") {
+		t.Errorf("Muted(pulse1) = %t and Muted(triangle) = %t, want true and false", apu.Muted("pulse1"), apu.Muted("triangle"))
+	}
+
+	if err := apu.Solo("triangle"); err != nil {
+		t.Fatal(err)
+	}
+	if got := apu.Output(); got != triangle {
+		t.Errorf("Mix with triangle soloed = %g, want %g", got, triangle)
+	}
+
+	if err := apu.Solo("pulse1", "triangle"); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := apu.Output(), mixLevels(float64(apu.pulse1.output()), float64(apu.triangle.output()), 0, 0); got != want {
+		t.Errorf("Mix with pulse1 and triangle soloed = %g, want %g", got, want)
+	}
+
+	if err := apu.Solo("triangle", "bongos"); err == nil {
+		t.Error("Solo(bongos) succeeded")
+	}
+	if apu.Muted("pulse1") {
+		t.Error("A failed Solo changed the mutes")
+	}
+
+	apu.Solo()
+	if got := apu.Output(); got != 0 {
+		t.Errorf("Mix with nothing soloed = %g, want 0", got)
+	}
+}
@@ -0,0 +1,117 @@
+package apu
+
+import "fmt"
+
+// The MMC5's pulses match the APU's in level but are mixed linearly, and
+// its PCM channel spans roughly the DMC's range.
+const (
+	MMC5_PULSE_LEVEL float64 = PULSE_LEVEL / 15
+	MMC5_PCM_LEVEL   float64 = 0.0025
+)
+
+// The MMC5 clocks its envelopes and length counters at a fixed 240Hz, not
+// from the APU's frame counter
+const MMC5_FRAME_CYCLES int = 7457
+
+const (
+	MMC5_STATUS      uint16 = 0x5015
+	MMC5_PCM_CONTROL uint16 = 0x5010
+	MMC5_PCM_RAW     uint16 = 0x5011
+)
+
+const MMC5_PCM_READ_MODE byte = 0x01
+
+// Mmc5Audio is the sound half of Nintendo's MMC5: two APU pulses without
+// sweeps at $5000-$5007, and an 8 bit PCM channel written through $5011.
+// There is no MMC5 board (mapper 5) yet, so it is only used by NSFs.
+type Mmc5Audio struct {
+	pulse1, pulse2 pulse
+	pcm            byte
+	pcmReadMode    bool
+
+	frameTimer int
+	cycles     uint64
+}
+
+func NewMmc5Audio() *Mmc5Audio {
+	mmc5 := &Mmc5Audio{frameTimer: MMC5_FRAME_CYCLES}
+	mmc5.pulse1.noSweep = true
+	mmc5.pulse2.noSweep = true
+	return mmc5
+}
+
+func (mmc5 *Mmc5Audio) ReadByte(address uint16) (byte, error) {
+	switch address {
+	case MMC5_STATUS:
+		var status byte
+		if mmc5.pulse1.length.value > 0 {
+			status |= STATUS_PULSE1
+		}
+		if mmc5.pulse2.length.value > 0 {
+			status |= STATUS_PULSE2
+		}
+		return status, nil
+	case MMC5_PCM_CONTROL:
+		// The IRQ only fires in read mode, where samples come from reads of
+		// $8000-$BFFF, which isn't emulated
+		return 0x00, nil
+	default:
+		return 0x00, fmt.Errorf("Mmc5Audio.ReadByte(): Unmapped memory address 0x%x.", address)
+	}
+}
+
+func (mmc5 *Mmc5Audio) WriteByte(address uint16, data byte) error {
+	switch {
+	case address >= 0x5000 && address <= 0x5003:
+		mmc5.pulse1.write(address-0x5000, data)
+	case address >= 0x5004 && address <= 0x5007:
+		mmc5.pulse2.write(address-0x5004, data)
+	case address == MMC5_PCM_CONTROL:
+		mmc5.pcmReadMode = data&MMC5_PCM_READ_MODE > 0
+	case address == MMC5_PCM_RAW:
+		// Writes of zero are ignored, as zero marks the end of a sample in
+		// read mode
+		if !mmc5.pcmReadMode && data != 0 {
+			mmc5.pcm = data
+		}
+	case address == MMC5_STATUS:
+		mmc5.pulse1.length.setEnabled(data&STATUS_PULSE1 > 0)
+		mmc5.pulse2.length.setEnabled(data&STATUS_PULSE2 > 0)
+	}
+
+	return nil
+}
+
+func (mmc5 *Mmc5Audio) Clock() {
+	mmc5.frameTimer--
+	if mmc5.frameTimer == 0 {
+		mmc5.frameTimer = MMC5_FRAME_CYCLES
+		mmc5.pulse1.envelope.clock()
+		mmc5.pulse2.envelope.clock()
+		mmc5.pulse1.length.clock()
+		mmc5.pulse2.length.clock()
+	}
+
+	if mmc5.cycles%2 == 1 {
+		mmc5.pulse1.clockTimer()
+		mmc5.pulse2.clockTimer()
+	}
+	mmc5.cycles++
+}
+
+var mmc5Channels = []string{"mmc5.pulse1", "mmc5.pulse2", "mmc5.pcm"}
+
+func (mmc5 *Mmc5Audio) Channels() []string {
+	return mmc5Channels
+}
+
+func (mmc5 *Mmc5Audio) ChannelOutput(channel int) float64 {
+	switch channel {
+	case 0:
+		return float64(mmc5.pulse1.output()) * MMC5_PULSE_LEVEL
+	case 1:
+		return float64(mmc5.pulse2.output()) * MMC5_PULSE_LEVEL
+	default:
+		return float64(mmc5.pcm) * MMC5_PCM_LEVEL
+	}
+}
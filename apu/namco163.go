@@ -0,0 +1,123 @@
+package apu
+
+import "fmt"
+
+// A single channel playing a full scale wave at full volume is roughly six
+// times as loud as an APU pulse. The real figure varies from board to board
+// with the mixing resistor.
+const N163_LEVEL float64 = 6 * PULSE_LEVEL / 225
+
+const (
+	N163_CHANNEL_CYCLES int  = 15 // CPU cycles spent on each channel in turn
+	N163_AUTO_INCREMENT byte = 0x80
+	N163_SOUND_DISABLE  byte = 0x40 // In the $E000 register
+)
+
+// Namco163 is the sound half of Namco's 163: up to eight wavetable channels
+// playing 4 bit samples from 128 bytes of RAM, which also holds each
+// channel's registers from $40. The chip has a single DAC and updates one
+// channel every 15 cycles, outputting it until the next, so the more channels
+// are enabled the quieter and the more audibly multiplexed each one is.
+//
+// The RAM is reached through an address port at $F800-$FFFF and a data port
+// at $4800-$4FFF. The Namco 163 board (mapper 19) isn't emulated, so this is
+// for NSFs.
+type Namco163 struct {
+	ram           [128]byte
+	address       byte
+	autoIncrement bool
+	disabled      bool
+
+	timer   int
+	channel int // The channel being output
+	level   int
+}
+
+func NewNamco163() *Namco163 {
+	return &Namco163{timer: N163_CHANNEL_CYCLES}
+}
+
+// enabledChannels is read from the top of RAM. Channels are updated from 7
+// downwards, so with n enabled they're 8-n to 7.
+func (n163 *Namco163) enabledChannels() int {
+	return int(n163.ram[0x7F]>>4&0x07) + 1
+}
+
+func (n163 *Namco163) dataPort() *byte {
+	data := &n163.ram[n163.address]
+	if n163.autoIncrement {
+		n163.address = (n163.address + 1) & 0x7F
+	}
+	return data
+}
+
+func (n163 *Namco163) ReadByte(address uint16) (byte, error) {
+	if address >= 0x4800 && address <= 0x4FFF {
+		return *n163.dataPort(), nil
+	}
+	return 0x00, fmt.Errorf("Namco163.ReadByte(): Unmapped memory address 0x%x.", address)
+}
+
+func (n163 *Namco163) WriteByte(address uint16, data byte) error {
+	switch {
+	case address >= 0x4800 && address <= 0x4FFF:
+		*n163.dataPort() = data
+	case address >= 0xE000 && address <= 0xE7FF:
+		n163.disabled = data&N163_SOUND_DISABLE > 0
+	case address >= 0xF800:
+		n163.address = data & 0x7F
+		n163.autoIncrement = data&N163_AUTO_INCREMENT > 0
+	}
+
+	return nil
+}
+
+// update advances a channel's phase and samples its wave. The registers are
+// an 18 bit frequency and 24 bit phase spread over the channel's eight
+// bytes, with the wave's length and offset in 4 bit samples and a volume.
+func (n163 *Namco163) update(channel int) {
+	registers := n163.ram[0x40+channel*8 : 0x48+channel*8]
+
+	frequency := int(registers[0]) | int(registers[2])<<8 | int(registers[4]&0x03)<<16
+	phase := int(registers[1]) | int(registers[3])<<8 | int(registers[5])<<16
+	length := 256 - int(registers[4]&0xFC)
+
+	phase = (phase + frequency) % (length << 16)
+	registers[1], registers[3], registers[5] = byte(phase), byte(phase>>8), byte(phase>>16)
+
+	index := (phase>>16 + int(registers[6])) & 0xFF
+	sample := int(n163.ram[index>>1] >> (4 * (index & 0x01)) & 0x0F)
+
+	n163.level = (sample - 8) * int(registers[7]&0x0F)
+}
+
+func (n163 *Namco163) Clock() {
+	n163.timer--
+	if n163.timer > 0 {
+		return
+	}
+	n163.timer = N163_CHANNEL_CYCLES
+
+	n163.channel--
+	if n163.channel < 8-n163.enabledChannels() {
+		n163.channel = 7
+	}
+
+	if !n163.disabled {
+		n163.update(n163.channel)
+	}
+}
+
+var n163Channels = []string{"n163.1", "n163.2", "n163.3", "n163.4", "n163.5", "n163.6", "n163.7", "n163.8"}
+
+func (n163 *Namco163) Channels() []string {
+	return n163Channels
+}
+
+// ChannelOutput is zero for every channel but the one currently on the DAC.
+func (n163 *Namco163) ChannelOutput(channel int) float64 {
+	if n163.disabled || channel != n163.channel {
+		return 0
+	}
+	return float64(n163.level) * N163_LEVEL
+}
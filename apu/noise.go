@@ -0,0 +1,51 @@
+package apu
+
+// noise outputs bit 0 of a 15 bit linear feedback shift register. In short
+// mode the feedback taps bit 6 instead of bit 1, for a metallic 93 step loop.
+type noise struct {
+	envelope envelope
+	length   lengthCounter
+
+	periods            *[16]uint16 // In CPU cycles, from the region's timing
+	shortMode          bool
+	timer, timerPeriod uint16
+	shift              uint16
+}
+
+func (noise *noise) write(register uint16, data byte) {
+	switch register {
+	case 0:
+		noise.length.halt = data&0x20 > 0
+		noise.envelope.write(data)
+	case 2:
+		noise.shortMode = data&0x80 > 0
+		noise.timerPeriod = noise.periods[data&0x0F]
+	case 3:
+		noise.length.load(data >> 3)
+		noise.envelope.start = true
+	}
+}
+
+// clockTimer runs every CPU cycle.
+func (noise *noise) clockTimer() {
+	if noise.timer > 0 {
+		noise.timer--
+		return
+	}
+
+	noise.timer = noise.timerPeriod - 1
+
+	tap := uint(1)
+	if noise.shortMode {
+		tap = 6
+	}
+	feedback := (noise.shift ^ noise.shift>>tap) & 0x01
+	noise.shift = noise.shift>>1 | feedback<<14
+}
+
+func (noise *noise) output() byte {
+	if noise.shift&0x01 > 0 || noise.length.value == 0 {
+		return 0
+	}
+	return noise.envelope.output()
+}
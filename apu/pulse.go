@@ -0,0 +1,106 @@
+package apu
+
+var dutyTable = [4][8]byte{
+	{0, 1, 0, 0, 0, 0, 0, 0}, // 12.5%
+	{0, 1, 1, 0, 0, 0, 0, 0}, // 25%
+	{0, 1, 1, 1, 1, 0, 0, 0}, // 50%
+	{1, 0, 0, 1, 1, 1, 1, 1}, // 25% negated
+}
+
+type pulse struct {
+	envelope envelope
+	length   lengthCounter
+
+	// Pulse 1 negates its sweep with the ones' complement, pulse 2 with the
+	// two's complement, so pulse 1 sweeps down one further
+	onesComplement bool
+
+	// The MMC5's pulses have no sweep unit, so nothing mutes them
+	noSweep bool
+
+	duty, sequence     byte
+	timer, timerPeriod uint16
+
+	sweepEnabled, sweepNegate, sweepReload bool
+	sweepPeriod, sweepShift, sweepDivider  byte
+}
+
+func (pulse *pulse) write(register uint16, data byte) {
+	switch register {
+	case 0:
+		pulse.duty = data >> 6
+		pulse.length.halt = data&0x20 > 0
+		pulse.envelope.write(data)
+	case 1:
+		pulse.sweepEnabled = data&0x80 > 0
+		pulse.sweepPeriod = (data >> 4) & 0x07
+		pulse.sweepNegate = data&0x08 > 0
+		pulse.sweepShift = data & 0x07
+		pulse.sweepReload = true
+	case 2:
+		pulse.timerPeriod = pulse.timerPeriod&0x0700 | uint16(data)
+	case 3:
+		pulse.timerPeriod = pulse.timerPeriod&0x00FF | uint16(data&0x07)<<8
+		pulse.length.load(data >> 3)
+		pulse.sequence = 0
+		pulse.envelope.start = true
+	}
+}
+
+// clockTimer runs once per APU cycle, every other CPU cycle.
+func (pulse *pulse) clockTimer() {
+	if pulse.timer > 0 {
+		pulse.timer--
+		return
+	}
+
+	pulse.timer = pulse.timerPeriod
+	pulse.sequence = (pulse.sequence + 1) % 8
+}
+
+// targetPeriod is where the sweep unit would move the period next. It's
+// computed continuously, as it mutes the channel even with sweeps disabled.
+func (pulse *pulse) targetPeriod() int {
+	period := int(pulse.timerPeriod)
+	change := period >> pulse.sweepShift
+
+	if !pulse.sweepNegate {
+		return period + change
+	}
+	if pulse.onesComplement {
+		return period - change - 1
+	}
+	return period - change
+}
+
+func (pulse *pulse) muted() bool {
+	if pulse.noSweep {
+		return false
+	}
+	return pulse.timerPeriod < 8 || pulse.targetPeriod() > 0x7FF
+}
+
+// clockSweep runs on half frames.
+func (pulse *pulse) clockSweep() {
+	if pulse.sweepDivider == 0 && pulse.sweepEnabled && pulse.sweepShift > 0 && !pulse.muted() {
+		target := pulse.targetPeriod()
+		if target < 0 {
+			target = 0
+		}
+		pulse.timerPeriod = uint16(target)
+	}
+
+	if pulse.sweepDivider == 0 || pulse.sweepReload {
+		pulse.sweepDivider = pulse.sweepPeriod
+		pulse.sweepReload = false
+	} else {
+		pulse.sweepDivider--
+	}
+}
+
+func (pulse *pulse) output() byte {
+	if pulse.muted() || pulse.length.value == 0 || dutyTable[pulse.duty][pulse.sequence] == 0 {
+		return 0
+	}
+	return pulse.envelope.output()
+}
@@ -0,0 +1,115 @@
+package apu
+
+import (
+	"fmt"
+	"math"
+)
+
+// sampler turns the mixer output, which can change every CPU cycle, into PCM
+// at a fixed sample rate through a band-limited resampler and the console's
+// analog filters.
+type sampler struct {
+	rate            int
+	samplesPerCycle float64
+	blip            blipBuffer
+	level           float64 // Mixer output at the last step
+	highPass1       highPass
+	highPass2       highPass
+	lowPass         lowPass
+	samples         []int16
+}
+
+func newSampler(rate int, cpuClockRate float64) sampler {
+	sampler := sampler{rate: rate}
+	if rate > 0 {
+		sampler.samplesPerCycle = float64(rate) / cpuClockRate
+		sampler.highPass1 = newHighPass(HIGH_PASS_1_HZ, rate)
+		sampler.highPass2 = newHighPass(HIGH_PASS_2_HZ, rate)
+		sampler.lowPass = newLowPass(LOW_PASS_HZ, rate)
+	}
+	return sampler
+}
+
+// SetSampleRate starts collecting 16 bit PCM samples at rate Hz, or stops
+// when rate is 0. The rate can't be above the CPU's clock rate.
+func (apu *Apu) SetSampleRate(rate int) error {
+	if rate < 0 || float64(rate) > apu.timing.CpuClockRate {
+		return fmt.Errorf("Apu.SetSampleRate(): Sample rate of %d Hz isn't between 0 and the CPU clock rate of %.0f Hz.", rate, apu.timing.CpuClockRate)
+	}
+
+	apu.startSampling(rate)
+	return nil
+}
+
+func (apu *Apu) startSampling(rate int) {
+	apu.sampler = newSampler(rate, apu.timing.CpuClockRate)
+	if apu.stems != nil {
+		apu.SetStems(true)
+	}
+}
+
+func (apu *Apu) SampleRate() int {
+	return apu.sampler.rate
+}
+
+// Samples returns the samples produced since the last call.
+func (apu *Apu) Samples() []int16 {
+	samples := apu.sampler.samples
+	apu.sampler.samples = nil
+	return samples
+}
+
+// SetStems starts or stops resampling every channel on its own, alongside
+// the mixed output and at the same rate, so that the stems line up with it
+// sample for sample. Stems ignore muting. Any stem samples not yet collected
+// are dropped.
+func (apu *Apu) SetStems(enabled bool) {
+	apu.stems = nil
+	if !enabled {
+		return
+	}
+
+	apu.stems = make([]sampler, len(apu.muted))
+	for channel := range apu.stems {
+		apu.stems[channel] = newSampler(apu.sampler.rate, apu.timing.CpuClockRate)
+		// Start in step with the mixed output's next sample
+		apu.stems[channel].blip.time = apu.sampler.blip.time
+	}
+}
+
+func (apu *Apu) Stems() bool {
+	return apu.stems != nil
+}
+
+// StemSamples returns each channel's samples produced since the last call,
+// in the order of Channels. Each holds as many samples as Samples returns
+// over the same period.
+func (apu *Apu) StemSamples() [][]int16 {
+	stems := make([][]int16, len(apu.stems))
+	for channel := range apu.stems {
+		stems[channel] = apu.stems[channel].samples
+		apu.stems[channel].samples = nil
+	}
+	return stems
+}
+
+// add takes the mixer output for one CPU cycle.
+func (sampler *sampler) add(level float64) {
+	if level != sampler.level {
+		sampler.blip.addDelta(level - sampler.level)
+		sampler.level = level
+	}
+
+	// Switching to a slower region can leave the rate above the CPU clock,
+	// with more than one sample finished in a cycle
+	sampler.blip.advance(sampler.samplesPerCycle)
+	for sampler.blip.ready() {
+		sample := sampler.blip.readSample()
+		sample = sampler.highPass1.apply(sample)
+		sample = sampler.highPass2.apply(sample)
+		sample = sampler.lowPass.apply(sample)
+
+		value := math.Max(-1, math.Min(1, sample)) * math.MaxInt16
+		sampler.samples = append(sampler.samples, int16(value))
+	}
+}
@@ -0,0 +1,103 @@
+package apu
+
+import (
+	"math"
+	"testing"
+
+	"github.com/tjarjoura/nes-emulator/region"
+)
+
+// BenchmarkSampler runs the APU a frame at a time with 48kHz output and
+// reports how many times faster than real time it runs.
+func BenchmarkSampler(b *testing.B) {
+	benchmarks := []struct {
+		name      string
+		stems     bool
+		expansion bool
+	}{
+		{"bare", false, false},
+		{"stems", true, false},
+		{"vrc7", false, true},
+		{"vrc7+stems", true, true},
+	}
+
+	for _, benchmark := range benchmarks {
+		b.Run(benchmark.name, func(b *testing.B) {
+			apu := NewApu()
+			var vrc7 *Vrc7
+			if benchmark.expansion {
+				vrc7 = NewVrc7()
+				apu.SetExpansion(vrc7)
+			}
+			apu.SetSampleRate(48000)
+			apu.SetStems(benchmark.stems)
+
+			b.ResetTimer()
+			for frame := 0; frame < b.N; frame++ {
+				BenchFrame(apu, vrc7, frame)
+				apu.Samples()
+				apu.StemSamples()
+			}
+
+			emulated := float64(b.N*apu.timing.FrameCycles()) / apu.timing.CpuClockRate
+			b.ReportMetric(emulated/b.Elapsed().Seconds(), "x-realtime")
+		})
+	}
+}
+
+// sampleSecond plays a square wave for a second of CPU cycles and returns
+// how many samples came out.
+func sampleSecond(apu *Apu) int {
+	apu.WriteByte(0x4015, 0x01)
+	apu.WriteByte(0x4000, 0xBF)
+	apu.WriteByte(0x4002, 0x80)
+	apu.WriteByte(0x4003, 0x00)
+
+	samples := 0
+	for cycle := 0; cycle < int(apu.timing.CpuClockRate); cycle++ {
+		apu.Clock()
+		if cycle%apu.timing.FrameCycles() == 0 {
+			samples += len(apu.Samples())
+		}
+	}
+	return samples + len(apu.Samples())
+}
+
+func TestSampleRateLimits(t *testing.T) {
+	clock := int(region.NTSC.Timing().CpuClockRate)
+	tests := []struct {
+		rate  int
+		valid bool
+	}{
+		{-1, false},
+		{0, true},
+		{1, true},
+		{48000, true},
+		{clock, true},
+		{clock + 1, false},
+	}
+
+	for _, test := range tests {
+		apu := NewApu()
+		err := apu.SetSampleRate(test.rate)
+		if (err == nil) != test.valid {
+			t.Errorf("SetSampleRate(%d) returned %v, want valid = %t", test.rate, err, test.valid)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+
+		if got := sampleSecond(apu); math.Abs(float64(got-test.rate)) > 1 {
+			t.Errorf("%d samples in a second at %d Hz", got, test.rate)
+		}
+	}
+
+	// Switching to PAL leaves the rate above the slower CPU clock
+	apu := NewApu()
+	apu.SetSampleRate(clock)
+	apu.SetRegion(region.PAL)
+	if got := sampleSecond(apu); math.Abs(float64(got-clock)) > 1 {
+		t.Errorf("%d samples in a second at %d Hz after switching to PAL", got, clock)
+	}
+}
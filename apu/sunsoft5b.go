@@ -0,0 +1,217 @@
+package apu
+
+import (
+	"fmt"
+	"math"
+)
+
+// A 5B channel at full volume is roughly as loud as an APU pulse at full
+// volume. Its square waves swing between 0 and the channel's amplitude.
+const SUNSOFT5B_LEVEL float64 = PULSE_LEVEL
+
+const (
+	SUNSOFT5B_PRESCALER int     = 16  // CPU cycles per tone and noise clock
+	SUNSOFT5B_STEP_DB   float64 = 1.5 // Between each of the 32 output levels
+)
+
+// Mixer register bits, which are set to turn a source off
+const (
+	SUNSOFT5B_TONE_OFF  byte = 0x01
+	SUNSOFT5B_NOISE_OFF byte = 0x08
+)
+
+// Volume register bit selecting the envelope instead of a fixed volume
+const SUNSOFT5B_USE_ENVELOPE byte = 0x10
+
+// Envelope shape bits
+const (
+	SUNSOFT5B_HOLD      byte = 0x01
+	SUNSOFT5B_ALTERNATE byte = 0x02
+	SUNSOFT5B_ATTACK    byte = 0x04
+	SUNSOFT5B_CONTINUE  byte = 0x08
+)
+
+// Output levels are logarithmic, with 0 silent
+var sunsoft5bLevels = func() [32]float64 {
+	var levels [32]float64
+	for level := 1; level < 32; level++ {
+		levels[level] = math.Pow(10, float64(level-31)*SUNSOFT5B_STEP_DB/20)
+	}
+	return levels
+}()
+
+type sunsoft5bTone struct {
+	period, counter uint16
+	high            bool
+}
+
+// sunsoft5bEnvelope ramps through 32 levels, then holds, repeats or turns
+// around according to its shape.
+type sunsoft5bEnvelope struct {
+	period, counter uint16
+	shape           byte
+	step            byte
+	attack, holding bool
+}
+
+func (env *sunsoft5bEnvelope) restart(shape byte) {
+	env.shape = shape
+	env.step = 0
+	env.counter = 0
+	env.attack = shape&SUNSOFT5B_ATTACK > 0
+	env.holding = false
+}
+
+func (env *sunsoft5bEnvelope) clock() {
+	if env.holding {
+		return
+	}
+
+	env.step++
+	if env.step <= 31 {
+		return
+	}
+
+	switch {
+	case env.shape&SUNSOFT5B_CONTINUE == 0:
+		env.holding = true
+		env.attack = false
+		env.step = 31
+	case env.shape&SUNSOFT5B_HOLD > 0:
+		env.holding = true
+		env.step = 31
+		if env.shape&SUNSOFT5B_ALTERNATE > 0 {
+			env.attack = !env.attack
+		}
+	default:
+		env.step = 0
+		if env.shape&SUNSOFT5B_ALTERNATE > 0 {
+			env.attack = !env.attack
+		}
+	}
+}
+
+func (env *sunsoft5bEnvelope) level() byte {
+	if env.attack {
+		return env.step
+	}
+	return 31 - env.step
+}
+
+// Sunsoft5b is the sound half of Sunsoft's 5B, a licensed YM2149F (an AY-3-8910
+// variant): three square wave channels with a shared noise generator and
+// envelope. A register is selected by writing to $C000-$DFFF and written
+// through $E000-$FFFF. It is only heard in NSFs, since the FME-7 boards that
+// carry it (mapper 69) aren't emulated.
+type Sunsoft5b struct {
+	registers [16]byte
+	selected  byte
+
+	tones    [3]sunsoft5bTone
+	envelope sunsoft5bEnvelope
+
+	noisePeriod, noiseCounter byte
+	noiseShift                uint32 // 17 bit LFSR
+	noiseHalf                 bool   // Noise runs at half the tone clock
+
+	prescaler int
+}
+
+func NewSunsoft5b() *Sunsoft5b {
+	return &Sunsoft5b{noiseShift: 1, prescaler: SUNSOFT5B_PRESCALER}
+}
+
+func (sunsoft5b *Sunsoft5b) ReadByte(address uint16) (byte, error) {
+	return 0x00, fmt.Errorf("Sunsoft5b.ReadByte(): Unmapped memory address 0x%x.", address)
+}
+
+func (sunsoft5b *Sunsoft5b) WriteByte(address uint16, data byte) error {
+	switch address & 0xE000 {
+	case 0xC000:
+		sunsoft5b.selected = data & 0x0F
+	case 0xE000:
+		sunsoft5b.writeRegister(sunsoft5b.selected, data)
+	}
+
+	return nil
+}
+
+func (sunsoft5b *Sunsoft5b) writeRegister(register byte, data byte) {
+	sunsoft5b.registers[register] = data
+	regs := &sunsoft5b.registers
+
+	switch {
+	case register <= 5:
+		channel := register / 2
+		sunsoft5b.tones[channel].period = uint16(regs[channel*2]) | uint16(regs[channel*2+1]&0x0F)<<8
+	case register == 6:
+		sunsoft5b.noisePeriod = data & 0x1F
+	case register == 11 || register == 12:
+		sunsoft5b.envelope.period = uint16(regs[11]) | uint16(regs[12])<<8
+	case register == 13:
+		sunsoft5b.envelope.restart(data & 0x0F)
+	}
+}
+
+func (sunsoft5b *Sunsoft5b) Clock() {
+	// The envelope steps twice as often as a tone with the same period would
+	// toggle, to fit in its 32 steps
+	env := &sunsoft5b.envelope
+	if sunsoft5b.prescaler%(SUNSOFT5B_PRESCALER/2) == 0 {
+		env.counter++
+		if env.counter >= env.period {
+			env.counter = 0
+			env.clock()
+		}
+	}
+
+	sunsoft5b.prescaler--
+	if sunsoft5b.prescaler > 0 {
+		return
+	}
+	sunsoft5b.prescaler = SUNSOFT5B_PRESCALER
+
+	for i := range sunsoft5b.tones {
+		tone := &sunsoft5b.tones[i]
+		tone.counter++
+		if tone.counter >= tone.period {
+			tone.counter = 0
+			tone.high = !tone.high
+		}
+	}
+
+	sunsoft5b.noiseHalf = !sunsoft5b.noiseHalf
+	if sunsoft5b.noiseHalf {
+		sunsoft5b.noiseCounter++
+		if sunsoft5b.noiseCounter >= sunsoft5b.noisePeriod {
+			sunsoft5b.noiseCounter = 0
+			feedback := (sunsoft5b.noiseShift ^ sunsoft5b.noiseShift>>3) & 0x01
+			sunsoft5b.noiseShift = sunsoft5b.noiseShift>>1 | feedback<<16
+		}
+	}
+}
+
+var sunsoft5bChannels = []string{"5b.a", "5b.b", "5b.c"}
+
+func (sunsoft5b *Sunsoft5b) Channels() []string {
+	return sunsoft5bChannels
+}
+
+func (sunsoft5b *Sunsoft5b) ChannelOutput(channel int) float64 {
+	mixer := sunsoft5b.registers[7]
+	toneOn := sunsoft5b.tones[channel].high || mixer&(SUNSOFT5B_TONE_OFF<<channel) > 0
+	noiseOn := sunsoft5b.noiseShift&0x01 > 0 || mixer&(SUNSOFT5B_NOISE_OFF<<channel) > 0
+	if !toneOn || !noiseOn {
+		return 0
+	}
+
+	volume := sunsoft5b.registers[8+channel]
+	if volume&SUNSOFT5B_USE_ENVELOPE > 0 {
+		return sunsoft5bLevels[sunsoft5b.envelope.level()] * SUNSOFT5B_LEVEL
+	}
+	if volume&0x0F == 0 {
+		return 0
+	}
+	// Fixed volumes step by 3dB, every other envelope level
+	return sunsoft5bLevels[(volume&0x0F)*2+1] * SUNSOFT5B_LEVEL
+}
@@ -0,0 +1,64 @@
+package apu
+
+var triangleSequence = [32]byte{
+	15, 14, 13, 12, 11, 10, 9, 8, 7, 6, 5, 4, 3, 2, 1, 0,
+	0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15,
+}
+
+// The triangle has no volume control. Its sequencer only steps while both
+// the length counter and the linear counter, a finer grained second length
+// counter clocked on quarter frames, are non-zero.
+type triangle struct {
+	length lengthCounter
+
+	control                     bool // Halts the length counter and keeps reloading the linear counter
+	linearReload, linearCounter byte
+	linearReloadFlag            bool
+
+	timer, timerPeriod uint16
+	sequence           byte
+}
+
+func (triangle *triangle) write(register uint16, data byte) {
+	switch register {
+	case 0:
+		triangle.control = data&0x80 > 0
+		triangle.length.halt = triangle.control
+		triangle.linearReload = data & 0x7F
+	case 2:
+		triangle.timerPeriod = triangle.timerPeriod&0x0700 | uint16(data)
+	case 3:
+		triangle.timerPeriod = triangle.timerPeriod&0x00FF | uint16(data&0x07)<<8
+		triangle.length.load(data >> 3)
+		triangle.linearReloadFlag = true
+	}
+}
+
+// clockTimer runs every CPU cycle, twice as fast as the pulse timers.
+func (triangle *triangle) clockTimer() {
+	if triangle.timer > 0 {
+		triangle.timer--
+		return
+	}
+
+	triangle.timer = triangle.timerPeriod
+	if triangle.length.value > 0 && triangle.linearCounter > 0 {
+		triangle.sequence = (triangle.sequence + 1) % 32
+	}
+}
+
+func (triangle *triangle) clockLinearCounter() {
+	if triangle.linearReloadFlag {
+		triangle.linearCounter = triangle.linearReload
+	} else if triangle.linearCounter > 0 {
+		triangle.linearCounter--
+	}
+
+	if !triangle.control {
+		triangle.linearReloadFlag = false
+	}
+}
+
+func (triangle *triangle) output() byte {
+	return triangleSequence[triangle.sequence]
+}
@@ -0,0 +1,76 @@
+package apu
+
+// Length counter loads, indexed by bits 3-7 of the channel's fourth register.
+var lengthTable = [32]byte{
+	10, 254, 20, 2, 40, 4, 80, 6, 160, 8, 60, 10, 14, 12, 26, 14,
+	12, 16, 24, 18, 48, 20, 96, 22, 192, 24, 72, 26, 16, 28, 32, 30,
+}
+
+// lengthCounter silences a channel once it counts down to zero. It's clocked
+// on half frames unless halted.
+type lengthCounter struct {
+	enabled bool // Set through $4015
+	halt    bool
+	value   byte
+}
+
+func (length *lengthCounter) load(index byte) {
+	if length.enabled {
+		length.value = lengthTable[index&0x1F]
+	}
+}
+
+func (length *lengthCounter) setEnabled(enabled bool) {
+	length.enabled = enabled
+	if !enabled {
+		length.value = 0
+	}
+}
+
+func (length *lengthCounter) clock() {
+	if length.value > 0 && !length.halt {
+		length.value--
+	}
+}
+
+// envelope produces either a constant volume or a sawtooth decaying from 15,
+// clocked on quarter frames.
+type envelope struct {
+	start, loop, constant bool
+	volume                byte // Constant volume, or the divider period
+	divider, decay        byte
+}
+
+func (env *envelope) write(data byte) {
+	env.loop = data&0x20 > 0
+	env.constant = data&0x10 > 0
+	env.volume = data & 0x0F
+}
+
+func (env *envelope) clock() {
+	if env.start {
+		env.start = false
+		env.decay = 15
+		env.divider = env.volume
+		return
+	}
+
+	if env.divider > 0 {
+		env.divider--
+		return
+	}
+
+	env.divider = env.volume
+	if env.decay > 0 {
+		env.decay--
+	} else if env.loop {
+		env.decay = 15
+	}
+}
+
+func (env *envelope) output() byte {
+	if env.constant {
+		return env.volume
+	}
+	return env.decay
+}
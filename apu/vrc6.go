@@ -0,0 +1,186 @@
+package apu
+
+import "fmt"
+
+// A VRC6 pulse at full volume is about as loud as an APU pulse at full volume
+const VRC6_LEVEL float64 = PULSE_LEVEL / 15
+
+const (
+	VRC6_HALT      byte = 0x01
+	VRC6_SHIFT_4   byte = 0x02
+	VRC6_SHIFT_8   byte = 0x04
+	VRC6_SAW_STEPS byte = 14
+)
+
+// vrc6Pulse has sixteen steps and eight duty cycles, from 1/16 to 8/16, and
+// can hold its output high as a crude 4 bit DAC.
+type vrc6Pulse struct {
+	enabled, constant bool
+	duty, volume      byte
+	timer, period     uint16
+	step              byte // Counts down from 15
+}
+
+func (pulse *vrc6Pulse) write(register uint16, data byte) {
+	switch register {
+	case 0:
+		pulse.constant = data&0x80 > 0
+		pulse.duty = (data >> 4) & 0x07
+		pulse.volume = data & 0x0F
+	case 1:
+		pulse.period = pulse.period&0x0F00 | uint16(data)
+	case 2:
+		pulse.period = pulse.period&0x00FF | uint16(data&0x0F)<<8
+		pulse.enabled = data&0x80 > 0
+		if !pulse.enabled {
+			pulse.step = 15
+		}
+	}
+}
+
+func (pulse *vrc6Pulse) clock(shift uint) {
+	if !pulse.enabled {
+		return
+	}
+
+	if pulse.timer > 0 {
+		pulse.timer--
+		return
+	}
+	pulse.timer = pulse.period >> shift
+	pulse.step = (pulse.step - 1) & 0x0F
+}
+
+func (pulse *vrc6Pulse) output() byte {
+	if pulse.enabled && (pulse.constant || pulse.step <= pulse.duty) {
+		return pulse.volume
+	}
+	return 0
+}
+
+// vrc6Saw adds its rate to an 8 bit accumulator on every other step and
+// clears it on the fourteenth. The top five bits are the output; rates over
+// 42 overflow and distort, as on the real chip.
+type vrc6Saw struct {
+	enabled       bool
+	rate          byte
+	timer, period uint16
+	step          byte
+	accumulator   byte
+}
+
+func (saw *vrc6Saw) write(register uint16, data byte) {
+	switch register {
+	case 0:
+		saw.rate = data & 0x3F
+	case 1:
+		saw.period = saw.period&0x0F00 | uint16(data)
+	case 2:
+		saw.period = saw.period&0x00FF | uint16(data&0x0F)<<8
+		saw.enabled = data&0x80 > 0
+		if !saw.enabled {
+			saw.step = 0
+			saw.accumulator = 0
+		}
+	}
+}
+
+func (saw *vrc6Saw) clock(shift uint) {
+	if !saw.enabled {
+		return
+	}
+
+	if saw.timer > 0 {
+		saw.timer--
+		return
+	}
+	saw.timer = saw.period >> shift
+
+	saw.step++
+	if saw.step == VRC6_SAW_STEPS {
+		saw.step = 0
+		saw.accumulator = 0
+	} else if saw.step%2 == 0 {
+		saw.accumulator += saw.rate
+	}
+}
+
+func (saw *vrc6Saw) output() byte {
+	return saw.accumulator >> 3
+}
+
+// Vrc6 is the sound half of Konami's VRC6: two pulses and a sawtooth, with
+// registers at $9000-$9003, $A000-$A002 and $B000-$B002. Only NSFs play it,
+// as the VRC6 boards (mappers 24 and 26) aren't emulated.
+type Vrc6 struct {
+	pulse1, pulse2 vrc6Pulse
+	saw            vrc6Saw
+
+	halt  bool
+	shift uint // Speeds every channel up by 16 or 256 times
+}
+
+func NewVrc6() *Vrc6 {
+	vrc6 := &Vrc6{}
+	vrc6.pulse1.step = 15
+	vrc6.pulse2.step = 15
+	return vrc6
+}
+
+func (vrc6 *Vrc6) ReadByte(address uint16) (byte, error) {
+	return 0x00, fmt.Errorf("Vrc6.ReadByte(): Unmapped memory address 0x%x.", address)
+}
+
+func (vrc6 *Vrc6) WriteByte(address uint16, data byte) error {
+	register := address & 0x0003
+
+	switch address & 0xF000 {
+	case 0x9000:
+		if register == 3 {
+			vrc6.halt = data&VRC6_HALT > 0
+			switch {
+			case data&VRC6_SHIFT_8 > 0:
+				vrc6.shift = 8
+			case data&VRC6_SHIFT_4 > 0:
+				vrc6.shift = 4
+			default:
+				vrc6.shift = 0
+			}
+		} else {
+			vrc6.pulse1.write(register, data)
+		}
+	case 0xA000:
+		vrc6.pulse2.write(register, data)
+	case 0xB000:
+		vrc6.saw.write(register, data)
+	}
+
+	return nil
+}
+
+func (vrc6 *Vrc6) Clock() {
+	if vrc6.halt {
+		return
+	}
+
+	vrc6.pulse1.clock(vrc6.shift)
+	vrc6.pulse2.clock(vrc6.shift)
+	vrc6.saw.clock(vrc6.shift)
+}
+
+var vrc6Channels = []string{"vrc6.pulse1", "vrc6.pulse2", "vrc6.saw"}
+
+func (vrc6 *Vrc6) Channels() []string {
+	return vrc6Channels
+}
+
+func (vrc6 *Vrc6) ChannelOutput(channel int) float64 {
+	switch channel {
+	case 0:
+		return float64(vrc6.pulse1.output()) * VRC6_LEVEL
+	case 1:
+		return float64(vrc6.pulse2.output()) * VRC6_LEVEL
+	default:
+		return float64(vrc6.saw.output()) * VRC6_LEVEL
+	}
+}
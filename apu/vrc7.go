@@ -0,0 +1,510 @@
+package apu
+
+import (
+	"fmt"
+	"math"
+)
+
+// The VRC7 runs from a 3.58MHz crystal, twice the CPU clock, and produces a
+// sample of every channel each 72 of its clocks
+const (
+	VRC7_SAMPLE_CYCLES int     = 36
+	VRC7_SAMPLE_RATE   float64 = 3579545.0 / 72
+	VRC7_CHANNELS      int     = 6
+)
+
+// A channel at full volume is, peak to peak, roughly twice as loud as an APU
+// pulse at full volume
+const VRC7_LEVEL float64 = PULSE_LEVEL
+
+const (
+	VRC7_ADDRESS uint16 = 0x9010
+	VRC7_DATA    uint16 = 0x9030
+)
+
+// Levels are attenuations, counted in 0.375dB envelope steps. An operator
+// is silent at VRC7_EG_MUTE.
+const (
+	VRC7_EG_MUTE     int  = 127
+	VRC7_DAMP_RATE   byte = 12 // Quickly silences a channel being keyed on again
+	VRC7_PHASE_BITS  uint = 19
+	VRC7_FB_MAX_BITS uint = 9
+)
+
+// The low frequency oscillators for tremolo, 3.7Hz and 4.875dB deep, and
+// vibrato, 6.4Hz
+const (
+	VRC7_AM_STEPS   int = 210 // Triangle from 0 to 13 envelope steps and back
+	VRC7_AM_SAMPLES int = 64  // Samples per step
+	VRC7_PM_SAMPLES int = 1024
+)
+
+// Patch bytes 0 and 1, for the modulator and the carrier
+const (
+	VRC7_PATCH_AM        byte = 0x80
+	VRC7_PATCH_VIBRATO   byte = 0x40
+	VRC7_PATCH_SUSTAINED byte = 0x20 // Hold at the sustain level until key off
+	VRC7_PATCH_KSR       byte = 0x10
+	VRC7_PATCH_MULTIPLE  byte = 0x0F
+)
+
+// Patch byte 3
+const (
+	VRC7_PATCH_CARRIER_RECTIFY   byte = 0x10
+	VRC7_PATCH_MODULATOR_RECTIFY byte = 0x08
+	VRC7_PATCH_FEEDBACK          byte = 0x07
+)
+
+// Channel register $20-$25
+const (
+	VRC7_KEY_ON  byte = 0x10
+	VRC7_SUSTAIN byte = 0x20
+)
+
+// The built-in instruments, 1-15, in the OPLL's patch format. Instrument 0 is
+// the custom patch in registers $00-$07. These were dumped from a real VRC7.
+var vrc7Patches = [15][8]byte{
+	{0x03, 0x21, 0x05, 0x06, 0xE8, 0x81, 0x42, 0x27}, // Buzzy bell
+	{0x13, 0x41, 0x14, 0x0D, 0xD8, 0xF6, 0x23, 0x12}, // Guitar
+	{0x11, 0x11, 0x08, 0x08, 0xFA, 0xB2, 0x20, 0x12}, // Wurly
+	{0x31, 0x61, 0x0C, 0x07, 0xA8, 0x64, 0x61, 0x27}, // Flute
+	{0x32, 0x21, 0x1E, 0x06, 0xE1, 0x76, 0x01, 0x28}, // Clarinet
+	{0x02, 0x01, 0x06, 0x00, 0xA3, 0xE2, 0xF4, 0xF4}, // Synth
+	{0x21, 0x61, 0x1D, 0x07, 0x82, 0x81, 0x11, 0x07}, // Trumpet
+	{0x23, 0x21, 0x22, 0x17, 0xA2, 0x72, 0x01, 0x17}, // Organ
+	{0x35, 0x11, 0x25, 0x00, 0x40, 0x73, 0x72, 0x01}, // Bells
+	{0xB5, 0x01, 0x0F, 0x0F, 0xA8, 0xA5, 0x51, 0x02}, // Vibes
+	{0x17, 0xC1, 0x24, 0x07, 0xF8, 0xF8, 0x22, 0x12}, // Vibraphone
+	{0x71, 0x23, 0x11, 0x06, 0x65, 0x74, 0x18, 0x16}, // Tutti
+	{0x01, 0x02, 0xD3, 0x05, 0xC9, 0x95, 0x03, 0x02}, // Fretless
+	{0x61, 0x63, 0x0C, 0x00, 0x94, 0xC0, 0x33, 0xF6}, // Synth bass
+	{0x21, 0x72, 0x0D, 0x00, 0xC1, 0xD5, 0x56, 0x06}, // Sweep
+}
+
+// Frequency multiples, doubled so that 1/2 is a whole number
+var vrc7Multiples = [16]uint32{1, 2, 4, 6, 8, 10, 12, 14, 16, 18, 20, 20, 24, 24, 30, 30}
+
+// Key scale attenuation in 0.75dB steps for the top four bits of the
+// frequency, in octave 8. It falls by 6dB an octave, and the patch scales it
+// by 0, 1/4, 1/2 or 1.
+var vrc7KeyScale = [16]int{0, 32, 40, 45, 48, 51, 53, 55, 56, 58, 59, 60, 61, 62, 63, 64}
+var vrc7KeyScaleShifts = [4]uint{8, 2, 1, 0}
+
+// Vibrato moves the frequency by up to 7/1024ths, in eight steps a cycle,
+// by an amount that depends on its top three bits.
+var vrc7Vibrato = [8][8]int{
+	{0, 0, 0, 0, 0, 0, 0, 0},
+	{0, 0, 1, 0, 0, 0, -1, 0},
+	{0, 1, 2, 1, 0, -1, -2, -1},
+	{0, 1, 3, 1, 0, -1, -3, -1},
+	{0, 2, 4, 2, 0, -2, -4, -2},
+	{0, 2, 5, 2, 0, -2, -5, -2},
+	{0, 3, 6, 3, 0, -3, -6, -3},
+	{0, 3, 7, 3, 0, -3, -7, -3},
+}
+
+// Operators look up a quarter of a sine wave as log2 attenuation, in 1/256ths
+// of an octave, add their envelope and turn the sum back into a level with a
+// table of powers of two, as the chip does.
+var vrc7LogSin, vrc7Exp = makeVrc7Tables()
+
+func makeVrc7Tables() ([256]int, [256]int) {
+	var logSin, exp [256]int
+	for i := range logSin {
+		logSin[i] = int(math.Round(-math.Log2(math.Sin((float64(i)+0.5)*math.Pi/512)) * 256))
+		exp[i] = int(math.Round(math.Pow(2, float64(255-i)/256) * 1024))
+	}
+	return logSin, exp
+}
+
+// The envelope moves once on each of the samples where the pattern for the
+// bottom two bits of its rate has a 1, out of every eight chances.
+var vrc7EnvelopeSteps = [4][8]int{
+	{0, 1, 0, 1, 0, 1, 0, 1},
+	{0, 1, 0, 1, 1, 1, 0, 1},
+	{0, 1, 1, 1, 0, 1, 1, 1},
+	{0, 1, 1, 1, 1, 1, 1, 1},
+}
+
+// Rates 14 and up move by more than a step at a time.
+var vrc7FastEnvelopeSteps = [4][8]int{
+	{1, 1, 1, 1, 1, 1, 1, 1},
+	{1, 1, 1, 2, 1, 1, 1, 2},
+	{1, 2, 1, 2, 1, 2, 1, 2},
+	{1, 2, 2, 2, 1, 2, 2, 2},
+}
+
+type vrc7EnvelopeState int
+
+const (
+	VRC7_EG_DAMP vrc7EnvelopeState = iota
+	VRC7_EG_ATTACK
+	VRC7_EG_DECAY
+	VRC7_EG_SUSTAIN
+	VRC7_EG_RELEASE
+)
+
+// vrc7Operator is one sine oscillator with its envelope. Each channel has
+// two: a modulator bending the phase of a carrier, which is heard.
+type vrc7Operator struct {
+	phase    uint32 // VRC7_PHASE_BITS, of which the top 10 index the sine
+	envelope int    // Attenuation in envelope steps
+	state    vrc7EnvelopeState
+	output   [2]int // The last two outputs, for feedback
+}
+
+type vrc7Channel struct {
+	frequency  uint16 // 9 bits
+	block      byte   // Octave
+	keyOn      bool
+	sustain    bool
+	instrument byte
+	volume     byte // Attenuation in 3dB steps
+
+	modulator, carrier vrc7Operator
+}
+
+// Vrc7 is the sound half of Konami's VRC7, a cut down Yamaha OPLL (YM2413)
+// with six two operator FM channels, fifteen fixed instruments and one that
+// can be programmed. A register is selected through $9010 and written
+// through $9030.
+type Vrc7 struct {
+	custom   [8]byte
+	channels [VRC7_CHANNELS]vrc7Channel
+	selected byte
+	held     bool // In reset, silent and ignoring writes
+
+	counter int // Samples, for the envelopes and the LFOs
+	timer   int
+	levels  [VRC7_CHANNELS]float64
+}
+
+func NewVrc7() *Vrc7 {
+	vrc7 := new(Vrc7)
+	vrc7.Reset()
+	return vrc7
+}
+
+// Reset silences every channel and clears the registers.
+func (vrc7 *Vrc7) Reset() {
+	*vrc7 = Vrc7{timer: VRC7_SAMPLE_CYCLES}
+	for i := range vrc7.channels {
+		vrc7.channels[i].modulator.envelope = VRC7_EG_MUTE
+		vrc7.channels[i].modulator.state = VRC7_EG_RELEASE
+		vrc7.channels[i].carrier.envelope = VRC7_EG_MUTE
+		vrc7.channels[i].carrier.state = VRC7_EG_RELEASE
+	}
+}
+
+// SetReset holds the chip in reset, as the VRC7 board's silence bit does.
+// The registers are cleared and the chip stays silent until it is released.
+func (vrc7 *Vrc7) SetReset(held bool) {
+	if held {
+		vrc7.Reset()
+	}
+	vrc7.held = held
+}
+
+func (vrc7 *Vrc7) ReadByte(address uint16) (byte, error) {
+	return 0x00, fmt.Errorf("Vrc7.ReadByte(): Unmapped memory address 0x%x.", address)
+}
+
+func (vrc7 *Vrc7) WriteByte(address uint16, data byte) error {
+	if vrc7.held {
+		return nil
+	}
+
+	switch address {
+	case VRC7_ADDRESS:
+		vrc7.selected = data
+	case VRC7_DATA:
+		vrc7.writeRegister(vrc7.selected, data)
+	}
+
+	return nil
+}
+
+func (vrc7 *Vrc7) writeRegister(register byte, data byte) {
+	if register < 0x08 {
+		vrc7.custom[register] = data
+		return
+	}
+
+	index := int(register & 0x0F)
+	if index >= VRC7_CHANNELS {
+		return
+	}
+	channel := &vrc7.channels[index]
+
+	switch register & 0xF0 {
+	case 0x10:
+		channel.frequency = channel.frequency&0x100 | uint16(data)
+	case 0x20:
+		channel.frequency = channel.frequency&0xFF | uint16(data&0x01)<<8
+		channel.block = (data >> 1) & 0x07
+		channel.sustain = data&VRC7_SUSTAIN > 0
+
+		keyOn := data&VRC7_KEY_ON > 0
+		if keyOn && !channel.keyOn {
+			channel.modulator.state = VRC7_EG_DAMP
+			channel.carrier.state = VRC7_EG_DAMP
+		} else if !keyOn && channel.keyOn {
+			channel.modulator.state = VRC7_EG_RELEASE
+			channel.carrier.state = VRC7_EG_RELEASE
+		}
+		channel.keyOn = keyOn
+	case 0x30:
+		channel.instrument = data >> 4
+		channel.volume = data & 0x0F
+	}
+}
+
+func (vrc7 *Vrc7) patch(instrument byte) *[8]byte {
+	if instrument == 0 {
+		return &vrc7.custom
+	}
+	return &vrc7Patches[instrument-1]
+}
+
+// keyScale is the channel's block and top frequency bit, which speed up
+// envelopes on higher notes. Patches without KSR only use the top two bits.
+func (channel *vrc7Channel) keyScale(flags byte) int {
+	keyScale := int(channel.block)<<1 | int(channel.frequency>>8)
+	if flags&VRC7_PATCH_KSR == 0 {
+		keyScale >>= 2
+	}
+	return keyScale
+}
+
+// envelopeRate is the 4 bit rate for an operator's envelope state. op is 0
+// for the modulator and 1 for the carrier.
+func (channel *vrc7Channel) envelopeRate(operator *vrc7Operator, patch *[8]byte, op int) byte {
+	flags := patch[op]
+
+	// The modulator's envelope holds while the channel is keyed off
+	if op == 0 && !channel.keyOn {
+		return 0
+	}
+
+	switch operator.state {
+	case VRC7_EG_DAMP:
+		return VRC7_DAMP_RATE
+	case VRC7_EG_ATTACK:
+		return patch[4+op] >> 4
+	case VRC7_EG_DECAY:
+		return patch[4+op] & 0x0F
+	case VRC7_EG_SUSTAIN:
+		if flags&VRC7_PATCH_SUSTAINED > 0 {
+			return 0
+		}
+		return patch[6+op] & 0x0F
+	default: // VRC7_EG_RELEASE
+		switch {
+		case channel.sustain:
+			return 5
+		case flags&VRC7_PATCH_SUSTAINED > 0:
+			return patch[6+op] & 0x0F
+		default:
+			return 7
+		}
+	}
+}
+
+// startAttack moves on from the damping at key on. Attacks at the top rate
+// are instant.
+func (channel *vrc7Channel) startAttack(operator *vrc7Operator, patch *[8]byte, op int) {
+	operator.state = VRC7_EG_ATTACK
+	if int(patch[4+op]>>4)+channel.keyScale(patch[op])>>2 >= 15 {
+		operator.envelope = 0
+	}
+}
+
+// clockEnvelope advances an operator's envelope by a sample. Each rate
+// moves the envelope on samples where the counter's bottom 13-rate bits are
+// clear, following the step patterns for the bottom bits of the key scale.
+func (vrc7 *Vrc7) clockEnvelope(channel *vrc7Channel, operator *vrc7Operator, patch *[8]byte, op int) {
+	keyScale := channel.keyScale(patch[op])
+
+	if rate := channel.envelopeRate(operator, patch, op); rate > 0 {
+		high := int(rate) + keyScale>>2
+		if high > 15 {
+			high = 15
+		}
+		low := keyScale & 0x03
+
+		counter := vrc7.counter
+		if operator.state == VRC7_EG_ATTACK {
+			// The attack is exponential, moving by a fraction of the
+			// remaining attenuation
+			shift := 0
+			if high < 12 {
+				shift = 13 - high
+			}
+			mask := 1<<shift - 1
+
+			var step int
+			if high < 15 && operator.envelope > 0 && counter&mask&^3 == 0 {
+				switch high {
+				case 12, 13, 14:
+					step = 16 - high - vrc7EnvelopeSteps[low][counter&0x0C>>1]
+				default:
+					if vrc7EnvelopeSteps[low][counter>>shift&7] > 0 {
+						step = 4
+					}
+				}
+			}
+			if step > 0 {
+				operator.envelope -= operator.envelope>>step + 1
+				if operator.envelope < 0 {
+					operator.envelope = 0
+				}
+			}
+		} else {
+			shift := 0
+			if high < 13 {
+				shift = 13 - high
+			}
+
+			if counter&(1<<shift-1) == 0 {
+				switch high {
+				case 13:
+					operator.envelope += vrc7EnvelopeSteps[low][counter&0x0C>>1|counter&1]
+				case 14:
+					operator.envelope += vrc7FastEnvelopeSteps[low][counter&0x0C>>1]
+				case 15:
+					operator.envelope += 2
+				default:
+					operator.envelope += vrc7EnvelopeSteps[low][counter>>shift&7]
+				}
+				if operator.envelope > VRC7_EG_MUTE {
+					operator.envelope = VRC7_EG_MUTE
+				}
+			}
+		}
+	}
+
+	switch operator.state {
+	case VRC7_EG_DAMP:
+		if operator.envelope>>2 == VRC7_EG_MUTE>>2 {
+			channel.startAttack(operator, patch, op)
+
+			// The carrier restarts both oscillators once it's quiet
+			if op == 1 {
+				channel.modulator.phase = 0
+				channel.carrier.phase = 0
+			}
+		}
+	case VRC7_EG_ATTACK:
+		if operator.envelope == 0 {
+			operator.state = VRC7_EG_DECAY
+		}
+	case VRC7_EG_DECAY:
+		if operator.envelope>>3 == int(patch[6+op]>>4) {
+			operator.state = VRC7_EG_SUSTAIN
+		}
+	}
+}
+
+// clockOperator advances an operator by a sample and returns its output,
+// a 13 bit signed level. modulation shifts its phase, in 1024ths of a
+// cycle, and baseAttenuation is its fixed attenuation in envelope steps.
+func (vrc7 *Vrc7) clockOperator(channel *vrc7Channel, operator *vrc7Operator, patch *[8]byte, op int, modulation int, baseAttenuation int) int {
+	flags := patch[op]
+	vrc7.clockEnvelope(channel, operator, patch, op)
+
+	frequency := int(channel.frequency) << 1
+	if flags&VRC7_PATCH_VIBRATO > 0 {
+		frequency += vrc7Vibrato[channel.frequency>>6][vrc7.counter/VRC7_PM_SAMPLES%8]
+	}
+	operator.phase += uint32(frequency) * vrc7Multiples[flags&VRC7_PATCH_MULTIPLE] << channel.block >> 2
+	operator.phase &= 1<<VRC7_PHASE_BITS - 1
+
+	keyScale := vrc7KeyScale[channel.frequency>>5]<<2 - int(8-channel.block)<<5
+	if keyScale < 0 {
+		keyScale = 0
+	}
+	// In 0.1875dB steps until here
+	keyScale >>= 1 + vrc7KeyScaleShifts[patch[2+op]>>6]
+
+	attenuation := operator.envelope + baseAttenuation + keyScale
+	if flags&VRC7_PATCH_AM > 0 {
+		step := vrc7.counter / VRC7_AM_SAMPLES % VRC7_AM_STEPS
+		if step >= VRC7_AM_STEPS/2 {
+			step = VRC7_AM_STEPS - 1 - step
+		}
+		attenuation += step / 8
+	}
+	if attenuation >= VRC7_EG_MUTE {
+		return 0
+	}
+
+	index := int(operator.phase>>(VRC7_PHASE_BITS-10)) + modulation
+	rectify := VRC7_PATCH_MODULATOR_RECTIFY
+	if op == 1 {
+		rectify = VRC7_PATCH_CARRIER_RECTIFY
+	}
+	negative := index&0x200 > 0
+	if negative && patch[3]&rectify > 0 {
+		return 0
+	}
+
+	// The second and fourth quarters run backwards through the table
+	quarter := index & 0xFF
+	if index&0x100 > 0 {
+		quarter ^= 0xFF
+	}
+
+	level := vrc7LogSin[quarter] + attenuation<<4
+	if level>>8 > 12 {
+		return 0
+	}
+	output := vrc7Exp[level&0xFF] << 1 >> (level >> 8)
+	if negative {
+		return -output
+	}
+	return output
+}
+
+func (vrc7 *Vrc7) sampleChannel(channel *vrc7Channel) int {
+	patch := vrc7.patch(channel.instrument)
+	modulator := &channel.modulator
+
+	var feedback int
+	if shift := uint(patch[3] & VRC7_PATCH_FEEDBACK); shift > 0 {
+		feedback = (modulator.output[0] + modulator.output[1]) >> (VRC7_FB_MAX_BITS - shift)
+	}
+
+	modulatorAttenuation := int(patch[2]&0x3F) << 1
+	output := vrc7.clockOperator(channel, modulator, patch, 0, feedback, modulatorAttenuation)
+	modulator.output[1], modulator.output[0] = modulator.output[0], output
+
+	carrierAttenuation := int(channel.volume) << 3
+	return vrc7.clockOperator(channel, &channel.carrier, patch, 1, output, carrierAttenuation)
+}
+
+func (vrc7 *Vrc7) Clock() {
+	if vrc7.held {
+		return
+	}
+
+	vrc7.timer--
+	if vrc7.timer > 0 {
+		return
+	}
+	vrc7.timer = VRC7_SAMPLE_CYCLES
+
+	for i := range vrc7.channels {
+		vrc7.levels[i] = float64(vrc7.sampleChannel(&vrc7.channels[i])) / 4096
+	}
+	vrc7.counter++
+}
+
+var vrc7Channels = []string{"vrc7.1", "vrc7.2", "vrc7.3", "vrc7.4", "vrc7.5", "vrc7.6"}
+
+func (vrc7 *Vrc7) Channels() []string {
+	return vrc7Channels
+}
+
+func (vrc7 *Vrc7) ChannelOutput(channel int) float64 {
+	return vrc7.levels[channel] * VRC7_LEVEL
+}
@@ -0,0 +1,263 @@
+package apu
+
+import (
+	"encoding/binary"
+	"flag"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var updateGolden = flag.Bool("update", false, "rewrite the VRC7 golden renders in testdata/vrc7")
+
+// sinePatch is a custom instrument with a quiet modulator and no feedback,
+// so that the carrier plays close to a pure sine. Its carrier attacks
+// instantly and decays at decay down to the sustain level of 45dB.
+func sinePatch(decay byte) [8]byte {
+	return [8]byte{
+		0x21,         // Modulator: sustained, multiple 1
+		0x21,         // Carrier: sustained, multiple 1
+		0x3F,         // Modulator at its quietest
+		0x00,         // No rectification or feedback
+		0xF0,         // Modulator attack 15
+		0xF0 | decay, // Carrier attack 15
+		0x00,
+		0xF0, // Carrier sustains at 15 * 3dB
+	}
+}
+
+// playNote keys on channel 1 with patch as the custom instrument.
+func playNote(vrc7 *Vrc7, patch [8]byte, frequency uint16, block byte, volume byte) {
+	for register, data := range patch {
+		vrc7.writeRegister(byte(register), data)
+	}
+	vrc7.writeRegister(0x30, volume) // Custom instrument
+	vrc7.writeRegister(0x10, byte(frequency))
+	vrc7.writeRegister(0x20, VRC7_KEY_ON|block<<1|byte(frequency>>8))
+}
+
+func vrc7Samples(vrc7 *Vrc7, count int) []float64 {
+	samples := make([]float64, count)
+	for i := range samples {
+		for cycle := 0; cycle < VRC7_SAMPLE_CYCLES; cycle++ {
+			vrc7.Clock()
+		}
+		samples[i] = vrc7.levels[0]
+	}
+	return samples
+}
+
+func TestVrc7Pitch(t *testing.T) {
+	vrc7 := NewVrc7()
+	playNote(vrc7, sinePatch(0), 0x120, 4, 0)
+
+	vrc7Samples(vrc7, 1000) // Past the damping and attack
+	second := vrc7Samples(vrc7, 49716)
+
+	crossings := 0
+	for i := 1; i < len(second); i++ {
+		if (second[i-1] < 0) != (second[i] < 0) {
+			crossings++
+		}
+	}
+
+	// F * 2^(block - 19) cycles a sample
+	want := VRC7_SAMPLE_RATE * 0x120 * math.Pow(2, 4-19)
+	if got := float64(crossings) / 2; math.Abs(got-want) > 1 {
+		t.Errorf("Note at %gHz, want %gHz", got, want)
+	}
+}
+
+func peak(samples []float64) float64 {
+	var peak float64
+	for _, sample := range samples {
+		peak = math.Max(peak, math.Abs(sample))
+	}
+	return peak
+}
+
+func TestVrc7Volume(t *testing.T) {
+	for volume := byte(0); volume < 8; volume++ {
+		vrc7 := NewVrc7()
+		playNote(vrc7, sinePatch(0), 0x120, 4, volume)
+		level := peak(vrc7Samples(vrc7, 2000)[1000:])
+
+		// 3dB a step
+		want := math.Pow(10, -3*float64(volume)/20)
+		if math.Abs(level-want) > want*0.03 {
+			t.Errorf("Volume %d peaks at %g, want %g", volume, level, want)
+		}
+	}
+}
+
+func TestVrc7Decay(t *testing.T) {
+	vrc7 := NewVrc7()
+	// Block 1 keeps the key scale at 0, so that decay rate 8 moves the
+	// envelope a step every 64 samples
+	playNote(vrc7, sinePatch(8), 0x120, 1, 0)
+	carrier := &vrc7.channels[0].carrier
+
+	samples := 0
+	for ; carrier.state != VRC7_EG_DECAY; samples++ {
+		if samples > 1000 {
+			t.Fatal("Envelope never reached its decay")
+		}
+		vrc7Samples(vrc7, 1)
+	}
+
+	start := samples
+	for ; carrier.state == VRC7_EG_DECAY; samples++ {
+		if samples > 100000 {
+			t.Fatal("Envelope never reached its sustain level")
+		}
+		vrc7Samples(vrc7, 1)
+	}
+
+	if got, want := samples-start, 120*64; math.Abs(float64(got-want)) > 64 {
+		t.Errorf("Decay to 45dB took %d samples, want %d", got, want)
+	}
+	if carrier.envelope != 120 {
+		t.Errorf("Envelope sustains at %d steps, want 120", carrier.envelope)
+	}
+
+	// The sustain level holds for a sustained instrument
+	vrc7Samples(vrc7, 10000)
+	if carrier.envelope != 120 {
+		t.Errorf("Envelope moved to %d steps while sustained, want 120", carrier.envelope)
+	}
+}
+
+// vrc7GoldenCases are the renders compared against testdata/vrc7. Each
+// plays a note on channel 1 and keys it off three quarters of the way
+// through. The custom instruments release at rate 5. Cases with the slow
+// tremolo or vibrato keep one sample in every four.
+var vrc7GoldenCases = []struct {
+	name       string
+	instrument byte
+	custom     [8]byte
+	every      int
+}{
+	{"buzzy-bell", 1, [8]byte{}, 1},
+	{"guitar", 2, [8]byte{}, 1},
+	{"wurly", 3, [8]byte{}, 1},
+	{"flute", 4, [8]byte{}, 1},
+	{"clarinet", 5, [8]byte{}, 1},
+	{"synth", 6, [8]byte{}, 1},
+	{"trumpet", 7, [8]byte{}, 4},
+	{"organ", 8, [8]byte{}, 1},
+	{"bells", 9, [8]byte{}, 1},
+	{"vibes", 10, [8]byte{}, 4},
+	{"vibraphone", 11, [8]byte{}, 4},
+	{"tutti", 12, [8]byte{}, 1},
+	{"fretless", 13, [8]byte{}, 1},
+	{"synth-bass", 14, [8]byte{}, 1},
+	{"sweep", 15, [8]byte{}, 1},
+	{"sine", 0, sinePatch(0), 1},
+	// Modulator at full level, twice the carrier's frequency
+	{"modulator", 0, [8]byte{0x22, 0x21, 0x00, 0x00, 0xF0, 0xF0, 0x00, 0xF5}, 1},
+	{"feedback", 0, [8]byte{0x21, 0x21, 0x10, 0x07, 0xF0, 0xF0, 0x00, 0xF5}, 1},
+	{"rectify-carrier", 0, [8]byte{0x21, 0x21, 0x3F, 0x10, 0xF0, 0xF0, 0x00, 0xF5}, 1},
+	{"rectify-modulator", 0, [8]byte{0x21, 0x21, 0x00, 0x08, 0xF0, 0xF0, 0x00, 0xF5}, 1},
+	{"tremolo", 0, [8]byte{0x21, 0xA1, 0x3F, 0x00, 0xF0, 0xF0, 0x00, 0xF5}, 4},
+	{"vibrato", 0, [8]byte{0x21, 0x61, 0x3F, 0x00, 0xF0, 0xF0, 0x00, 0xF5}, 4},
+}
+
+const VRC7_GOLDEN_SAMPLES int = 4096
+
+// renderGolden plays a golden case as 16 bit samples of the carrier's
+// output, before the channel's volume is applied.
+func renderGolden(instrument byte, custom [8]byte, every int) []int16 {
+	vrc7 := NewVrc7()
+	if instrument == 0 {
+		playNote(vrc7, custom, 0x120, 4, 0)
+	} else {
+		vrc7.writeRegister(0x30, instrument<<4)
+		vrc7.writeRegister(0x10, 0x20)
+		vrc7.writeRegister(0x20, VRC7_KEY_ON|4<<1|0x01)
+	}
+
+	render := make([]int16, VRC7_GOLDEN_SAMPLES)
+	for i := range render {
+		if i == VRC7_GOLDEN_SAMPLES*3/4 {
+			vrc7.writeRegister(0x20, 4<<1|0x01)
+		}
+		samples := vrc7Samples(vrc7, every)
+		render[i] = int16(math.Round(samples[every-1] * 4096))
+	}
+	return render
+}
+
+// compareRenders returns the correlation of two renders and the RMS of
+// their difference as a fraction of want's RMS.
+func compareRenders(got []int16, want []int16) (float64, float64) {
+	var gotSum, wantSum float64
+	for i := range want {
+		gotSum += float64(got[i])
+		wantSum += float64(want[i])
+	}
+	gotMean, wantMean := gotSum/float64(len(want)), wantSum/float64(len(want))
+
+	var cross, gotSquares, wantSquares, errorSquares, wantPower float64
+	for i := range want {
+		g, w := float64(got[i])-gotMean, float64(want[i])-wantMean
+		cross += g * w
+		gotSquares += g * g
+		wantSquares += w * w
+		errorSquares += (float64(got[i]) - float64(want[i])) * (float64(got[i]) - float64(want[i]))
+		wantPower += float64(want[i]) * float64(want[i])
+	}
+
+	if gotSquares == 0 || wantSquares == 0 {
+		return 0, 1
+	}
+	return cross / math.Sqrt(gotSquares*wantSquares), math.Sqrt(errorSquares / wantPower)
+}
+
+// TestVrc7Golden compares renders of the built-in instruments, and custom
+// ones for the modulator, feedback, rectification, tremolo and vibrato,
+// against the 16 bit little endian renders in testdata/vrc7. The renders
+// there were made by this implementation with -update, so they catch
+// regressions rather than prove it matches the chip. Renders from a
+// reference OPLL emulator with the same note and timing can replace them.
+func TestVrc7Golden(t *testing.T) {
+	for _, test := range vrc7GoldenCases {
+		got := renderGolden(test.instrument, test.custom, test.every)
+		path := filepath.Join("testdata", "vrc7", test.name+".pcm")
+
+		if *updateGolden {
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				t.Fatal(err)
+			}
+			file, err := os.Create(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = binary.Write(file, binary.LittleEndian, got)
+			file.Close()
+			if err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Errorf("%s: %s", test.name, err)
+			continue
+		}
+		if len(data) != 2*VRC7_GOLDEN_SAMPLES {
+			t.Errorf("%s: %d bytes of golden render, want %d", test.name, len(data), 2*VRC7_GOLDEN_SAMPLES)
+			continue
+		}
+		want := make([]int16, VRC7_GOLDEN_SAMPLES)
+		for i := range want {
+			want[i] = int16(binary.LittleEndian.Uint16(data[2*i:]))
+		}
+
+		correlation, rms := compareRenders(got, want)
+		if correlation < 0.99 || rms > 0.1 {
+			t.Errorf("%s: correlation %.4f and RMS error %.4f against the golden render, want at least 0.99 and at most 0.1", test.name, correlation, rms)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/tjarjoura/nes-emulator/apu"
+	"github.com/tjarjoura/nes-emulator/region"
+	"log"
+	"os"
+	"time"
+)
+
+func apuBenchMain(args []string) {
+	flags := flag.NewFlagSet("apu-bench", flag.ExitOnError)
+	seconds := flags.Float64("seconds", 60, "seconds of audio to generate")
+	sampleRate := flags.Int("sample-rate", 48000, "output sample rate in Hz")
+	vrc7 := flags.Bool("vrc7", false, "also play the six VRC7 channels")
+	stems := flags.Bool("stems", false, "also resample each channel on its own, as -stems does")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s apu-bench [flags]\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if *sampleRate <= 0 {
+		log.Fatalf("-sample-rate: %d Hz isn't above 0\n", *sampleRate)
+	}
+
+	a := apu.NewApu()
+	err := a.SetSampleRate(*sampleRate)
+	if err != nil {
+		log.Fatalf("-sample-rate: %s\n", err)
+	}
+
+	var expansion *apu.Vrc7
+	if *vrc7 {
+		expansion = apu.NewVrc7()
+		a.SetExpansion(expansion)
+	}
+	a.SetStems(*stems)
+
+	timing := region.NTSC.Timing()
+	frames := int(*seconds * region.NTSC.FrameRate())
+	samples := 0
+
+	start := time.Now()
+	for frame := 0; frame < frames; frame++ {
+		err = apu.BenchFrame(a, expansion, frame)
+		if err != nil {
+			log.Fatalf("apu.BenchFrame(): %s\n", err)
+		}
+		samples += len(a.Samples())
+		a.StemSamples()
+	}
+	elapsed := time.Since(start)
+
+	cycles := frames * timing.FrameCycles()
+	fmt.Printf("cycles: %d\n", cycles)
+	fmt.Printf("samples: %d\n", samples)
+	fmt.Printf("elapsed: %s\n", elapsed)
+	fmt.Printf("speed: %.1fx real time\n", float64(cycles)/timing.CpuClockRate/elapsed.Seconds())
+}
@@ -0,0 +1,64 @@
+package cartridge
+
+import "github.com/tjarjoura/nes-emulator/types"
+
+const (
+	AXROM_PRG_BANK    byte = 0x07
+	AXROM_NAMETABLE_B byte = 0x10
+)
+
+// AxROM (mapper 7) switches 32KB of PRG ROM and picks which 1KB of nametable
+// RAM fills the screen with a single register at $8000-$FFFF.
+type AxROM struct {
+	Cartridge
+
+	bank byte
+}
+
+func NewAxROM(cartridge Cartridge) *AxROM {
+	cartridge.mirroring = types.MIRROR_SINGLE_A
+	return &AxROM{Cartridge: cartridge}
+}
+
+func (axrom *AxROM) PrgOffset(address uint16) (int, bool) {
+	if address < 0x8000 {
+		return 0, false
+	}
+
+	return axrom.prgBankOffset(int(axrom.bank&AXROM_PRG_BANK), 32768) + int(address-0x8000), true
+}
+
+func (axrom *AxROM) ReadByte(address uint16) (byte, error) {
+	if offset, ok := axrom.PrgOffset(address); ok {
+		return axrom.prgRom[offset], nil
+	}
+
+	return axrom.Cartridge.ReadByte(address)
+}
+
+func (axrom *AxROM) WriteByte(address uint16, data byte) error {
+	if address < 0x8000 {
+		return axrom.Cartridge.WriteByte(address, data)
+	}
+
+	axrom.bank = data
+
+	if axrom.bank&AXROM_NAMETABLE_B > 0 {
+		axrom.mirroring = types.MIRROR_SINGLE_B
+	} else {
+		axrom.mirroring = types.MIRROR_SINGLE_A
+	}
+	return nil
+}
+
+func (axrom *AxROM) ReadPpu(address uint16) byte {
+	return axrom.ppuRead(address, int(address%0x2000))
+}
+
+func (axrom *AxROM) WritePpu(address uint16, data byte) {
+	axrom.ppuWrite(address, int(address%0x2000), data)
+}
+
+func (axrom *AxROM) PeekPpu(address uint16) byte {
+	return axrom.ReadPpu(address)
+}
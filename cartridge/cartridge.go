@@ -1,13 +1,98 @@
 package cartridge
 
+import (
+	"fmt"
+	"github.com/tjarjoura/nes-emulator/types"
+)
+
+// Cartridge holds the memories shared by every board, for the mappers to
+// bank into the CPU and PPU address spaces.
 type Cartridge struct {
-	prgRom, chrRom []byte
+	prgRom, chrRom, prgRam []byte
+	chrRam                 bool // chrRom is writable RAM
+	mirroring              types.Mirroring
+
+	// Four-screen boards carry their own nametable RAM. It holds all four
+	// nametables, leaving the console's 2KB unused.
+	vram []byte
+}
+
+func newCartridge(prgRom []byte, chrRom []byte, prgRamSize uint16, mirroring types.Mirroring) Cartridge {
+	cartridge := Cartridge{prgRom: prgRom, chrRom: chrRom, prgRam: make([]byte, prgRamSize), mirroring: mirroring}
+
+	// Boards without CHR ROM have 8KB of CHR RAM in its place
+	if len(chrRom) == 0 {
+		cartridge.chrRom = make([]byte, 8192)
+		cartridge.chrRam = true
+	}
+
+	if mirroring == types.MIRROR_FOUR_SCREEN {
+		cartridge.vram = make([]byte, 4096)
+	}
+
+	return cartridge
 }
 
+// ReadByte handles the PRG RAM at $6000-$7FFF.
 func (cartridge *Cartridge) ReadByte(address uint16) (byte, error) {
-	return 0, nil // TODO implement
+	if address >= 0x6000 && address <= 0x7FFF && len(cartridge.prgRam) > 0 {
+		return cartridge.prgRam[int(address-0x6000)%len(cartridge.prgRam)], nil
+	}
+
+	return 0x00, fmt.Errorf("Cartridge.ReadByte(): Unmapped memory address 0x%x.", address)
 }
 
 func (cartridge *Cartridge) WriteByte(address uint16, data byte) error {
-	return nil // TODO implement
+	if address >= 0x6000 && address <= 0x7FFF && len(cartridge.prgRam) > 0 {
+		cartridge.prgRam[int(address-0x6000)%len(cartridge.prgRam)] = data
+	}
+
+	return nil
+}
+
+func (cartridge *Cartridge) Mirroring() types.Mirroring {
+	return cartridge.mirroring
+}
+
+// A12Rise does nothing for boards that don't watch the PPU address bus.
+func (cartridge *Cartridge) A12Rise() {}
+
+// ExpansionAudio returns nil for boards without a sound chip.
+func (cartridge *Cartridge) ExpansionAudio() types.ExpansionAudio {
+	return nil
+}
+
+// Clock does nothing for boards without a timer.
+func (cartridge *Cartridge) Clock() {}
+
+// Irq is never raised by boards without an IRQ counter.
+func (cartridge *Cartridge) Irq() bool {
+	return false
+}
+
+// ppuRead and ppuWrite serve the PPU bus for a mapper, given the offset its
+// CHR banking maps address to.
+func (cartridge *Cartridge) ppuRead(address uint16, chrOffset int) byte {
+	if address >= 0x2000 {
+		return cartridge.vram[address%0x1000]
+	}
+	return cartridge.chrRom[chrOffset]
+}
+
+func (cartridge *Cartridge) ppuWrite(address uint16, chrOffset int, data byte) {
+	if address >= 0x2000 {
+		cartridge.vram[address%0x1000] = data
+	} else if cartridge.chrRam {
+		cartridge.chrRom[chrOffset] = data
+	}
+}
+
+// prgBankOffset returns the offset of a PRG ROM bank, wrapping bank numbers
+// past the end of the ROM.
+func (cartridge *Cartridge) prgBankOffset(bank int, size int) int {
+	return bank * size % len(cartridge.prgRom)
+}
+
+func (cartridge *Cartridge) chrBankOffset(bank int, size int) int {
+	return bank * size % len(cartridge.chrRom)
 }
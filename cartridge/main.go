@@ -3,30 +3,45 @@ package cartridge
 import (
 	"bytes"
 	"fmt"
+	"github.com/tjarjoura/nes-emulator/region"
 	"github.com/tjarjoura/nes-emulator/types"
 	"io"
 	"os"
 )
 
-func getCartridge(mapperNumber byte, prgRom []byte, chrRom []byte, prgRamSize uint16) (types.MappedHardware, error) {
-	var cartridge types.MappedHardware
+// Rom holds the contents of an iNES file as laid out on disk.
+type Rom struct {
+	Mapper         byte
+	PrgRom, ChrRom []byte
+	PrgRamSize     uint16
+	PrgFileOffset  int // Offset of the first PRG ROM byte within the file
+	Region         region.Region
+	Mirroring      types.Mirroring
+}
+
+func getCartridge(mapperNumber byte, prgRom []byte, chrRom []byte, prgRamSize uint16, mirroring types.Mirroring) (types.Cartridge, error) {
+	var cartridge types.Cartridge
 
-	prgRam := make([]byte, prgRamSize)
+	base := newCartridge(prgRom, chrRom, prgRamSize, mirroring)
 
 	switch mapperNumber {
 	case 0:
-		return &NROM{prgRamSize, prgRom, chrRom, prgRam}, nil
+		return &NROM{base}, nil
+	case 1:
+		return NewMMC1(base), nil
+	case 7:
+		return NewAxROM(base), nil
+	case 85:
+		return NewVRC7(base), nil
 	default:
 		return cartridge, fmt.Errorf("Unsupported mapper number: %d", mapperNumber)
 	}
 }
 
-func CartridgeFromFile(filename string) (types.MappedHardware, error) {
-	var cartridge types.MappedHardware
-
+func RomFromFile(filename string) (*Rom, error) {
 	romFile, err := os.Open(filename)
 	if err != nil {
-		return cartridge, err
+		return nil, err
 	}
 	defer romFile.Close()
 
@@ -35,29 +50,49 @@ func CartridgeFromFile(filename string) (types.MappedHardware, error) {
 
 	inesMagic := []byte{'N', 'E', 'S', 0x1A}
 	if !bytes.Equal(header[0:4], inesMagic) {
-		return cartridge, fmt.Errorf("%s: Unrecognized file format", filename)
+		return nil, fmt.Errorf("%s: Unrecognized file format", filename)
 	}
 
 	prgRomSize := uint(header[4]) * 16384
 	chrRomSize := uint(header[5]) * 8192
 
-	fmt.Printf("PRG ROM Size: %d\tCHR ROM Rize: %d\n", prgRomSize, chrRomSize)
-
-	var prgRamSize uint16 = 0
-	hasPrgRam := (header[6] & 0x02) > 0
-	if hasPrgRam {
-		if header[8] > 0 {
-			prgRamSize = uint16(header[8]) * 8192
-		} else {
-			prgRamSize = 8192
-		}
+	// The battery bit only says whether PRG RAM is saved. Boards are assumed
+	// to have 8KB of it unless byte 8 asks for more, as test ROMs report
+	// their results at $6000 even on boards that never had RAM.
+	var prgRamSize uint16 = 8192
+	if header[8] > 0 {
+		prgRamSize = uint16(header[8]) * 8192
 	}
 
-	fmt.Printf("PRG RAM Size: %x, header[8]: %x\n", prgRamSize, header[8])
-
 	mapperLo := (header[6] & 0xF0) >> 4
 	mapperHi := header[7] & 0xF0
-	fmt.Printf("Mapper number: %d\n", mapperHi|mapperLo)
+
+	var mirroring types.Mirroring
+	if header[6]&0x08 > 0 {
+		mirroring = types.MIRROR_FOUR_SCREEN
+	} else if header[6]&0x01 > 0 {
+		mirroring = types.MIRROR_VERTICAL
+	} else {
+		mirroring = types.MIRROR_HORIZONTAL
+	}
+
+	// NES 2.0 headers carry the CPU/PPU timing in byte 12. Older headers
+	// only have a rarely set PAL flag in byte 9.
+	var romRegion region.Region
+	if header[7]&0x0C == 0x08 {
+		switch header[12] & 0x03 {
+		case 1:
+			romRegion = region.PAL
+		case 3:
+			romRegion = region.DENDY
+		default: // NTSC or multi-region
+			romRegion = region.NTSC
+		}
+	} else if header[9]&0x01 > 0 {
+		romRegion = region.PAL
+	}
+
+	prgFileOffset := len(header)
 
 	var trainer []byte
 	hasTrainer := (header[6] & 0x04) > 0
@@ -65,21 +100,37 @@ func CartridgeFromFile(filename string) (types.MappedHardware, error) {
 		trainer = make([]byte, 512)
 		_, err = io.ReadFull(romFile, trainer)
 		if err != nil {
-			return cartridge, err
+			return nil, err
 		}
+		prgFileOffset += len(trainer)
 	}
 
 	prgRom := make([]byte, prgRomSize)
 	_, err = io.ReadFull(romFile, prgRom)
 	if err != nil {
-		return cartridge, err
+		return nil, err
 	}
 
 	chrRom := make([]byte, chrRomSize)
 	_, err = io.ReadFull(romFile, chrRom)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Rom{mapperHi | mapperLo, prgRom, chrRom, prgRamSize, prgFileOffset, romRegion, mirroring}, nil
+}
+
+func CartridgeFromRom(rom *Rom) (types.Cartridge, error) {
+	return getCartridge(rom.Mapper, rom.PrgRom, rom.ChrRom, rom.PrgRamSize, rom.Mirroring)
+}
+
+func CartridgeFromFile(filename string) (types.Cartridge, error) {
+	var cartridge types.Cartridge
+
+	rom, err := RomFromFile(filename)
 	if err != nil {
 		return cartridge, err
 	}
 
-	return getCartridge(mapperHi|mapperLo, prgRom, chrRom, prgRamSize)
+	return CartridgeFromRom(rom)
 }
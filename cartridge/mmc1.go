@@ -1,14 +1,143 @@
 package cartridge
 
+import "github.com/tjarjoura/nes-emulator/types"
+
+const (
+	MMC1_RESET         byte = 0x80
+	MMC1_MIRRORING     byte = 0x03
+	MMC1_PRG_MODE      byte = 0x0C
+	MMC1_CHR_4K        byte = 0x10
+	MMC1_PRG_RAM_OFF   byte = 0x10
+	MMC1_CONTROL_RESET byte = 0x0C // Fix the last PRG bank at $C000
+)
+
+// MMC1 (mapper 1) is programmed through a serial port: five writes to
+// $8000-$FFFF shift a value in one bit at a time, and the address of the
+// fifth write selects the register it lands in.
 type MMC1 struct {
 	Cartridge
+
+	shift, shiftCount                    byte
+	control, chrBank0, chrBank1, prgBank byte
+}
+
+func NewMMC1(cartridge Cartridge) *MMC1 {
+	mmc1 := &MMC1{Cartridge: cartridge, control: MMC1_CONTROL_RESET}
+	mmc1.updateMirroring()
+	return mmc1
+}
+
+func (mmc1 *MMC1) updateMirroring() {
+	switch mmc1.control & MMC1_MIRRORING {
+	case 0:
+		mmc1.mirroring = types.MIRROR_SINGLE_A
+	case 1:
+		mmc1.mirroring = types.MIRROR_SINGLE_B
+	case 2:
+		mmc1.mirroring = types.MIRROR_VERTICAL
+	case 3:
+		mmc1.mirroring = types.MIRROR_HORIZONTAL
+	}
+}
+
+func (mmc1 *MMC1) PrgOffset(address uint16) (int, bool) {
+	if address < 0x8000 {
+		return 0, false
+	}
+
+	// 512KB boards use bit 4 of the CHR bank registers to pick a 256KB half
+	var outer int
+	if len(mmc1.prgRom) > 256*1024 {
+		outer = int(mmc1.chrBank0 & 0x10)
+	}
+
+	bank := int(mmc1.prgBank & 0x0F)
+	lastBank := (len(mmc1.prgRom)/16384 - 1) & 0x0F
+	offset := int(address & 0x3FFF)
+
+	switch (mmc1.control & MMC1_PRG_MODE) >> 2 {
+	case 0, 1: // 32KB, ignoring the low bit of the bank number
+		return mmc1.prgBankOffset((outer|bank&^1)+int(address-0x8000)/16384, 16384) + offset, true
+	case 2: // First bank fixed at $8000
+		if address < 0xC000 {
+			return mmc1.prgBankOffset(outer, 16384) + offset, true
+		}
+		return mmc1.prgBankOffset(outer|bank, 16384) + offset, true
+	default: // Last bank fixed at $C000
+		if address < 0xC000 {
+			return mmc1.prgBankOffset(outer|bank, 16384) + offset, true
+		}
+		return mmc1.prgBankOffset(outer|lastBank, 16384) + offset, true
+	}
 }
 
 func (mmc1 *MMC1) ReadByte(address uint16) (byte, error) {
-	read_byte := mmc1.prgRom[address]
-	return read_byte, nil // TODO implement
+	if offset, ok := mmc1.PrgOffset(address); ok {
+		return mmc1.prgRom[offset], nil
+	}
+	if address >= 0x6000 && mmc1.prgBank&MMC1_PRG_RAM_OFF > 0 {
+		return 0x00, nil
+	}
+
+	return mmc1.Cartridge.ReadByte(address)
 }
 
 func (mmc1 *MMC1) WriteByte(address uint16, data byte) error {
-	return nil // TODO implement
+	if address < 0x8000 {
+		if mmc1.prgBank&MMC1_PRG_RAM_OFF > 0 {
+			return nil
+		}
+		return mmc1.Cartridge.WriteByte(address, data)
+	}
+
+	if data&MMC1_RESET > 0 {
+		mmc1.shift, mmc1.shiftCount = 0, 0
+		mmc1.control |= MMC1_CONTROL_RESET
+		return nil
+	}
+
+	mmc1.shift |= (data & 0x01) << mmc1.shiftCount
+	mmc1.shiftCount++
+	if mmc1.shiftCount < 5 {
+		return nil
+	}
+
+	switch address & 0xE000 {
+	case 0x8000:
+		mmc1.control = mmc1.shift
+		mmc1.updateMirroring()
+	case 0xA000:
+		mmc1.chrBank0 = mmc1.shift
+	case 0xC000:
+		mmc1.chrBank1 = mmc1.shift
+	case 0xE000:
+		mmc1.prgBank = mmc1.shift
+	}
+
+	mmc1.shift, mmc1.shiftCount = 0, 0
+	return nil
+}
+
+func (mmc1 *MMC1) chrOffset(address uint16) int {
+	address %= 0x2000
+
+	if mmc1.control&MMC1_CHR_4K == 0 {
+		return mmc1.chrBankOffset(int(mmc1.chrBank0>>1), 8192) + int(address)
+	}
+	if address < 0x1000 {
+		return mmc1.chrBankOffset(int(mmc1.chrBank0), 4096) + int(address)
+	}
+	return mmc1.chrBankOffset(int(mmc1.chrBank1), 4096) + int(address-0x1000)
+}
+
+func (mmc1 *MMC1) ReadPpu(address uint16) byte {
+	return mmc1.ppuRead(address, mmc1.chrOffset(address))
+}
+
+func (mmc1 *MMC1) WritePpu(address uint16, data byte) {
+	mmc1.ppuWrite(address, mmc1.chrOffset(address), data)
+}
+
+func (mmc1 *MMC1) PeekPpu(address uint16) byte {
+	return mmc1.ReadPpu(address)
 }
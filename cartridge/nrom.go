@@ -3,14 +3,13 @@ package cartridge
 import "fmt"
 
 type NROM struct {
-	prgRamSize             uint16
-	prgRom, chrRom, prgRam []byte
+	Cartridge
 }
 
 func (nrom *NROM) ReadByte(address uint16) (byte, error) {
 	if address >= 0x6000 && address <= 0x7FFF {
-		if nrom.prgRamSize > 0 {
-			return nrom.prgRam[(address-0x6000)%nrom.prgRamSize], nil
+		if len(nrom.prgRam) > 0 {
+			return nrom.Cartridge.ReadByte(address)
 		} else {
 			return 0x00, fmt.Errorf("NROM.ReadByte(): Unmapped memory address 0x%x.", address)
 		}
@@ -31,10 +30,22 @@ func (nrom *NROM) ReadByte(address uint16) (byte, error) {
 	}
 }
 
-func (nrom *NROM) WriteByte(address uint16, data byte) error {
-	if address >= 0x6000 && address <= 0x7FFF && nrom.prgRamSize > 0 {
-		nrom.prgRam[(address-0x6000)%nrom.prgRamSize] = data
+func (nrom *NROM) PrgOffset(address uint16) (int, bool) {
+	if address < 0x8000 {
+		return 0, false
 	}
 
-	return nil // TODO implement
+	return int(address-0x8000) % len(nrom.prgRom), true
+}
+
+func (nrom *NROM) ReadPpu(address uint16) byte {
+	return nrom.ppuRead(address, int(address%0x2000))
+}
+
+func (nrom *NROM) WritePpu(address uint16, data byte) {
+	nrom.ppuWrite(address, int(address%0x2000), data)
+}
+
+func (nrom *NROM) PeekPpu(address uint16) byte {
+	return nrom.ReadPpu(address)
 }
@@ -0,0 +1,200 @@
+package cartridge
+
+import (
+	"github.com/tjarjoura/nes-emulator/apu"
+	"github.com/tjarjoura/nes-emulator/types"
+)
+
+const (
+	VRC7_MIRRORING byte = 0x03
+	VRC7_SILENCE   byte = 0x40 // Holds the sound chip in reset
+	VRC7_PRG_RAM   byte = 0x80
+)
+
+// IRQ control register bits
+const (
+	VRC_IRQ_ENABLE_AFTER_ACK byte = 0x01
+	VRC_IRQ_ENABLE           byte = 0x02
+	VRC_IRQ_CYCLE_MODE       byte = 0x04
+)
+
+// In scanline mode the IRQ counter is clocked every 341/3 CPU cycles, which
+// the prescaler counts in thirds
+const VRC_IRQ_PRESCALER int = 341
+
+// VRC7 (mapper 85) is Konami's board with an FM sound chip. Three 8KB PRG
+// banks and eight 1KB CHR banks are switched through registers at $8000-$DFFF,
+// with each pair of registers told apart by address line A4 on most boards
+// and A3 on the rest, and the last PRG bank is fixed at $E000.
+type VRC7 struct {
+	Cartridge
+
+	prgBanks [3]byte
+	chrBanks [8]byte
+	control  byte
+	audio    *apu.Vrc7
+
+	irqLatch, irqCounter, irqControl byte
+	irqPrescaler                     int
+	irq                              bool
+}
+
+func NewVRC7(cartridge Cartridge) *VRC7 {
+	// Every VRC7 board has 8KB of work RAM, though not all are battery backed
+	if len(cartridge.prgRam) == 0 {
+		cartridge.prgRam = make([]byte, 8192)
+	}
+
+	vrc7 := &VRC7{Cartridge: cartridge, audio: apu.NewVrc7()}
+	vrc7.updateMirroring()
+	return vrc7
+}
+
+func (vrc7 *VRC7) updateMirroring() {
+	switch vrc7.control & VRC7_MIRRORING {
+	case 0:
+		vrc7.mirroring = types.MIRROR_VERTICAL
+	case 1:
+		vrc7.mirroring = types.MIRROR_HORIZONTAL
+	case 2:
+		vrc7.mirroring = types.MIRROR_SINGLE_A
+	case 3:
+		vrc7.mirroring = types.MIRROR_SINGLE_B
+	}
+}
+
+func (vrc7 *VRC7) PrgOffset(address uint16) (int, bool) {
+	if address < 0x8000 {
+		return 0, false
+	}
+
+	window := int(address-0x8000) / 8192
+	bank := len(vrc7.prgRom)/8192 - 1
+	if window < 3 {
+		bank = int(vrc7.prgBanks[window] & 0x3F)
+	}
+
+	return vrc7.prgBankOffset(bank, 8192) + int(address&0x1FFF), true
+}
+
+func (vrc7 *VRC7) ReadByte(address uint16) (byte, error) {
+	if offset, ok := vrc7.PrgOffset(address); ok {
+		return vrc7.prgRom[offset], nil
+	}
+	if address >= 0x6000 && vrc7.control&VRC7_PRG_RAM == 0 {
+		return 0x00, nil
+	}
+
+	return vrc7.Cartridge.ReadByte(address)
+}
+
+func (vrc7 *VRC7) WriteByte(address uint16, data byte) error {
+	if address < 0x8000 {
+		if vrc7.control&VRC7_PRG_RAM == 0 {
+			return nil
+		}
+		return vrc7.Cartridge.WriteByte(address, data)
+	}
+
+	// The second register of each pair
+	second := address&0x0018 > 0
+
+	switch address & 0xF000 {
+	case 0x8000:
+		if second {
+			vrc7.prgBanks[1] = data
+		} else {
+			vrc7.prgBanks[0] = data
+		}
+	case 0x9000:
+		if address == apu.VRC7_ADDRESS || address == apu.VRC7_DATA {
+			return vrc7.audio.WriteByte(address, data)
+		}
+		vrc7.prgBanks[2] = data
+	case 0xA000, 0xB000, 0xC000, 0xD000:
+		bank := int(address-0xA000) / 0x1000 * 2
+		if second {
+			bank++
+		}
+		vrc7.chrBanks[bank] = data
+	case 0xE000:
+		if second {
+			vrc7.irqLatch = data
+			return nil
+		}
+
+		vrc7.audio.SetReset(data&VRC7_SILENCE > 0)
+		vrc7.control = data
+		vrc7.updateMirroring()
+	case 0xF000:
+		if second {
+			// Acknowledge, and go back to the enable setting from before the
+			// IRQ
+			vrc7.irq = false
+			if vrc7.irqControl&VRC_IRQ_ENABLE_AFTER_ACK > 0 {
+				vrc7.irqControl |= VRC_IRQ_ENABLE
+			} else {
+				vrc7.irqControl &^= VRC_IRQ_ENABLE
+			}
+			return nil
+		}
+
+		vrc7.irq = false
+		vrc7.irqControl = data
+		if data&VRC_IRQ_ENABLE > 0 {
+			vrc7.irqCounter = vrc7.irqLatch
+			vrc7.irqPrescaler = VRC_IRQ_PRESCALER
+		}
+	}
+
+	return nil
+}
+
+func (vrc7 *VRC7) Irq() bool {
+	return vrc7.irq
+}
+
+func (vrc7 *VRC7) clockIrqCounter() {
+	if vrc7.irqCounter == 0xFF {
+		vrc7.irqCounter = vrc7.irqLatch
+		vrc7.irq = true
+	} else {
+		vrc7.irqCounter++
+	}
+}
+
+func (vrc7 *VRC7) ExpansionAudio() types.ExpansionAudio {
+	return vrc7.audio
+}
+
+// Clock runs the IRQ counter.
+func (vrc7 *VRC7) Clock() {
+	if vrc7.irqControl&VRC_IRQ_ENABLE > 0 {
+		if vrc7.irqControl&VRC_IRQ_CYCLE_MODE > 0 {
+			vrc7.clockIrqCounter()
+		} else {
+			vrc7.irqPrescaler -= 3
+			if vrc7.irqPrescaler <= 0 {
+				vrc7.irqPrescaler += VRC_IRQ_PRESCALER
+				vrc7.clockIrqCounter()
+			}
+		}
+	}
+}
+
+func (vrc7 *VRC7) chrOffset(address uint16) int {
+	address %= 0x2000
+	return vrc7.chrBankOffset(int(vrc7.chrBanks[address/0x400]), 1024) + int(address%0x400)
+}
+
+func (vrc7 *VRC7) ReadPpu(address uint16) byte {
+	return vrc7.ppuRead(address, vrc7.chrOffset(address))
+}
+
+func (vrc7 *VRC7) WritePpu(address uint16, data byte) {
+	vrc7.ppuWrite(address, vrc7.chrOffset(address), data)
+}
+
+func (vrc7 *VRC7) PeekPpu(address uint16) byte {
+	return vrc7.ReadPpu(address)
+}
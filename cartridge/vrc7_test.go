@@ -0,0 +1,79 @@
+package cartridge
+
+import (
+	"testing"
+
+	"github.com/tjarjoura/nes-emulator/apu"
+	"github.com/tjarjoura/nes-emulator/types"
+)
+
+func newTestVRC7() *VRC7 {
+	return NewVRC7(newCartridge(make([]byte, 32768), nil, 0, types.MIRROR_VERTICAL))
+}
+
+func TestVRC7IrqCycleMode(t *testing.T) {
+	vrc7 := newTestVRC7()
+	vrc7.WriteByte(0xE010, 0xF0)                              // Latch
+	vrc7.WriteByte(0xF000, VRC_IRQ_ENABLE|VRC_IRQ_CYCLE_MODE) // Reload and start
+
+	// The counter counts up from the latch and fires as it wraps past $FF
+	for cycle := 1; cycle <= 16; cycle++ {
+		vrc7.Clock()
+		if vrc7.Irq() != (cycle == 16) {
+			t.Fatalf("Irq() = %t after %d cycles, want it set after 16", vrc7.Irq(), cycle)
+		}
+	}
+
+	vrc7.WriteByte(0xF010, 0x00) // Acknowledge
+	if vrc7.Irq() {
+		t.Error("Irq() still set after acknowledging")
+	}
+}
+
+func TestVRC7SilenceHoldsAudio(t *testing.T) {
+	vrc7 := newTestVRC7()
+	audio := vrc7.ExpansionAudio()
+
+	play := func() float64 {
+		vrc7.WriteByte(apu.VRC7_ADDRESS, 0x30)
+		vrc7.WriteByte(apu.VRC7_DATA, 0x30) // Flute at full volume
+		vrc7.WriteByte(apu.VRC7_ADDRESS, 0x10)
+		vrc7.WriteByte(apu.VRC7_DATA, 0xAC)
+		vrc7.WriteByte(apu.VRC7_ADDRESS, 0x20)
+		vrc7.WriteByte(apu.VRC7_DATA, 0x1A) // Key on, octave 5
+
+		var peak float64
+		for cycle := 0; cycle < 20000; cycle++ {
+			audio.Clock()
+			if level := audio.ChannelOutput(0); level > peak {
+				peak = level
+			}
+		}
+		if audio.ChannelOutput(1) != 0 {
+			t.Errorf("Channel 2 output %g, want it silent", audio.ChannelOutput(1))
+		}
+		return peak
+	}
+
+	if peak := play(); peak == 0 {
+		t.Fatal("No output from a keyed on channel")
+	}
+
+	vrc7.WriteByte(0xE000, VRC7_SILENCE)
+	if peak := play(); peak != 0 {
+		t.Errorf("Output %g while silenced, want 0", peak)
+	}
+
+	// Releasing the chip leaves it cleared, so the note has to be played
+	// again to be heard
+	vrc7.WriteByte(0xE000, 0x00)
+	for cycle := 0; cycle < 20000; cycle++ {
+		audio.Clock()
+	}
+	if level := audio.ChannelOutput(0); level != 0 {
+		t.Errorf("Output %g after release, want the reset to have keyed off", level)
+	}
+	if peak := play(); peak == 0 {
+		t.Error("No output after releasing the silence bit")
+	}
+}
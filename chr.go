@@ -0,0 +1,127 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/tjarjoura/nes-emulator/cartridge"
+	"github.com/tjarjoura/nes-emulator/debugview"
+	"github.com/tjarjoura/nes-emulator/palette"
+	"image"
+	"image/color"
+	"image/png"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// parseColors reads four NES colour indices such as "0F,00,10,30".
+func parseColors(list string, pal *palette.Palette) ([4]color.RGBA, error) {
+	var colors [4]color.RGBA
+
+	fields := strings.Split(list, ",")
+	if len(fields) != len(colors) {
+		return colors, fmt.Errorf("expected %d colours, got %d", len(colors), len(fields))
+	}
+
+	for i, field := range fields {
+		index, err := strconv.ParseUint(strings.TrimSpace(field), 16, 8)
+		if err != nil || index >= uint64(palette.COLORS) {
+			return colors, fmt.Errorf("bad colour index %q", field)
+		}
+		colors[i] = pal.Color(uint16(index))
+	}
+
+	return colors, nil
+}
+
+func writePng(filename string, img image.Image) error {
+	pngFile, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer pngFile.Close()
+
+	return png.Encode(pngFile, img)
+}
+
+func chrMain(args []string) {
+	flags := flag.NewFlagSet("chr", flag.ExitOnError)
+	output := flags.String("o", "chr.png", "PNG file to write")
+	paletteFile := flags.String("palette", "", ".pal file to take colours from instead of the built-in palette")
+	colorList := flags.String("colors", "", "four NES colour indices in hex, e.g. 0F,00,10,30 (default greyscale)")
+	frames := flags.Uint64("frames", 0, "run this many frames first and show the CHR currently banked in")
+	regionName := flags.String("region", "auto", "with -frames, console region: ntsc, pal, dendy or auto")
+	subpalette := flags.Int("subpalette", -1, "with -frames, colour tiles with this subpalette (0-7) from palette RAM")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s chr [flags] FILENAME\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if flags.NArg() < 1 {
+		flags.Usage()
+		os.Exit(1)
+	}
+
+	if *subpalette != -1 {
+		if *subpalette < 0 || *subpalette > 7 {
+			log.Fatalf("-subpalette: %d isn't between 0 and 7\n", *subpalette)
+		}
+		if *frames == 0 {
+			log.Fatalf("-subpalette: palette RAM is only set while running, so it needs -frames\n")
+		}
+	}
+
+	var err error
+	pal := palette.NewPalette()
+	if *paletteFile != "" {
+		pal, err = palette.PaletteFromFile(*paletteFile)
+		if err != nil {
+			log.Fatalf("PaletteFromFile(): %s\n", err)
+		}
+	}
+
+	colors := debugview.GREYSCALE
+	if *colorList != "" {
+		colors, err = parseColors(*colorList, pal)
+		if err != nil {
+			log.Fatalf("-colors: %s\n", err)
+		}
+	}
+
+	rom, err := cartridge.RomFromFile(flags.Arg(0))
+	if err != nil {
+		log.Fatalf("RomFromFile(): %s\n", err)
+	}
+
+	var img *image.RGBA
+	if *frames == 0 {
+		if len(rom.ChrRom) == 0 {
+			log.Fatalf("%s has CHR RAM; use -frames to capture it while running\n", flags.Arg(0))
+		}
+		img = debugview.ChrSheet(rom.ChrRom, colors)
+	} else {
+		console, err := loadConsole(rom, *regionName)
+		if err != nil {
+			log.Fatalf("loadConsole(): %s\n", err)
+		}
+
+		for frame := uint64(1); frame <= *frames; frame++ {
+			err = console.RunFrame()
+			if err != nil {
+				log.Fatalf("Frame %d: console.RunFrame(): %s\n", frame, err)
+			}
+		}
+
+		if *subpalette >= 0 {
+			colors = debugview.SubpaletteColors(console.Ppu, pal, *subpalette)
+		}
+		img = debugview.PatternTables(console.Ppu, colors)
+	}
+
+	err = writePng(*output, img)
+	if err != nil {
+		log.Fatalf("writePng(): %s\n", err)
+	}
+}
@@ -0,0 +1,108 @@
+package console
+
+import (
+	"github.com/tjarjoura/nes-emulator/apu"
+	"github.com/tjarjoura/nes-emulator/cpu"
+	"github.com/tjarjoura/nes-emulator/input"
+	"github.com/tjarjoura/nes-emulator/ppu"
+	"github.com/tjarjoura/nes-emulator/region"
+	"github.com/tjarjoura/nes-emulator/types"
+)
+
+// Console wires the CPU, PPU and cartridge together and keeps them running
+// in step with each other.
+type Console struct {
+	Cpu       *cpu.Cpu
+	Ppu       *ppu.Ppu
+	Apu       *apu.Apu
+	Input     *input.Ports
+	Cartridge types.Cartridge
+
+	region     region.Region
+	timing     *region.Timing
+	dotCounter int    // Dots owed to the PPU, in units of 1/timing.Cycles
+	clocked    uint64 // CPU cycles the other devices have been run for
+}
+
+func NewConsole(cartridge types.Cartridge) *Console {
+	console := &Console{
+		Cpu:       new(cpu.Cpu),
+		Ppu:       ppu.NewPpu(cartridge),
+		Apu:       apu.NewApu(),
+		Input:     new(input.Ports),
+		Cartridge: cartridge,
+		timing:    region.NTSC.Timing(),
+	}
+
+	console.Cpu.LoadProgram(cartridge)
+	console.Cpu.ConnectPpu(console.Ppu)
+	console.Cpu.ConnectApu(console.Apu)
+	console.Cpu.SetIrqLine(func() bool {
+		return console.Apu.Irq() || cartridge.Irq()
+	})
+	console.Apu.ConnectMemory(console.Cpu.ReadMemory, console.Cpu.Stall)
+	console.Apu.SetExpansion(cartridge.ExpansionAudio())
+	console.Cpu.ConnectInput(console.Input)
+	console.Ppu.SetNmiHandler(console.Cpu.TriggerNmi)
+	console.Cpu.SetBusHook(console.catchUp)
+	console.Cpu.Reset()
+
+	return console
+}
+
+// SetRegion switches every component to NTSC, PAL or Dendy timing.
+func (console *Console) SetRegion(region region.Region) {
+	console.region = region
+	console.timing = region.Timing()
+	console.Ppu.SetRegion(region)
+	console.Apu.SetRegion(region)
+}
+
+func (console *Console) Region() region.Region {
+	return console.region
+}
+
+// catchUp runs the PPU, APU and cartridge up to the start of a CPU cycle. The CPU
+// calls it before touching their registers, so reads and writes land on the
+// cycle they happen on rather than at the end of the instruction.
+func (console *Console) catchUp(cycle uint64) {
+	for ; console.clocked < cycle; console.clocked++ {
+		console.Apu.Clock()
+		console.Cartridge.Clock()
+
+		console.dotCounter += console.timing.Dots
+		for console.dotCounter >= console.timing.Cycles {
+			console.Ppu.Clock()
+			console.dotCounter -= console.timing.Cycles
+		}
+	}
+}
+
+// Reset presses the reset button. The CPU restarts through its reset vector
+// and the APU's channels are silenced, as if $4015 were cleared.
+func (console *Console) Reset() {
+	console.Cpu.Reset()
+	console.Apu.WriteByte(0x4015, 0x00)
+}
+
+// Step executes a single CPU instruction and then catches the PPU and APU up
+// with the cycles it took.
+func (console *Console) Step() error {
+	err := console.Cpu.Step(false)
+	console.catchUp(console.Cpu.Cycles())
+	return err
+}
+
+// RunFrame runs the console until the PPU starts a new frame.
+func (console *Console) RunFrame() error {
+	frame := console.Ppu.Frame()
+
+	for console.Ppu.Frame() == frame {
+		err := console.Step()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
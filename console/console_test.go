@@ -0,0 +1,242 @@
+package console
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/tjarjoura/nes-emulator/cartridge"
+	"github.com/tjarjoura/nes-emulator/types"
+)
+
+// programConsole builds an NROM console with CHR RAM that starts running
+// program at $8000.
+func programConsole(t *testing.T, program []byte) *Console {
+	t.Helper()
+
+	prgRom := make([]byte, 16384)
+	copy(prgRom, program)
+	prgRom[0x3FFC], prgRom[0x3FFD] = 0x00, 0x80 // Reset vector
+
+	cart, err := cartridge.CartridgeFromRom(&cartridge.Rom{
+		PrgRom:     prgRom,
+		PrgRamSize: 8192,
+		Mirroring:  types.MIRROR_HORIZONTAL,
+	})
+	if err != nil {
+		t.Fatalf("CartridgeFromRom(): %s", err)
+	}
+	return NewConsole(cart)
+}
+
+// spriteStatus shows sprites over a background of solid tiles for a few
+// frames and returns every PPUSTATUS bit the CPU saw set.
+func spriteStatus(t *testing.T, sprites []byte) byte {
+	t.Helper()
+
+	console := programConsole(t, []byte{
+		0x2C, 0x02, 0x20, // BIT $2002
+		0x10, 0xFB, // BPL to the BIT, waiting out the PPU's warm up
+		0x2C, 0x02, 0x20, // BIT $2002
+		0x10, 0xFB, // BPL
+		0xA9, 0x00, // LDA #$00
+		0x8D, 0x03, 0x20, // STA $2003
+		0xA9, 0x02, // LDA #$02
+		0x8D, 0x14, 0x40, // STA $4014; OAM DMA from $0200
+		0xA9, 0x18, // LDA #$18
+		0x8D, 0x01, 0x20, // STA $2001; background and sprites on
+		0xAD, 0x02, 0x20, // LDA $2002
+		0x0D, 0x00, 0x60, // ORA $6000
+		0x8D, 0x00, 0x60, // STA $6000
+		0x4C, 0x19, 0x80, // JMP to the LDA
+	})
+
+	// Tile 0 is solid and fills the nametables, tile 1 is transparent
+	for row := uint16(0); row < 8; row++ {
+		console.Cartridge.WritePpu(row, 0xFF)
+	}
+
+	oam := console.Cpu.Ram()[0x200:0x300]
+	for i := range oam {
+		oam[i] = 0xFF
+	}
+	copy(oam, sprites)
+
+	for frame := 0; frame < 4; frame++ {
+		if err := console.RunFrame(); err != nil {
+			t.Fatalf("RunFrame(): %s", err)
+		}
+	}
+	return console.Cpu.ReadMemory(0x6000)
+}
+
+func TestSpriteStatus(t *testing.T) {
+	// A line of sprites at Y $40, using tile 1 so that they can't hit
+	line := func(count int) []byte {
+		var sprites []byte
+		for i := 0; i < count; i++ {
+			sprites = append(sprites, 0x40, 0x01, 0x00, byte(i*16))
+		}
+		return sprites
+	}
+
+	tests := []struct {
+		name     string
+		sprites  []byte
+		hit      bool
+		overflow bool
+	}{
+		{"Opaque sprite 0", []byte{0x30, 0x00, 0x00, 0x40}, true, false},
+		{"Transparent sprite 0", []byte{0x30, 0x01, 0x00, 0x40}, false, false},
+		{"Sprite 0 behind the background", []byte{0x30, 0x00, 0x20, 0x40}, true, false},
+		{"Sprite 0 at X 255", []byte{0x30, 0x00, 0x00, 0xFF}, false, false},
+		{"Opaque sprite 1", []byte{0x30, 0x01, 0x00, 0x40, 0x30, 0x00, 0x00, 0x40}, false, false},
+		{"Eight sprites on a line", line(8), false, false},
+		{"Nine sprites on a line", line(9), false, true},
+	}
+
+	for _, test := range tests {
+		status := spriteStatus(t, test.sprites)
+		if hit := status&0x40 > 0; hit != test.hit {
+			t.Errorf("%s: Sprite 0 hit = %t, want %t", test.name, hit, test.hit)
+		}
+		if overflow := status&0x20 > 0; overflow != test.overflow {
+			t.Errorf("%s: Sprite overflow = %t, want %t", test.name, overflow, test.overflow)
+		}
+	}
+}
+
+// frameIrqDelay writes $4017 after a setup instruction and returns the cycle
+// of the write along with how many cycles later the frame IRQ is raised.
+func frameIrqDelay(t *testing.T, setup []byte) (uint64, uint64) {
+	t.Helper()
+
+	program := append(setup,
+		0x8D, 0x17, 0x40, // STA $4017; 4-step mode, IRQ enabled
+		0x4C, byte(0x8000+len(setup)+3), 0x80, // JMP to itself
+	)
+	console := programConsole(t, program)
+
+	var write uint64
+	console.Cpu.SetBusHook(func(cycle uint64) {
+		if write == 0 {
+			write = cycle
+		}
+		console.catchUp(cycle)
+	})
+
+	for i := 0; i < 2; i++ {
+		if err := console.Step(); err != nil {
+			t.Fatalf("Step(): %s", err)
+		}
+	}
+	if write == 0 {
+		t.Fatal("$4017 write never reached the bus")
+	}
+
+	// Step the APU one cycle at a time to find the exact cycle of the IRQ
+	for !console.Apu.Irq() {
+		if console.clocked-write > 40000 {
+			t.Fatal("No frame IRQ within a frame counter sequence")
+		}
+		console.catchUp(console.clocked + 1)
+	}
+	return write, console.clocked - write
+}
+
+func TestFrameCounterWriteParity(t *testing.T) {
+	evenWrite, evenDelay := frameIrqDelay(t, []byte{0xA9, 0x00}) // LDA #$00, 2 cycles
+	oddWrite, oddDelay := frameIrqDelay(t, []byte{0xA5, 0x00})   // LDA $00, 3 cycles
+
+	if evenWrite%2 == oddWrite%2 {
+		t.Fatalf("Writes on cycles %d and %d, want one of each parity", evenWrite, oddWrite)
+	}
+	if evenWrite%2 == 1 {
+		evenDelay, oddDelay = oddDelay, evenDelay
+	}
+
+	// The sequence restarts 3 cycles after a write on an APU cycle and 4
+	// after one between them, and raises the IRQ 29828 cycles after that
+	if evenDelay != 29830 || oddDelay != 29831 {
+		t.Errorf("IRQ %d cycles after an even write and %d after an odd one, want 29830 and 29831", evenDelay, oddDelay)
+	}
+}
+
+// runTestRom runs one of blargg's test ROMs until it reports a result at
+// $6000, and fails with the message it leaves at $6004 if that isn't 0.
+func runTestRom(t *testing.T, filename string) {
+	rom, err := cartridge.RomFromFile(filename)
+	if err != nil {
+		t.Fatalf("RomFromFile(): %s", err)
+	}
+	cart, err := cartridge.CartridgeFromRom(rom)
+	if err != nil {
+		t.Fatalf("CartridgeFromRom(): %s", err)
+	}
+	console := NewConsole(cart)
+	console.SetRegion(rom.Region)
+
+	read := console.Cpu.ReadMemory
+	resetIn := 0
+	for frame := 0; frame < 60*30; frame++ {
+		if err := console.RunFrame(); err != nil {
+			t.Fatalf("RunFrame(): %s", err)
+		}
+
+		if read(0x6001) != 0xDE || read(0x6002) != 0xB0 || read(0x6003) != 0x61 {
+			continue
+		}
+
+		switch status := read(0x6000); {
+		case status == 0x80:
+		case status == 0x81:
+			// The ROM wants the reset button pressed, at least 100ms later
+			if resetIn == 0 {
+				resetIn = 7
+			} else if resetIn--; resetIn == 0 {
+				console.Reset()
+			}
+		case status == 0x00:
+			return
+		default:
+			var message strings.Builder
+			for address := uint16(0x6004); address < 0x7000 && read(address) != 0; address++ {
+				message.WriteByte(read(address))
+			}
+			t.Fatalf("Result %d: %s", status, strings.TrimSpace(message.String()))
+		}
+	}
+	t.Fatal("No result after 30 seconds")
+}
+
+// runTestRoms runs every ROM in a directory of the nes-test-roms collection,
+// found through $NES_TEST_ROMS.
+func runTestRoms(t *testing.T, dir string) {
+	root := os.Getenv("NES_TEST_ROMS")
+	if root == "" {
+		t.Skip("NES_TEST_ROMS isn't set to an nes-test-roms checkout")
+	}
+	roms, _ := filepath.Glob(filepath.Join(root, dir, "*.nes"))
+	if len(roms) == 0 {
+		t.Skipf("No ROMs in %s", filepath.Join(root, dir))
+	}
+
+	for _, filename := range roms {
+		t.Run(filepath.Base(filename), func(t *testing.T) {
+			runTestRom(t, filename)
+		})
+	}
+}
+
+func TestApuTestRoms(t *testing.T) {
+	runTestRoms(t, "apu_test/rom_singles")
+}
+
+func TestSpriteHitTestRoms(t *testing.T) {
+	runTestRoms(t, "ppu_sprite_hit/rom_singles")
+}
+
+func TestSpriteOverflowTestRoms(t *testing.T) {
+	runTestRoms(t, "ppu_sprite_overflow/rom_singles")
+}
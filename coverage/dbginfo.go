@@ -0,0 +1,198 @@
+package coverage
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+type dbgSegment struct {
+	start, size  int
+	outputOffset int
+	inOutput     bool
+}
+
+type dbgSpan struct {
+	segment     int
+	start, size int
+	isData      bool
+}
+
+type dbgLine struct {
+	file, line int
+	spans      []int
+}
+
+// dbgScope is a .proc, the only kind of scope that has a label of its own.
+type dbgScope struct {
+	name   string
+	parent int
+	symbol int
+	spans  []int
+}
+
+// DebugInfo is the subset of an ld65 --dbgfile needed to map ROM bytes back
+// to the source lines and procedures that produced them.
+type DebugInfo struct {
+	files    map[int]string
+	segments map[int]dbgSegment
+	spans    map[int]dbgSpan
+	lines    map[int]dbgLine
+	scopes   map[int]dbgScope
+	symbols  map[int][]int // Lines each label is defined on
+}
+
+// splitAttributes splits a record such as `id=0,name="a,b.s",size=3` into
+// its key/value pairs, honouring quoted values.
+func splitAttributes(record string) map[string]string {
+	attributes := make(map[string]string)
+
+	var field strings.Builder
+	quoted := false
+	flush := func() {
+		key, value, _ := strings.Cut(field.String(), "=")
+		attributes[key] = value
+		field.Reset()
+	}
+
+	for _, char := range record {
+		switch {
+		case char == '"':
+			quoted = !quoted
+		case char == ',' && !quoted:
+			flush()
+		default:
+			field.WriteRune(char)
+		}
+	}
+	flush()
+
+	return attributes
+}
+
+func parseNumber(attributes map[string]string, key string) (int, error) {
+	value, ok := attributes[key]
+	if !ok {
+		return 0, fmt.Errorf("missing %s attribute", key)
+	}
+
+	number, err := strconv.ParseInt(value, 0, 64)
+	return int(number), err
+}
+
+// parseList parses a list of ids joined by '+', as in `span=3+4`. A missing
+// attribute is an empty list.
+func parseList(attributes map[string]string, key string) ([]int, error) {
+	value, ok := attributes[key]
+	if !ok {
+		return nil, nil
+	}
+
+	var ids []int
+	for _, field := range strings.Split(value, "+") {
+		id, err := strconv.Atoi(field)
+		if err != nil {
+			return nil, fmt.Errorf("bad %s list %q", key, value)
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
+
+func DebugInfoFromFile(filename string) (*DebugInfo, error) {
+	dbgFile, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer dbgFile.Close()
+
+	info := &DebugInfo{
+		files:    make(map[int]string),
+		segments: make(map[int]dbgSegment),
+		spans:    make(map[int]dbgSpan),
+		lines:    make(map[int]dbgLine),
+		scopes:   make(map[int]dbgScope),
+		symbols:  make(map[int][]int),
+	}
+
+	scanner := bufio.NewScanner(dbgFile)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+
+		kind, record, found := strings.Cut(scanner.Text(), "\t")
+		if !found {
+			continue
+		}
+
+		attributes := splitAttributes(record)
+		id, err := parseNumber(attributes, "id")
+		if err != nil && kind != "version" && kind != "info" {
+			return nil, fmt.Errorf("%s:%d: %s", filename, lineNumber, err)
+		}
+
+		switch kind {
+		case "file":
+			info.files[id] = attributes["name"]
+
+		case "seg":
+			var segment dbgSegment
+			segment.start, _ = parseNumber(attributes, "start")
+			segment.size, _ = parseNumber(attributes, "size")
+			segment.outputOffset, err = parseNumber(attributes, "ooffs")
+			segment.inOutput = err == nil
+			info.segments[id] = segment
+
+		case "span":
+			var span dbgSpan
+			span.segment, _ = parseNumber(attributes, "seg")
+			span.start, _ = parseNumber(attributes, "start")
+			span.size, _ = parseNumber(attributes, "size")
+			// ca65 only attaches a type to spans produced by data directives
+			_, span.isData = attributes["type"]
+			info.spans[id] = span
+
+		case "line":
+			var line dbgLine
+			line.file, _ = parseNumber(attributes, "file")
+			line.line, _ = parseNumber(attributes, "line")
+			line.spans, err = parseList(attributes, "span")
+			if err != nil {
+				return nil, fmt.Errorf("%s:%d: %s", filename, lineNumber, err)
+			}
+			info.lines[id] = line
+
+		case "scope":
+			symbol, err := parseNumber(attributes, "sym")
+			if err != nil {
+				// Plain .scope blocks and the file scope have no label
+				continue
+			}
+
+			scope := dbgScope{name: attributes["name"], symbol: symbol, parent: -1}
+			if parent, err := parseNumber(attributes, "parent"); err == nil {
+				scope.parent = parent
+			}
+			scope.spans, err = parseList(attributes, "span")
+			if err != nil {
+				return nil, fmt.Errorf("%s:%d: %s", filename, lineNumber, err)
+			}
+			info.scopes[id] = scope
+
+		case "sym":
+			info.symbols[id], err = parseList(attributes, "def")
+			if err != nil {
+				return nil, fmt.Errorf("%s:%d: %s", filename, lineNumber, err)
+			}
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return info, nil
+}
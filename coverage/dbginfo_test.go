@@ -0,0 +1,100 @@
+package coverage
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// DBG_FIXTURE is what ld65 writes for a .proc with a nested one, a data
+// byte between them and a segment that isn't in the ROM.
+const DBG_FIXTURE string = `version	major=2,minor=0
+info	csym=0,file=1,lib=0,line=3,mod=1,scope=3,seg=2,span=4,sym=2,type=4
+file	id=0,name="main, with comma.s",size=200,mtime=0x5F000000,mod=0
+seg	id=0,name="CODE",start=0x008000,size=0x0010,addrsize=absolute,type=ro,oname="test.nes",ooffs=16
+seg	id=1,name="BSS",start=0x000200,size=0x0010,addrsize=absolute,type=rw
+span	id=0,seg=0,start=0,size=3
+span	id=1,seg=0,start=3,size=2,type=1
+span	id=2,seg=1,start=0,size=1
+span	id=3,seg=0,start=5,size=1
+line	id=0,file=0,line=4,span=0
+line	id=1,file=0,line=5,span=1+3
+line	id=2,file=0,line=3
+scope	id=0,name="",mod=0,size=6,span=0+1+3
+scope	id=1,name="reset",mod=0,type=scope,size=6,parent=0,sym=0,span=0+1+3
+scope	id=2,name="loop",mod=0,type=scope,size=1,parent=1,sym=1,span=3
+sym	id=0,name="reset",addrsize=absolute,size=6,scope=0,def=2,ref=5,val=0x8000,seg=0,type=lab
+sym	id=1,name="loop",addrsize=absolute,scope=1,def=1,val=0x8005,seg=0,type=lab
+`
+
+func debugInfoFromString(t *testing.T, contents string) (*DebugInfo, error) {
+	filename := filepath.Join(t.TempDir(), "test.dbg")
+	err := os.WriteFile(filename, []byte(contents), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return DebugInfoFromFile(filename)
+}
+
+func TestDebugInfoFromFile(t *testing.T) {
+	fixture := &DebugInfo{
+		files: map[int]string{0: "main, with comma.s"},
+		segments: map[int]dbgSegment{
+			0: {start: 0x8000, size: 0x10, outputOffset: 16, inOutput: true},
+			1: {start: 0x200, size: 0x10},
+		},
+		spans: map[int]dbgSpan{
+			0: {segment: 0, start: 0, size: 3},
+			1: {segment: 0, start: 3, size: 2, isData: true},
+			2: {segment: 1, start: 0, size: 1},
+			3: {segment: 0, start: 5, size: 1},
+		},
+		lines: map[int]dbgLine{
+			0: {file: 0, line: 4, spans: []int{0}},
+			1: {file: 0, line: 5, spans: []int{1, 3}},
+			2: {file: 0, line: 3},
+		},
+		scopes: map[int]dbgScope{
+			1: {name: "reset", parent: 0, symbol: 0, spans: []int{0, 1, 3}},
+			2: {name: "loop", parent: 1, symbol: 1, spans: []int{3}},
+		},
+		symbols: map[int][]int{0: {2}, 1: {1}},
+	}
+
+	tests := []struct {
+		name     string
+		contents string
+		want     *DebugInfo
+	}{
+		{"fixture", DBG_FIXTURE, fixture},
+		{"missing id", "file\tname=\"main.s\"\n", nil},
+		{"bad number", "seg\tid=0x,name=\"CODE\"\n", nil},
+		{"bad span list", "line\tid=0,file=0,line=1,span=1+x\n", nil},
+		{"bad scope spans", "scope\tid=1,name=\"a\",sym=0,span=+\n", nil},
+		{"bad definitions", "sym\tid=0,name=\"a\",def=2+\n", nil},
+	}
+
+	for _, test := range tests {
+		info, err := debugInfoFromString(t, test.contents)
+		if test.want == nil {
+			if err == nil {
+				t.Errorf("%s: DebugInfoFromFile() succeeded, want an error", test.name)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%s: DebugInfoFromFile() = %s", test.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(info, test.want) {
+			t.Errorf("%s: DebugInfoFromFile() = %+v, want %+v", test.name, info, test.want)
+		}
+	}
+
+	if _, err := DebugInfoFromFile(filepath.Join(t.TempDir(), "missing.dbg")); err == nil {
+		t.Error("DebugInfoFromFile() of a missing file succeeded")
+	}
+}
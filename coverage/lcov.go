@@ -0,0 +1,210 @@
+package coverage
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// sourceHits maps a source file to the execution count of each of its lines.
+type sourceHits map[string]map[int]uint64
+
+func (hits sourceHits) add(file string, line int, count uint64) {
+	if hits[file] == nil {
+		hits[file] = make(map[int]uint64)
+	}
+
+	if count >= hits[file][line] {
+		hits[file][line] = count
+	}
+}
+
+// codeOffset returns where a span of code starts in PRG ROM, and false for
+// data and for spans that aren't in the ROM.
+func (recorder *Recorder) codeOffset(info *DebugInfo, spanId int) (int, bool) {
+	span, ok := info.spans[spanId]
+	if !ok || span.isData {
+		return 0, false
+	}
+
+	segment, ok := info.segments[span.segment]
+	if !ok || !segment.inOutput {
+		return 0, false
+	}
+
+	start := segment.outputOffset + span.start - recorder.prgFileOffset
+	if start < 0 || start >= len(recorder.prgRom) {
+		return 0, false
+	}
+
+	return start, true
+}
+
+func (recorder *Recorder) debugInfoHits(info *DebugInfo) sourceHits {
+	hits := make(sourceHits)
+
+	for _, line := range info.lines {
+		file, ok := info.files[line.file]
+		if !ok {
+			continue
+		}
+
+		for _, spanId := range line.spans {
+			start, ok := recorder.codeOffset(info, spanId)
+			if !ok {
+				continue
+			}
+
+			hits.add(file, line.line, recorder.spanHits(start, info.spans[spanId].size))
+		}
+	}
+
+	return hits
+}
+
+// sourceFunction is a .proc, counted by how many times its first
+// instruction ran.
+type sourceFunction struct {
+	name string
+	line int
+	hits uint64
+}
+
+// scopeName joins a .proc's name to those of the procs it's nested in, so
+// that lcov sees a different name for each.
+func scopeName(info *DebugInfo, scope dbgScope) string {
+	name := scope.name
+	for parent, ok := info.scopes[scope.parent]; ok; parent, ok = info.scopes[parent.parent] {
+		name = parent.name + "::" + name
+	}
+	return name
+}
+
+func (recorder *Recorder) debugInfoFunctions(info *DebugInfo) map[string][]sourceFunction {
+	functions := make(map[string][]sourceFunction)
+
+	for _, scope := range info.scopes {
+		definitions := info.symbols[scope.symbol]
+		if len(definitions) == 0 {
+			continue
+		}
+		line, ok := info.lines[definitions[0]]
+		if !ok {
+			continue
+		}
+		file, ok := info.files[line.file]
+		if !ok {
+			continue
+		}
+
+		entry := -1
+		for _, spanId := range scope.spans {
+			start, ok := recorder.codeOffset(info, spanId)
+			if ok && (entry < 0 || start < entry) {
+				entry = start
+			}
+		}
+		if entry < 0 {
+			continue
+		}
+
+		functions[file] = append(functions[file], sourceFunction{
+			name: scopeName(info, scope),
+			line: line.line,
+			hits: recorder.hits[entry],
+		})
+	}
+
+	for _, list := range functions {
+		sort.Slice(list, func(i, j int) bool {
+			if list[i].line != list[j].line {
+				return list[i].line < list[j].line
+			}
+			return list[i].name < list[j].name
+		})
+	}
+
+	return functions
+}
+
+func (recorder *Recorder) listingHits(listingName string) sourceHits {
+	hits := make(sourceHits)
+
+	for number, line := range recorder.listing() {
+		if line.executable {
+			hits.add(listingName, number+1, line.hits)
+		}
+	}
+
+	return hits
+}
+
+// WriteLcov writes an lcov tracefile. When debug info is given, hits are
+// attributed to the assembler source lines, and each .proc is reported as a
+// function; otherwise they refer to lines of the listing written by
+// WriteListing, which is expected to be saved as listingName.
+func (recorder *Recorder) WriteLcov(w io.Writer, testName string, info *DebugInfo, listingName string) error {
+	var hits sourceHits
+	var functions map[string][]sourceFunction
+	if info != nil {
+		hits = recorder.debugInfoHits(info)
+		functions = recorder.debugInfoFunctions(info)
+	} else {
+		hits = recorder.listingHits(listingName)
+	}
+
+	var files []string
+	for file := range hits {
+		files = append(files, file)
+	}
+	for file := range functions {
+		if hits[file] == nil {
+			files = append(files, file)
+		}
+	}
+	sort.Strings(files)
+
+	writer := bufio.NewWriter(w)
+
+	for _, file := range files {
+		fmt.Fprintf(writer, "TN:%s\n", testName)
+		fmt.Fprintf(writer, "SF:%s\n", file)
+
+		functionsHit := 0
+		for _, function := range functions[file] {
+			fmt.Fprintf(writer, "FN:%d,%s\n", function.line, function.name)
+		}
+		for _, function := range functions[file] {
+			if function.hits > 0 {
+				functionsHit++
+			}
+			fmt.Fprintf(writer, "FNDA:%d,%s\n", function.hits, function.name)
+		}
+		if len(functions[file]) > 0 {
+			fmt.Fprintf(writer, "FNF:%d\n", len(functions[file]))
+			fmt.Fprintf(writer, "FNH:%d\n", functionsHit)
+		}
+
+		var lines []int
+		for line := range hits[file] {
+			lines = append(lines, line)
+		}
+		sort.Ints(lines)
+
+		linesHit := 0
+		for _, line := range lines {
+			count := hits[file][line]
+			if count > 0 {
+				linesHit++
+			}
+			fmt.Fprintf(writer, "DA:%d,%d\n", line, count)
+		}
+
+		fmt.Fprintf(writer, "LF:%d\n", len(lines))
+		fmt.Fprintf(writer, "LH:%d\n", linesHit)
+		fmt.Fprintln(writer, "end_of_record")
+	}
+
+	return writer.Flush()
+}
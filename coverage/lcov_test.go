@@ -0,0 +1,118 @@
+package coverage
+
+import (
+	"strings"
+	"testing"
+)
+
+// newTestRecorder makes a recorder for a 16 byte PRG ROM that follows a
+// 16 byte iNES header, with the given execution counts.
+func newTestRecorder(prgRom []byte, hits map[int]uint64) *Recorder {
+	recorder := &Recorder{
+		prgRom:        prgRom,
+		prgFileOffset: 16,
+		hits:          make([]uint64, len(prgRom)),
+		origins:       make(map[int]uint16),
+		otherHits:     make(map[uint16]uint64),
+	}
+	for offset, count := range hits {
+		recorder.hits[offset] = count
+	}
+	return recorder
+}
+
+func TestWriteLcov(t *testing.T) {
+	info, err := debugInfoFromString(t, DBG_FIXTURE)
+	if err != nil {
+		t.Fatalf("DebugInfoFromFile() = %s", err)
+	}
+
+	// NOP, NOP, RTS
+	prgRom := append([]byte{0xEA, 0xEA, 0x60}, make([]byte, 13)...)
+
+	tests := []struct {
+		name string
+		info *DebugInfo
+		hits map[int]uint64
+		want []string
+	}{
+		{
+			"debug info",
+			info,
+			map[int]uint64{0: 1, 5: 10},
+			[]string{
+				"TN:test",
+				"SF:main, with comma.s",
+				"FN:3,reset",
+				"FN:5,reset::loop",
+				"FNDA:1,reset",
+				"FNDA:10,reset::loop",
+				"FNF:2",
+				"FNH:2",
+				"DA:4,1",
+				"DA:5,10",
+				"LF:2",
+				"LH:2",
+				"end_of_record",
+			},
+		},
+		{
+			// The data byte on line 5 doesn't count as executed code
+			"debug info with a proc never called",
+			info,
+			map[int]uint64{0: 1, 3: 4},
+			[]string{
+				"TN:test",
+				"SF:main, with comma.s",
+				"FN:3,reset",
+				"FN:5,reset::loop",
+				"FNDA:1,reset",
+				"FNDA:0,reset::loop",
+				"FNF:2",
+				"FNH:1",
+				"DA:4,1",
+				"DA:5,0",
+				"LF:2",
+				"LH:1",
+				"end_of_record",
+			},
+		},
+		{
+			// Line 1 of the listing is the bank header
+			"listing",
+			nil,
+			map[int]uint64{0: 2, 2: 1},
+			[]string{
+				"TN:test",
+				"SF:test.lst",
+				"DA:2,2",
+				"DA:3,0",
+				"DA:4,1",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		var lcov strings.Builder
+		recorder := newTestRecorder(prgRom, test.hits)
+		err := recorder.WriteLcov(&lcov, "test", test.info, "test.lst")
+		if err != nil {
+			t.Errorf("%s: WriteLcov() = %s", test.name, err)
+			continue
+		}
+
+		// Lines after the wanted ones are only allowed for the listing, which
+		// goes on through the rest of the ROM
+		got := strings.Split(lcov.String(), "\n")
+		complete := test.want[len(test.want)-1] == "end_of_record"
+		if len(got) < len(test.want) || complete && len(got) != len(test.want)+1 {
+			t.Errorf("%s: WriteLcov() wrote %q, want %q", test.name, got, test.want)
+			continue
+		}
+		for i, line := range test.want {
+			if got[i] != line {
+				t.Errorf("%s: line %d = %q, want %q", test.name, i+1, got[i], line)
+			}
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package coverage
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/tjarjoura/nes-emulator/cpu"
+	"io"
+	"sort"
+	"strings"
+)
+
+type listingLine struct {
+	text       string
+	offset     int // PRG ROM offset, or -1 for lines that are not instructions
+	hits       uint64
+	executable bool
+}
+
+// isExecuted reports whether any instruction starts in [start, end).
+func (recorder *Recorder) isExecuted(start int, end int) bool {
+	for offset := start; offset < end && offset < len(recorder.hits); offset++ {
+		if recorder.hits[offset] > 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// listing produces a linear-sweep disassembly of every bank. Decoding
+// resynchronises on executed addresses, so instructions that were never run
+// are still shown but can't swallow the start of an executed one.
+func (recorder *Recorder) listing() []listingLine {
+	var lines []listingLine
+
+	for bank := 0; bank < recorder.Banks(); bank++ {
+		origin := recorder.bankOrigin(bank)
+		bankStart := bank * BANK_SIZE
+		bankEnd := bankStart + BANK_SIZE
+		if bankEnd > len(recorder.prgRom) {
+			bankEnd = len(recorder.prgRom)
+		}
+
+		lines = append(lines, listingLine{
+			text:   fmt.Sprintf("; Bank %02d ($%04X-$%04X)", bank, origin, int(origin)+bankEnd-bankStart-1),
+			offset: -1,
+		})
+
+		for offset := bankStart; offset < bankEnd; {
+			address := origin + uint16(offset-bankStart)
+			text, length := cpu.Disassemble(address, recorder.prgRom[offset:bankEnd])
+
+			if recorder.hits[offset] == 0 && recorder.isExecuted(offset+1, offset+length) {
+				text, length = fmt.Sprintf(".byte $%02X", recorder.prgRom[offset]), 1
+			}
+
+			var code []string
+			for _, data := range recorder.prgRom[offset : offset+length] {
+				code = append(code, fmt.Sprintf("%02X", data))
+			}
+
+			hits := recorder.hits[offset]
+			count := "#####"
+			if hits > 0 {
+				count = fmt.Sprintf("%d", hits)
+			}
+
+			lines = append(lines, listingLine{
+				text:       fmt.Sprintf("%10s  %04X  %-8s  %s", count, address, strings.Join(code, " "), text),
+				offset:     offset,
+				hits:       hits,
+				executable: true,
+			})
+
+			offset += length
+		}
+
+		lines = append(lines, listingLine{offset: -1})
+	}
+
+	if len(recorder.otherHits) > 0 {
+		lines = append(lines, listingLine{text: "; Outside PRG ROM", offset: -1})
+
+		var addresses []int
+		for address := range recorder.otherHits {
+			addresses = append(addresses, int(address))
+		}
+		sort.Ints(addresses)
+
+		for _, address := range addresses {
+			lines = append(lines, listingLine{
+				text:   fmt.Sprintf("%10d  %04X", recorder.otherHits[uint16(address)], address),
+				offset: -1,
+			})
+		}
+	}
+
+	return lines
+}
+
+// WriteListing writes an annotated disassembly of PRG ROM. Each line starts
+// with the number of times the instruction was executed, or ##### if it
+// never was.
+func (recorder *Recorder) WriteListing(w io.Writer) error {
+	writer := bufio.NewWriter(w)
+
+	for _, line := range recorder.listing() {
+		fmt.Fprintln(writer, line.text)
+	}
+
+	return writer.Flush()
+}
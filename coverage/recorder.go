@@ -0,0 +1,89 @@
+package coverage
+
+import (
+	"github.com/tjarjoura/nes-emulator/cartridge"
+	"github.com/tjarjoura/nes-emulator/types"
+)
+
+// Banks are reported in iNES PRG units regardless of the mapper's own
+// switching granularity.
+const BANK_SIZE int = 16384
+
+// Recorder counts how many times each PRG ROM byte was executed as the
+// start of an instruction.
+type Recorder struct {
+	cartridge     types.Cartridge
+	prgRom        []byte
+	prgFileOffset int
+	hits          []uint64
+	origins       map[int]uint16 // CPU address each bank was executed at
+	otherHits     map[uint16]uint64
+}
+
+func NewRecorder(cartridge types.Cartridge, rom *cartridge.Rom) *Recorder {
+	return &Recorder{
+		cartridge:     cartridge,
+		prgRom:        rom.PrgRom,
+		prgFileOffset: rom.PrgFileOffset,
+		hits:          make([]uint64, len(rom.PrgRom)),
+		origins:       make(map[int]uint16),
+		otherHits:     make(map[uint16]uint64),
+	}
+}
+
+// Record is meant to be installed with Cpu.SetExecuteHook.
+func (recorder *Recorder) Record(address uint16) {
+	offset, ok := recorder.cartridge.PrgOffset(address)
+	if !ok || offset >= len(recorder.hits) {
+		recorder.otherHits[address]++
+		return
+	}
+
+	recorder.hits[offset]++
+
+	bank := offset / BANK_SIZE
+	if _, seen := recorder.origins[bank]; !seen {
+		recorder.origins[bank] = address - uint16(offset%BANK_SIZE)
+	}
+}
+
+func (recorder *Recorder) Banks() int {
+	return (len(recorder.prgRom) + BANK_SIZE - 1) / BANK_SIZE
+}
+
+// Hits returns the execution count of the instruction at the given offset
+// within the given bank.
+func (recorder *Recorder) Hits(bank int, offset int) uint64 {
+	index := bank*BANK_SIZE + offset
+	if index < 0 || index >= len(recorder.hits) {
+		return 0
+	}
+
+	return recorder.hits[index]
+}
+
+// spanHits returns the highest execution count of any instruction starting
+// in PRG ROM between start and start+size.
+func (recorder *Recorder) spanHits(start int, size int) uint64 {
+	var hits uint64
+	for offset := start; offset < start+size && offset < len(recorder.hits); offset++ {
+		if offset >= 0 && recorder.hits[offset] > hits {
+			hits = recorder.hits[offset]
+		}
+	}
+
+	return hits
+}
+
+// bankOrigin returns the CPU address the bank was seen at, falling back to
+// the usual location of a 16KB bank when it was never executed.
+func (recorder *Recorder) bankOrigin(bank int) uint16 {
+	if origin, ok := recorder.origins[bank]; ok {
+		return origin
+	}
+
+	if bank == recorder.Banks()-1 {
+		return 0xC000
+	}
+	return 0x8000
+}
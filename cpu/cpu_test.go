@@ -0,0 +1,354 @@
+package cpu
+
+import "testing"
+
+// flatMemory backs everything from $4020 up with RAM, so test programs can
+// live anywhere in cartridge space.
+type flatMemory [0x10000]byte
+
+func (mem *flatMemory) ReadByte(address uint16) (byte, error) {
+	return mem[address], nil
+}
+
+func (mem *flatMemory) WriteByte(address uint16, data byte) error {
+	mem[address] = data
+	return nil
+}
+
+// run loads program at $8000 and steps through it until the program counter
+// runs off its end.
+func run(t *testing.T, program []byte, setup func(cpu *Cpu, mem *flatMemory)) (*Cpu, *flatMemory) {
+	t.Helper()
+
+	mem := new(flatMemory)
+	copy(mem[0x8000:], program)
+	end := 0x8000 + uint16(len(program))
+
+	cpu := new(Cpu)
+	cpu.LoadProgram(mem)
+	cpu.sp = 0xFF
+	if setup != nil {
+		setup(cpu, mem)
+	}
+
+	for steps := 0; cpu.pc != end; steps++ {
+		if steps > 10000 {
+			t.Fatalf("Program didn't reach $%04X, stuck at $%04X", end, cpu.pc)
+		}
+		if err := cpu.Step(false); err != nil {
+			t.Fatalf("Step(): %s", err)
+		}
+	}
+
+	return cpu, mem
+}
+
+func TestAbsoluteAddressing(t *testing.T) {
+	cpu, mem := run(t, []byte{
+		0xA9, 0x42, // LDA #$42
+		0x8D, 0x00, 0x60, // STA $6000
+		0xA2, 0x02, // LDX #$02
+		0x9D, 0x00, 0x60, // STA $6000,X
+		0xAE, 0x00, 0x60, // LDX $6000
+		0xA0, 0x01, // LDY #$01
+		0xB9, 0x01, 0x60, // LDA $6001,Y
+	}, nil)
+
+	if mem[0x6000] != 0x42 || mem[0x6002] != 0x42 {
+		t.Errorf("$6000 = %02X, $6002 = %02X, want 42", mem[0x6000], mem[0x6002])
+	}
+	if cpu.ram[0x60] != 0x00 {
+		t.Errorf("$0060 = %02X, want the store to miss it", cpu.ram[0x60])
+	}
+	if cpu.x != 0x42 || cpu.a != 0x42 {
+		t.Errorf("X = %02X, A = %02X, want 42", cpu.x, cpu.a)
+	}
+}
+
+func TestZeroPageAddressing(t *testing.T) {
+	cpu, _ := run(t, []byte{
+		0xA9, 0x11, // LDA #$11
+		0x85, 0x10, // STA $10
+		0xA9, 0x22, // LDA #$22
+		0x85, 0x0F, // STA $0F
+		0xA2, 0xFF, // LDX #$FF
+		0xB5, 0x10, // LDA $10,X, which wraps to $0F
+		0xA4, 0x10, // LDY $10
+		0xE6, 0x10, // INC $10
+	}, nil)
+
+	if cpu.a != 0x22 {
+		t.Errorf("A = %02X, want 22 from the wrapped $0F", cpu.a)
+	}
+	if cpu.y != 0x11 || cpu.ram[0x10] != 0x12 {
+		t.Errorf("Y = %02X, $10 = %02X, want 11 and 12", cpu.y, cpu.ram[0x10])
+	}
+}
+
+func TestIndirectAddressing(t *testing.T) {
+	cpu, mem := run(t, []byte{
+		0xA9, 0x00, // LDA #$00
+		0x85, 0xFF, // STA $FF
+		0xA9, 0x60, // LDA #$60
+		0x85, 0x00, // STA $00; ($FF) wraps to point at $6000
+		0xA9, 0x5A, // LDA #$5A
+		0xA0, 0x03, // LDY #$03
+		0x91, 0xFF, // STA ($FF),Y
+		0xA2, 0x04, // LDX #$04
+		0xA9, 0x77, // LDA #$77
+		0x81, 0xFB, // STA ($FB,X), through $FF again
+		0xB1, 0xFF, // LDA ($FF),Y
+	}, nil)
+
+	if mem[0x6003] != 0x5A || mem[0x6000] != 0x77 {
+		t.Errorf("$6003 = %02X, $6000 = %02X, want 5A and 77", mem[0x6003], mem[0x6000])
+	}
+	if cpu.a != 0x5A {
+		t.Errorf("A = %02X, want 5A", cpu.a)
+	}
+}
+
+func TestJumps(t *testing.T) {
+	cpu, mem := run(t, []byte{
+		0x20, 0x0A, 0x80, // $8000 JSR $800A
+		0x4C, 0x10, 0x80, // $8003 JMP $8010
+		0x00, 0x00, 0x00, 0x00, // $8006 padding
+		0xA9, 0x01, // $800A LDA #$01
+		0x60,             // $800C RTS
+		0x00, 0x00, 0x00, // $800D padding
+		0x6C, 0xFF, 0x60, // $8010 JMP ($60FF)
+		0x00, 0x00, // $8013 padding
+		0xA2, 0x07, // $8015 LDX #$07
+	}, func(cpu *Cpu, mem *flatMemory) {
+		// The pointer's high byte comes from $6000, not $6100
+		mem[0x60FF] = 0x15
+		mem[0x6000] = 0x80
+		mem[0x6100] = 0x90
+	})
+
+	if cpu.a != 0x01 || cpu.x != 0x07 {
+		t.Errorf("A = %02X, X = %02X, want 01 and 07", cpu.a, cpu.x)
+	}
+	if cpu.sp != 0xFF {
+		t.Errorf("SP = %02X, want the stack balanced at FF", cpu.sp)
+	}
+	if mem[0x8006] != 0x00 {
+		t.Errorf("Padding was overwritten")
+	}
+}
+
+func TestBranches(t *testing.T) {
+	cpu, _ := run(t, []byte{
+		0xA2, 0x05, // LDX #$05
+		0xA9, 0x00, // LDA #$00
+		0x18,       // loop: CLC
+		0x69, 0x03, // ADC #$03
+		0xCA,       // DEX
+		0xD0, 0xFA, // BNE loop
+		0xF0, 0x02, // BEQ +2
+		0xA9, 0xFF, // LDA #$FF, skipped
+	}, nil)
+
+	if cpu.a != 15 {
+		t.Errorf("A = %d, want 15", cpu.a)
+	}
+}
+
+func TestBranchCycles(t *testing.T) {
+	cpu := new(Cpu)
+	mem := new(flatMemory)
+	cpu.LoadProgram(mem)
+
+	cycles := func(pc uint16, code ...byte) uint64 {
+		copy(mem[pc:], code)
+		cpu.pc = pc
+		start := cpu.cycles
+		cpu.Step(false)
+		return cpu.cycles - start
+	}
+
+	cpu.zeroFl = false
+	if n := cycles(0x8000, 0xF0, 0x10); n != 2 {
+		t.Errorf("Untaken branch took %d cycles, want 2", n)
+	}
+	if n := cycles(0x8000, 0xD0, 0x10); n != 3 {
+		t.Errorf("Taken branch took %d cycles, want 3", n)
+	}
+	if n := cycles(0x80F0, 0xD0, 0x20); n != 4 || cpu.pc != 0x8112 {
+		t.Errorf("Branch across a page took %d cycles to $%04X, want 4 to $8112", n, cpu.pc)
+	}
+
+	cpu.x = 0xFF
+	if n := cycles(0x8000, 0xBD, 0x01, 0x60); n != 5 {
+		t.Errorf("LDA abs,X across a page took %d cycles, want 5", n)
+	}
+	if n := cycles(0x8000, 0x9D, 0x01, 0x60); n != 5 {
+		t.Errorf("STA abs,X took %d cycles, want 5", n)
+	}
+}
+
+func TestArithmeticFlags(t *testing.T) {
+	tests := []struct {
+		name    string
+		program []byte
+		a       byte
+		carry   bool
+		over    bool
+		sign    bool
+		zero    bool
+	}{
+		{"ADC overflow", []byte{0x18, 0xA9, 0x50, 0x69, 0x50}, 0xA0, false, true, true, false},
+		{"ADC carry", []byte{0x18, 0xA9, 0xFF, 0x69, 0x01}, 0x00, true, false, false, true},
+		{"SBC borrow", []byte{0x38, 0xA9, 0x00, 0xE9, 0x01}, 0xFF, false, false, true, false},
+		{"SBC overflow", []byte{0x38, 0xA9, 0x50, 0xE9, 0xB0}, 0xA0, false, true, true, false},
+		{"SBC no overflow", []byte{0x38, 0xA9, 0x50, 0xE9, 0x30}, 0x20, true, false, false, false},
+		{"CMP greater", []byte{0xA9, 0xF0, 0xC9, 0x10}, 0xF0, true, false, true, false},
+		{"CMP less", []byte{0xA9, 0x10, 0xC9, 0xF0}, 0x10, false, false, false, false},
+		{"ASL", []byte{0x38, 0xA9, 0x81, 0x0A}, 0x02, true, false, false, false},
+		{"PLA", []byte{0xA9, 0x00, 0x48, 0xA9, 0x01, 0x68}, 0x00, false, false, false, true},
+	}
+
+	for _, test := range tests {
+		cpu, _ := run(t, test.program, nil)
+		if cpu.a != test.a || cpu.carryFl != test.carry || cpu.overflowFl != test.over || cpu.signFl != test.sign || cpu.zeroFl != test.zero {
+			t.Errorf("%s: A=%02X C=%t V=%t N=%t Z=%t, want A=%02X C=%t V=%t N=%t Z=%t", test.name,
+				cpu.a, cpu.carryFl, cpu.overflowFl, cpu.signFl, cpu.zeroFl,
+				test.a, test.carry, test.over, test.sign, test.zero)
+		}
+	}
+}
+
+func TestBit(t *testing.T) {
+	cpu, _ := run(t, []byte{
+		0xA9, 0x40, // LDA #$40
+		0x85, 0x10, // STA $10
+		0xA9, 0x01, // LDA #$01
+		0x24, 0x10, // BIT $10
+	}, nil)
+
+	if !cpu.zeroFl || !cpu.overflowFl || cpu.signFl {
+		t.Errorf("Z=%t V=%t N=%t, want Z and V set from $40", cpu.zeroFl, cpu.overflowFl, cpu.signFl)
+	}
+}
+
+func TestBrkAndRti(t *testing.T) {
+	cpu, mem := run(t, []byte{
+		0x00, 0xEA, // $8000 BRK and its padding byte
+		0xA9, 0x33, // $8002 LDA #$33
+	}, func(cpu *Cpu, mem *flatMemory) {
+		mem[IRQ_VECTOR] = 0x00
+		mem[IRQ_VECTOR+1] = 0x90
+		copy(mem[0x9000:], []byte{
+			0x68,             // PLA, the status
+			0x8D, 0x00, 0x60, // STA $6000
+			0x08, // PHP, to put back a status for RTI
+			0x40, // RTI
+		})
+	})
+
+	if mem[0x6000]&(STATUS_BREAK|STATUS_UNUSED) != STATUS_BREAK|STATUS_UNUSED {
+		t.Errorf("Pushed status %02X, want B and bit 5 set", mem[0x6000])
+	}
+	if cpu.a != 0x33 {
+		t.Errorf("A = %02X, want RTI to resume after the padding byte", cpu.a)
+	}
+}
+
+func TestInterruptReturnsToInterruptedInstruction(t *testing.T) {
+	mem := new(flatMemory)
+	copy(mem[0x8000:], []byte{0xE8, 0xE8}) // INX; INX
+	mem[NMI_VECTOR] = 0x00
+	mem[NMI_VECTOR+1] = 0x90
+	mem[0x9000] = 0x40 // RTI
+
+	cpu := new(Cpu)
+	cpu.LoadProgram(mem)
+	cpu.sp = 0xFF
+
+	cpu.Step(false)
+	cpu.TriggerNmi()
+	for cpu.pc != 0x8002 {
+		cpu.Step(false)
+	}
+
+	if cpu.x != 2 || cpu.sp != 0xFF {
+		t.Errorf("X = %d, SP = %02X, want 2 and FF", cpu.x, cpu.sp)
+	}
+}
+
+func TestReset(t *testing.T) {
+	mem := new(flatMemory)
+	mem[RESET_VECTOR] = 0x34
+	mem[RESET_VECTOR+1] = 0xC2
+
+	cpu := new(Cpu)
+	cpu.LoadProgram(mem)
+	cpu.Reset()
+
+	if cpu.pc != 0xC234 || cpu.sp != 0xFD || !cpu.interruptFl {
+		t.Errorf("PC = %04X, SP = %02X, I = %t, want C234, FD and set", cpu.pc, cpu.sp, cpu.interruptFl)
+	}
+}
+
+func TestCall(t *testing.T) {
+	mem := new(flatMemory)
+	copy(mem[0x8000:], []byte{
+		0x8D, 0x00, 0x60, // STA $6000
+		0x8E, 0x01, 0x60, // STX $6001
+		0x20, 0x0A, 0x80, // JSR $800A
+		0x60, // RTS
+		0x60, // $800A RTS
+	})
+
+	cpu := new(Cpu)
+	cpu.LoadProgram(mem)
+	if err := cpu.Call(0x8000, 0x12, 0x34, 1000); err != nil {
+		t.Fatalf("Call(): %s", err)
+	}
+
+	if mem[0x6000] != 0x12 || mem[0x6001] != 0x34 {
+		t.Errorf("$6000 = %02X, $6001 = %02X, want 12 and 34", mem[0x6000], mem[0x6001])
+	}
+}
+
+func TestBusHookCycle(t *testing.T) {
+	var accesses []uint64
+	var start uint64
+	run(t, []byte{
+		0xA9, 0x80, // LDA #$80, 2 cycles
+		0x8D, 0x00, 0x20, // STA $2000, 4 cycles
+		0xAD, 0x02, 0x20, // LDA $2002, 4 cycles
+		0x8D, 0x00, 0x60, // STA $6000, which isn't a register
+	}, func(cpu *Cpu, mem *flatMemory) {
+		start = cpu.cycles
+		cpu.SetBusHook(func(cycle uint64) {
+			accesses = append(accesses, cycle)
+		})
+	})
+
+	if len(accesses) != 2 {
+		t.Fatalf("Bus hook was called %d times, want 2", len(accesses))
+	}
+	if accesses[0] != start+5 || accesses[1] != start+9 {
+		t.Errorf("Accesses on cycles %d and %d, want %d and %d", accesses[0]-start, accesses[1]-start, 5, 9)
+	}
+}
+
+func TestOpenBus(t *testing.T) {
+	cpu, _ := run(t, []byte{
+		0xAD, 0x00, 0x40, // LDA $4000, which is write only
+		0xA9, 0x00, // LDA #$00
+		0x85, 0x10, // STA $10
+		0xAE, 0x1F, 0x40, // LDX $401F, a disabled test register
+		0xAC, 0x16, 0x40, // LDY $4016 with no controllers connected
+	}, func(cpu *Cpu, mem *flatMemory) {
+		cpu.ConnectApu(mem)
+		mem[0x4000] = 0x99
+		mem[0x401F] = 0x99
+	})
+
+	// Each read sees the high byte of its own operand, the last byte read
+	if cpu.a != 0x00 || cpu.x != 0x40 || cpu.y != 0x40 {
+		t.Errorf("A = %02X, X = %02X, Y = %02X, want 00, 40 and 40", cpu.a, cpu.x, cpu.y)
+	}
+}
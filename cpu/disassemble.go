@@ -0,0 +1,79 @@
+package cpu
+
+import "fmt"
+
+var instructionLengths = map[int]int{
+	MODE_IMPLIED:        1,
+	MODE_ACCUMULATOR:    1,
+	MODE_IMMEDIATE:      2,
+	MODE_ZERO_PAGE:      2,
+	MODE_ABSOLUTE:       3,
+	MODE_RELATIVE:       2,
+	MODE_INDIRECT:       3,
+	MODE_INDEX_INDIRECT: 2,
+	MODE_INDIRECT_INDEX: 2,
+}
+
+// InstructionLength returns the size in bytes of the instruction starting
+// with opcode. Unrecognized opcodes are treated as a single data byte.
+func InstructionLength(opcode byte) int {
+	instruction, ok := instructions[opcode]
+	if !ok {
+		return 1
+	}
+
+	return instructionLengths[instruction.mode]
+}
+
+// Disassemble decodes the instruction at the start of code, which is located
+// at address, and returns its assembly text and length in bytes.
+func Disassemble(address uint16, code []byte) (string, int) {
+	if len(code) == 0 {
+		return "", 0
+	}
+
+	instruction, ok := instructions[code[0]]
+	length := InstructionLength(code[0])
+	if !ok || len(code) < length {
+		return fmt.Sprintf(".byte $%02X", code[0]), 1
+	}
+
+	var operand uint16
+	if length == 2 {
+		operand = uint16(code[1])
+	} else if length == 3 {
+		operand = uint16(code[2])<<8 | uint16(code[1])
+	}
+
+	index := ""
+	if instruction.reg == REG_X {
+		index = ",X"
+	} else if instruction.reg == REG_Y {
+		index = ",Y"
+	}
+
+	var text string
+	switch instruction.mode {
+	case MODE_ACCUMULATOR:
+		text = instruction.neumonic + " A"
+	case MODE_IMMEDIATE:
+		text = fmt.Sprintf("%s #$%02X", instruction.neumonic, operand)
+	case MODE_ZERO_PAGE:
+		text = fmt.Sprintf("%s $%02X%s", instruction.neumonic, operand, index)
+	case MODE_ABSOLUTE:
+		text = fmt.Sprintf("%s $%04X%s", instruction.neumonic, operand, index)
+	case MODE_RELATIVE:
+		target := address + 2 + uint16(int8(operand))
+		text = fmt.Sprintf("%s $%04X", instruction.neumonic, target)
+	case MODE_INDIRECT:
+		text = fmt.Sprintf("%s ($%04X)", instruction.neumonic, operand)
+	case MODE_INDEX_INDIRECT:
+		text = fmt.Sprintf("%s ($%02X,X)", instruction.neumonic, operand)
+	case MODE_INDIRECT_INDEX:
+		text = fmt.Sprintf("%s ($%02X),Y", instruction.neumonic, operand)
+	default:
+		text = instruction.neumonic
+	}
+
+	return text, length
+}
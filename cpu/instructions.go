@@ -17,152 +17,146 @@ const (
 
 type addressMode struct{ mode, reg int }
 
+// Handlers are passed the operand for immediate instructions, the target
+// for branches and jumps, and the effective address for everything else.
+// The program counter has already moved past the instruction.
 type instruction struct {
 	handler func(cpu *Cpu, arg uint16, mode addressMode) error
 	addressMode
 	neumonic string
 }
 
-func adc(cpu *Cpu, arg uint16, mode addressMode) error {
-	var result, carryVal uint16
+// operand reads the value an instruction works on, which is the argument
+// itself in immediate mode.
+func (cpu *Cpu) operand(arg uint16, mode addressMode) byte {
+	if mode.mode == MODE_IMMEDIATE {
+		return byte(arg)
+	}
+	return cpu.byteAt(arg)
+}
 
-	if cpu.carryFl {
-		carryVal = 1
-	} else {
-		carryVal = 0
+func (cpu *Cpu) setZeroSign(value byte) {
+	cpu.zeroFl = value == 0
+	cpu.signFl = int8(value) < 0
+}
+
+// branch jumps to target if taken, which costs a cycle, or two if the target
+// is on another page.
+func (cpu *Cpu) branch(taken bool, target uint16) {
+	if !taken {
+		return
 	}
 
-	if mode.mode == MODE_IMMEDIATE {
-		result = carryVal + uint16(cpu.a) + arg
-		cpu.overflowFl = ((cpu.a ^ byte(result)) & (byte(arg) ^ byte(result)) & 0x80) != 0
-	} else {
-		result = carryVal + uint16(cpu.a) + uint16(cpu.byteAt(arg))
-		cpu.overflowFl = ((cpu.a ^ byte(result)) & (cpu.byteAt(arg) ^ byte(result)) & 0x80) != 0
+	cpu.cycles++
+	if cpu.pc&0xFF00 != target&0xFF00 {
+		cpu.cycles++
 	}
+	cpu.pc = target
+}
 
-	cpu.a = byte(result)
+func (cpu *Cpu) compare(register byte, arg uint16, mode addressMode) {
+	operand := cpu.operand(arg, mode)
+	cpu.carryFl = register >= operand
+	cpu.setZeroSign(register - operand)
+}
+
+func adc(cpu *Cpu, arg uint16, mode addressMode) error {
+	operand := cpu.operand(arg, mode)
+
+	result := uint16(cpu.a) + uint16(operand)
+	if cpu.carryFl {
+		result++
+	}
 
-	cpu.carryFl = (result > 0xFF)
-	cpu.zeroFl = (cpu.a == 0)
-	cpu.signFl = (int8(cpu.a) < 0)
+	cpu.overflowFl = ((cpu.a ^ byte(result)) & (operand ^ byte(result)) & 0x80) != 0
+	cpu.carryFl = result > 0xFF
+	cpu.a = byte(result)
+	cpu.setZeroSign(cpu.a)
 
 	return nil
 }
 
 func and(cpu *Cpu, arg uint16, mode addressMode) error {
-	if mode.mode == MODE_IMMEDIATE {
-		cpu.a &= byte(arg)
-	} else {
-		cpu.a &= cpu.byteAt(arg)
-	}
-
-	cpu.zeroFl = (cpu.a == 0)
-	cpu.signFl = (int8(cpu.a) < 0)
+	cpu.a &= cpu.operand(arg, mode)
+	cpu.setZeroSign(cpu.a)
 
 	return nil
 }
 
 func asl(cpu *Cpu, arg uint16, mode addressMode) error {
-	var oldCarry byte = 0x0
-	if cpu.carryFl {
-		oldCarry = 0x1
-	}
-
 	if mode.mode == MODE_ACCUMULATOR {
 		cpu.carryFl = cpu.a&0x80 > 0
 		cpu.a <<= 1
-		cpu.a |= oldCarry
+		cpu.setZeroSign(cpu.a)
 
 		return nil
+	}
 
-	} else {
-		target := cpu.byteAt(arg)
-		cpu.carryFl = target&0x80 > 0
-		target <<= 1
-		target |= oldCarry
+	target := cpu.byteAt(arg)
+	cpu.carryFl = target&0x80 > 0
+	target <<= 1
+	cpu.setZeroSign(target)
 
-		err := cpu.writeByte(arg, target)
-		return err
-	}
+	return cpu.writeByte(arg, target)
 }
 
 func bcc(cpu *Cpu, arg uint16, mode addressMode) error {
-	if !cpu.carryFl {
-		cpu.pc = arg
-	}
-
+	cpu.branch(!cpu.carryFl, arg)
 	return nil
 }
 
 func bcs(cpu *Cpu, arg uint16, mode addressMode) error {
-	if cpu.carryFl {
-		cpu.pc = arg
-	}
-
+	cpu.branch(cpu.carryFl, arg)
 	return nil
 }
 
 func beq(cpu *Cpu, arg uint16, mode addressMode) error {
-	if cpu.zeroFl {
-		cpu.pc = arg
-	}
-
+	cpu.branch(cpu.zeroFl, arg)
 	return nil
 }
 
 func bit(cpu *Cpu, arg uint16, mode addressMode) error {
 	target := cpu.byteAt(arg)
 
-	result := cpu.a & target
-
-	cpu.zeroFl = result == 0
-	cpu.overflowFl = target&0x80 > 0
-	cpu.signFl = target&0xF0 > 0
+	cpu.zeroFl = cpu.a&target == 0
+	cpu.overflowFl = target&0x40 > 0
+	cpu.signFl = target&0x80 > 0
 
 	return nil
 }
 
 func bmi(cpu *Cpu, arg uint16, mode addressMode) error {
-	if cpu.signFl {
-		cpu.pc = arg
-	}
-
+	cpu.branch(cpu.signFl, arg)
 	return nil
 }
 
 func bne(cpu *Cpu, arg uint16, mode addressMode) error {
-	if !cpu.zeroFl {
-		cpu.pc = arg
-	}
-
+	cpu.branch(!cpu.zeroFl, arg)
 	return nil
 }
 
 func bpl(cpu *Cpu, arg uint16, mode addressMode) error {
-	if !cpu.signFl {
-		cpu.pc = arg
-	}
-
+	cpu.branch(!cpu.signFl, arg)
 	return nil
 }
 
+// brk is a software IRQ. It skips the byte after the opcode and pushes the
+// status with the B flag set so the handler can tell it from a real IRQ.
 func brk(cpu *Cpu, arg uint16, mode addressMode) error {
+	cpu.pushWordToStack(cpu.pc + 1)
+	cpu.pushByteToStack(cpu.getStatusFlagsByte() | STATUS_BREAK)
+	cpu.interruptFl = true
+	cpu.pc = cpu.wordAt(IRQ_VECTOR)
 	return nil
 }
 
 func bvc(cpu *Cpu, arg uint16, mode addressMode) error {
-	if !cpu.overflowFl {
-		cpu.pc = arg
-	}
-
+	cpu.branch(!cpu.overflowFl, arg)
 	return nil
 }
 
 func bvs(cpu *Cpu, arg uint16, mode addressMode) error {
-	if cpu.overflowFl {
-		cpu.pc = arg
-	}
-
+	cpu.branch(cpu.overflowFl, arg)
 	return nil
 }
 
@@ -187,103 +181,61 @@ func clv(cpu *Cpu, arg uint16, mode addressMode) error {
 }
 
 func cmp(cpu *Cpu, arg uint16, mode addressMode) error {
-	var result int8
-	if mode.mode == MODE_IMMEDIATE {
-		result = int8(cpu.a - byte(arg))
-	} else {
-		result = int8(cpu.a - cpu.byteAt(arg))
-	}
-
-	cpu.carryFl = (result >= 0)
-	cpu.signFl = (result < 0)
-	cpu.zeroFl = (result == 0)
-
+	cpu.compare(cpu.a, arg, mode)
 	return nil
 }
 
 func cpx(cpu *Cpu, arg uint16, mode addressMode) error {
-	var result int8
-	if mode.mode == MODE_IMMEDIATE {
-		result = int8(cpu.x - byte(arg))
-	} else {
-		result = int8(cpu.x - cpu.byteAt(arg))
-	}
-
-	cpu.carryFl = (result >= 0)
-	cpu.signFl = (result < 0)
-	cpu.zeroFl = (result == 0)
-
+	cpu.compare(cpu.x, arg, mode)
 	return nil
 }
 
 func cpy(cpu *Cpu, arg uint16, mode addressMode) error {
-	var result int8
-	if mode.mode == MODE_IMMEDIATE {
-		result = int8(cpu.y - byte(arg))
-	} else {
-		result = int8(cpu.y - cpu.byteAt(arg))
-	}
-
-	cpu.carryFl = (result >= 0)
-	cpu.signFl = (result < 0)
-	cpu.zeroFl = (result == 0)
-
+	cpu.compare(cpu.y, arg, mode)
 	return nil
 }
 
 func dec(cpu *Cpu, arg uint16, mode addressMode) error {
 	data := cpu.byteAt(arg) - 1
-	cpu.zeroFl = data == 0
-	cpu.signFl = int8(data) < 0
+	cpu.setZeroSign(data)
 	cpu.writeByte(arg, data)
 	return nil
 }
 
 func dex(cpu *Cpu, arg uint16, mode addressMode) error {
 	cpu.x -= 1
-	cpu.zeroFl = cpu.x == 0
-	cpu.signFl = int8(cpu.x) < 0
+	cpu.setZeroSign(cpu.x)
 	return nil
 }
 
 func dey(cpu *Cpu, arg uint16, mode addressMode) error {
 	cpu.y -= 1
-	cpu.zeroFl = cpu.y == 0
-	cpu.signFl = int8(cpu.y) < 0
+	cpu.setZeroSign(cpu.y)
 	return nil
 }
 
 func eor(cpu *Cpu, arg uint16, mode addressMode) error {
-	if mode.mode == MODE_IMMEDIATE {
-		cpu.a ^= byte(arg)
-	} else {
-		cpu.a ^= cpu.byteAt(arg)
-	}
-
-	cpu.zeroFl = cpu.a == 0
-	cpu.signFl = int8(cpu.a) < 0
+	cpu.a ^= cpu.operand(arg, mode)
+	cpu.setZeroSign(cpu.a)
 	return nil
 }
 
 func inc(cpu *Cpu, arg uint16, mode addressMode) error {
 	data := cpu.byteAt(arg) + 1
-	cpu.zeroFl = data == 0
-	cpu.signFl = int8(data) < 0
+	cpu.setZeroSign(data)
 	cpu.writeByte(arg, data)
 	return nil
 }
 
 func inx(cpu *Cpu, arg uint16, mode addressMode) error {
 	cpu.x += 1
-	cpu.zeroFl = cpu.x == 0
-	cpu.signFl = int8(cpu.x) < 0
+	cpu.setZeroSign(cpu.x)
 	return nil
 }
 
 func iny(cpu *Cpu, arg uint16, mode addressMode) error {
 	cpu.y += 1
-	cpu.zeroFl = cpu.y == 0
-	cpu.signFl = int8(cpu.y) < 0
+	cpu.setZeroSign(cpu.y)
 	return nil
 }
 
@@ -292,66 +244,46 @@ func jmp(cpu *Cpu, arg uint16, mode addressMode) error {
 	return nil
 }
 
+// jsr pushes the address of its own last byte, which RTS adds one to.
 func jsr(cpu *Cpu, arg uint16, mode addressMode) error {
-	cpu.pushWordToStack(cpu.pc)
+	cpu.pushWordToStack(cpu.pc - 1)
 	cpu.pc = arg
 	return nil
 }
 
 func lda(cpu *Cpu, arg uint16, mode addressMode) error {
-	if mode.mode == MODE_IMMEDIATE {
-		cpu.a = byte(arg)
-	} else {
-		cpu.a = cpu.byteAt(arg)
-	}
-
-	cpu.zeroFl = cpu.a == 0
-	cpu.signFl = int8(cpu.a) < 0
-
+	cpu.a = cpu.operand(arg, mode)
+	cpu.setZeroSign(cpu.a)
 	return nil
 }
 
 func ldx(cpu *Cpu, arg uint16, mode addressMode) error {
-	if mode.mode == MODE_IMMEDIATE {
-		cpu.x = byte(arg)
-	} else {
-		cpu.x = cpu.byteAt(arg)
-	}
-
-	cpu.zeroFl = cpu.x == 0
-	cpu.signFl = int8(cpu.x) < 0
-
+	cpu.x = cpu.operand(arg, mode)
+	cpu.setZeroSign(cpu.x)
 	return nil
 }
 
 func ldy(cpu *Cpu, arg uint16, mode addressMode) error {
-	if mode.mode == MODE_IMMEDIATE {
-		cpu.y = byte(arg)
-	} else {
-		cpu.y = cpu.byteAt(arg)
-	}
-
-	cpu.zeroFl = cpu.y == 0
-	cpu.signFl = int8(cpu.y) < 0
-
+	cpu.y = cpu.operand(arg, mode)
+	cpu.setZeroSign(cpu.y)
 	return nil
 }
 
 func lsr(cpu *Cpu, arg uint16, mode addressMode) error {
-	var result byte
 	if mode.mode == MODE_ACCUMULATOR {
 		cpu.carryFl = cpu.a&0x1 > 0
-		result = cpu.a >> 1
-		cpu.a = result
-	} else {
-		data := cpu.byteAt(arg)
-		cpu.carryFl = data&0x1 > 0
-		result = data >> 1
-		cpu.writeByte(arg, result)
+		cpu.a >>= 1
+		cpu.setZeroSign(cpu.a)
+
+		return nil
 	}
 
-	cpu.zeroFl = result == 0
-	cpu.signFl = result < 0
+	data := cpu.byteAt(arg)
+	cpu.carryFl = data&0x1 > 0
+	data >>= 1
+	cpu.setZeroSign(data)
+
+	cpu.writeByte(arg, data)
 	return nil
 }
 
@@ -361,46 +293,29 @@ func nop(cpu *Cpu, arg uint16, mode addressMode) error {
 }
 
 func ora(cpu *Cpu, arg uint16, mode addressMode) error {
-	var operand byte
-
-	if mode.mode == MODE_IMMEDIATE {
-		operand = byte(arg)
-	} else {
-		operand = cpu.byteAt(arg)
-	}
-
-	cpu.a |= operand
-	cpu.zeroFl = (cpu.a == 0)
-	cpu.signFl = (int8(cpu.a) < 0)
-
+	cpu.a |= cpu.operand(arg, mode)
+	cpu.setZeroSign(cpu.a)
 	return nil
 }
 
 func pha(cpu *Cpu, arg uint16, mode addressMode) error {
-	err := cpu.pushByteToStack(cpu.a)
-	return err
+	cpu.pushByteToStack(cpu.a)
+	return nil
 }
 
 func php(cpu *Cpu, arg uint16, mode addressMode) error {
-	statusFlagsByte := cpu.getStatusFlagsByte()
-	statusFlagsByte &= 0x10 // Set B flag
-	err := cpu.pushByteToStack(statusFlagsByte)
-	return err
+	cpu.pushByteToStack(cpu.getStatusFlagsByte() | STATUS_BREAK)
+	return nil
 }
 
 func pla(cpu *Cpu, arg uint16, mode addressMode) error {
-	var err error
-	cpu.a, err = cpu.pullByteFromStack()
-	return err
+	cpu.a = cpu.pullByteFromStack()
+	cpu.setZeroSign(cpu.a)
+	return nil
 }
 
 func plp(cpu *Cpu, arg uint16, mode addressMode) error {
-	statusFlagsByte, err := cpu.pullByteFromStack()
-	if err != nil {
-		return err
-	}
-
-	cpu.restoreStatusFlags(statusFlagsByte)
+	cpu.restoreStatusFlags(cpu.pullByteFromStack())
 	return nil
 }
 
@@ -412,21 +327,18 @@ func rol(cpu *Cpu, arg uint16, mode addressMode) error {
 
 	if mode.mode == MODE_ACCUMULATOR {
 		cpu.carryFl = cpu.a&0x80 > 0
-		cpu.a <<= 1
-		cpu.a |= oldCarry
-		cpu.zeroFl = cpu.a == 0
-		cpu.signFl = int8(cpu.a) < 0
-
-	} else {
-		target := cpu.byteAt(arg)
-		cpu.carryFl = target&0x80 > 0
-		target <<= 1
-		target |= oldCarry
-		cpu.zeroFl = target == 0
-		cpu.signFl = int8(target) < 0
-		cpu.writeByte(arg, target)
+		cpu.a = cpu.a<<1 | oldCarry
+		cpu.setZeroSign(cpu.a)
+
+		return nil
 	}
 
+	target := cpu.byteAt(arg)
+	cpu.carryFl = target&0x80 > 0
+	target = target<<1 | oldCarry
+	cpu.setZeroSign(target)
+
+	cpu.writeByte(arg, target)
 	return nil
 }
 
@@ -438,72 +350,44 @@ func ror(cpu *Cpu, arg uint16, mode addressMode) error {
 
 	if mode.mode == MODE_ACCUMULATOR {
 		cpu.carryFl = cpu.a&0x01 > 0
-		cpu.a >>= 1
-		cpu.a |= oldCarry
-		cpu.zeroFl = cpu.a == 0
-		cpu.signFl = int8(cpu.a) < 0
-
-	} else {
-		target := cpu.byteAt(arg)
-		cpu.carryFl = target&0x01 > 0
-		target >>= 1
-		target |= oldCarry
-		cpu.zeroFl = target == 0
-		cpu.signFl = int8(target) < 0
-		cpu.writeByte(arg, target)
+		cpu.a = cpu.a>>1 | oldCarry
+		cpu.setZeroSign(cpu.a)
+
+		return nil
 	}
 
+	target := cpu.byteAt(arg)
+	cpu.carryFl = target&0x01 > 0
+	target = target>>1 | oldCarry
+	cpu.setZeroSign(target)
+
+	cpu.writeByte(arg, target)
 	return nil
 }
 
 func rti(cpu *Cpu, arg uint16, mode addressMode) error {
-	statusFlagsByte, err := cpu.pullByteFromStack()
-	if err != nil {
-		return err
-	}
-
-	cpu.restoreStatusFlags(statusFlagsByte)
-	savedPC, err := cpu.pullWordFromStack()
-	if err != nil {
-		return err
-	}
-
-	cpu.pc = savedPC
+	cpu.restoreStatusFlags(cpu.pullByteFromStack())
+	cpu.pc = cpu.pullWordFromStack()
 	return nil
 }
 
 func rts(cpu *Cpu, arg uint16, mode addressMode) error {
-	savedPC, err := cpu.pullWordFromStack()
-	if err != nil {
-		return err
-	}
-
-	cpu.pc = savedPC
+	cpu.pc = cpu.pullWordFromStack() + 1
 	return nil
 }
 
 func sbc(cpu *Cpu, arg uint16, mode addressMode) error {
-	var result, carryVal uint16
-
-	if cpu.carryFl {
-		carryVal = 1
-	} else {
-		carryVal = 0
-	}
+	operand := cpu.operand(arg, mode)
 
-	if mode.mode == MODE_IMMEDIATE {
-		result = uint16(cpu.a) - arg - (1 - carryVal)
-		cpu.overflowFl = ((cpu.a ^ byte(result)) & (byte(arg) ^ byte(result)) & 0x80) != 0
-	} else {
-		result = uint16(cpu.a) - uint16(cpu.byteAt(arg)) - (1 - carryVal)
-		cpu.overflowFl = ((cpu.a ^ byte(result)) & (cpu.byteAt(arg) ^ byte(result)) & 0x80) != 0
+	result := uint16(cpu.a) - uint16(operand)
+	if !cpu.carryFl {
+		result--
 	}
 
+	cpu.overflowFl = ((cpu.a ^ operand) & (cpu.a ^ byte(result)) & 0x80) != 0
+	cpu.carryFl = result <= 0xFF // No borrow
 	cpu.a = byte(result)
-
-	cpu.carryFl = (result <= 0xFF)
-	cpu.zeroFl = (cpu.a == 0)
-	cpu.signFl = (int8(cpu.a) < 0)
+	cpu.setZeroSign(cpu.a)
 
 	return nil
 }
@@ -540,29 +424,25 @@ func sty(cpu *Cpu, arg uint16, mode addressMode) error {
 
 func tax(cpu *Cpu, arg uint16, mode addressMode) error {
 	cpu.x = cpu.a
-	cpu.zeroFl = cpu.x == 0
-	cpu.signFl = int8(cpu.x) < 0
+	cpu.setZeroSign(cpu.x)
 	return nil
 }
 
 func tay(cpu *Cpu, arg uint16, mode addressMode) error {
 	cpu.y = cpu.a
-	cpu.zeroFl = cpu.y == 0
-	cpu.signFl = int8(cpu.y) < 0
+	cpu.setZeroSign(cpu.y)
 	return nil
 }
 
 func tsx(cpu *Cpu, arg uint16, mode addressMode) error {
 	cpu.x = cpu.sp
-	cpu.zeroFl = cpu.x == 0
-	cpu.signFl = int8(cpu.x) < 0
+	cpu.setZeroSign(cpu.x)
 	return nil
 }
 
 func txa(cpu *Cpu, arg uint16, mode addressMode) error {
 	cpu.a = cpu.x
-	cpu.zeroFl = cpu.a == 0
-	cpu.signFl = int8(cpu.a) < 0
+	cpu.setZeroSign(cpu.a)
 	return nil
 }
 
@@ -573,8 +453,7 @@ func txs(cpu *Cpu, arg uint16, mode addressMode) error {
 
 func tya(cpu *Cpu, arg uint16, mode addressMode) error {
 	cpu.a = cpu.y
-	cpu.zeroFl = cpu.a == 0
-	cpu.signFl = int8(cpu.a) < 0
+	cpu.setZeroSign(cpu.a)
 	return nil
 }
 
@@ -590,7 +469,7 @@ var instructions = map[byte]instruction{
 	0x0A: instruction{asl, addressMode{MODE_ACCUMULATOR, REG_NONE}, "ASL"},
 	0x0D: instruction{ora, addressMode{MODE_ABSOLUTE, REG_NONE}, "ORA"},
 	0x0E: instruction{asl, addressMode{MODE_ABSOLUTE, REG_NONE}, "ASL"},
-	0x10: instruction{bpl, addressMode{MODE_IMPLIED, REG_NONE}, "BPL"},
+	0x10: instruction{bpl, addressMode{MODE_RELATIVE, REG_NONE}, "BPL"},
 	0x11: instruction{ora, addressMode{MODE_INDIRECT_INDEX, REG_Y}, "ORA"},
 	0x15: instruction{ora, addressMode{MODE_ZERO_PAGE, REG_X}, "ORA"},
 	0x16: instruction{asl, addressMode{MODE_ZERO_PAGE, REG_X}, "ASL"},
@@ -598,7 +477,7 @@ var instructions = map[byte]instruction{
 	0x19: instruction{ora, addressMode{MODE_ABSOLUTE, REG_Y}, "ORA"},
 	0x1D: instruction{ora, addressMode{MODE_ABSOLUTE, REG_X}, "ORA"},
 	0x1E: instruction{asl, addressMode{MODE_ABSOLUTE, REG_X}, "ASL"},
-	0x20: instruction{jsr, addressMode{MODE_IMPLIED, REG_NONE}, "JSR"},
+	0x20: instruction{jsr, addressMode{MODE_ABSOLUTE, REG_NONE}, "JSR"},
 	0x21: instruction{and, addressMode{MODE_INDEX_INDIRECT, REG_X}, "AND"},
 	0x24: instruction{bit, addressMode{MODE_ZERO_PAGE, REG_NONE}, "BIT"},
 	0x25: instruction{and, addressMode{MODE_ZERO_PAGE, REG_NONE}, "AND"},
@@ -609,7 +488,7 @@ var instructions = map[byte]instruction{
 	0x2C: instruction{bit, addressMode{MODE_ABSOLUTE, REG_NONE}, "BIT"},
 	0x2D: instruction{and, addressMode{MODE_ABSOLUTE, REG_NONE}, "AND"},
 	0x2E: instruction{rol, addressMode{MODE_ABSOLUTE, REG_NONE}, "ROL"},
-	0x30: instruction{bmi, addressMode{MODE_IMPLIED, REG_NONE}, "BMI"},
+	0x30: instruction{bmi, addressMode{MODE_RELATIVE, REG_NONE}, "BMI"},
 	0x31: instruction{and, addressMode{MODE_INDIRECT_INDEX, REG_Y}, "AND"},
 	0x35: instruction{and, addressMode{MODE_ZERO_PAGE, REG_X}, "AND"},
 	0x36: instruction{rol, addressMode{MODE_ZERO_PAGE, REG_X}, "ROL"},
@@ -627,7 +506,7 @@ var instructions = map[byte]instruction{
 	0x4C: instruction{jmp, addressMode{MODE_ABSOLUTE, REG_NONE}, "JMP"},
 	0x4D: instruction{eor, addressMode{MODE_ABSOLUTE, REG_NONE}, "EOR"},
 	0x4E: instruction{lsr, addressMode{MODE_ABSOLUTE, REG_NONE}, "LSR"},
-	0x50: instruction{bvc, addressMode{MODE_IMPLIED, REG_NONE}, "BVC"},
+	0x50: instruction{bvc, addressMode{MODE_RELATIVE, REG_NONE}, "BVC"},
 	0x51: instruction{eor, addressMode{MODE_INDIRECT_INDEX, REG_Y}, "EOR"},
 	0x55: instruction{eor, addressMode{MODE_ZERO_PAGE, REG_X}, "EOR"},
 	0x56: instruction{lsr, addressMode{MODE_ZERO_PAGE, REG_X}, "LSR"},
@@ -645,7 +524,7 @@ var instructions = map[byte]instruction{
 	0x6C: instruction{jmp, addressMode{MODE_INDIRECT, REG_NONE}, "JMP"},
 	0x6D: instruction{adc, addressMode{MODE_ABSOLUTE, REG_NONE}, "ADC"},
 	0x6E: instruction{ror, addressMode{MODE_ABSOLUTE, REG_NONE}, "ROR"},
-	0x70: instruction{bvs, addressMode{MODE_IMPLIED, REG_NONE}, "BVS"},
+	0x70: instruction{bvs, addressMode{MODE_RELATIVE, REG_NONE}, "BVS"},
 	0x71: instruction{adc, addressMode{MODE_INDIRECT_INDEX, REG_Y}, "ADC"},
 	0x75: instruction{adc, addressMode{MODE_ZERO_PAGE, REG_X}, "ADC"},
 	0x76: instruction{ror, addressMode{MODE_ZERO_PAGE, REG_X}, "ROR"},
@@ -662,7 +541,7 @@ var instructions = map[byte]instruction{
 	0x8C: instruction{sty, addressMode{MODE_ABSOLUTE, REG_NONE}, "STY"},
 	0x8D: instruction{sta, addressMode{MODE_ABSOLUTE, REG_NONE}, "STA"},
 	0x8E: instruction{stx, addressMode{MODE_ABSOLUTE, REG_NONE}, "STX"},
-	0x90: instruction{bcc, addressMode{MODE_IMPLIED, REG_NONE}, "BCC"},
+	0x90: instruction{bcc, addressMode{MODE_RELATIVE, REG_NONE}, "BCC"},
 	0x91: instruction{sta, addressMode{MODE_INDIRECT_INDEX, REG_Y}, "STA"},
 	0x94: instruction{sty, addressMode{MODE_ZERO_PAGE, REG_X}, "STY"},
 	0x95: instruction{sta, addressMode{MODE_ZERO_PAGE, REG_X}, "STA"},
@@ -683,7 +562,7 @@ var instructions = map[byte]instruction{
 	0xAC: instruction{ldy, addressMode{MODE_ABSOLUTE, REG_NONE}, "LDY"},
 	0xAD: instruction{lda, addressMode{MODE_ABSOLUTE, REG_NONE}, "LDA"},
 	0xAE: instruction{ldx, addressMode{MODE_ABSOLUTE, REG_NONE}, "LDX"},
-	0xB0: instruction{bcs, addressMode{MODE_IMPLIED, REG_NONE}, "BCS"},
+	0xB0: instruction{bcs, addressMode{MODE_RELATIVE, REG_NONE}, "BCS"},
 	0xB1: instruction{lda, addressMode{MODE_INDIRECT_INDEX, REG_Y}, "LDA"},
 	0xB4: instruction{ldy, addressMode{MODE_ZERO_PAGE, REG_X}, "LDY"},
 	0xB5: instruction{lda, addressMode{MODE_ZERO_PAGE, REG_X}, "LDA"},
@@ -705,7 +584,7 @@ var instructions = map[byte]instruction{
 	0xCC: instruction{cpy, addressMode{MODE_ABSOLUTE, REG_NONE}, "CPY"},
 	0xCD: instruction{cmp, addressMode{MODE_ABSOLUTE, REG_NONE}, "CMP"},
 	0xCE: instruction{dec, addressMode{MODE_ABSOLUTE, REG_NONE}, "DEC"},
-	0xD0: instruction{bne, addressMode{MODE_IMPLIED, REG_NONE}, "BNE"},
+	0xD0: instruction{bne, addressMode{MODE_RELATIVE, REG_NONE}, "BNE"},
 	0xD1: instruction{cmp, addressMode{MODE_INDIRECT_INDEX, REG_Y}, "CMP"},
 	0xD5: instruction{cmp, addressMode{MODE_ZERO_PAGE, REG_X}, "CMP"},
 	0xD6: instruction{dec, addressMode{MODE_ZERO_PAGE, REG_X}, "DEC"},
@@ -724,7 +603,7 @@ var instructions = map[byte]instruction{
 	0xEC: instruction{cpx, addressMode{MODE_ABSOLUTE, REG_NONE}, "CPX"},
 	0xED: instruction{sbc, addressMode{MODE_ABSOLUTE, REG_NONE}, "SBC"},
 	0xEE: instruction{inc, addressMode{MODE_ABSOLUTE, REG_NONE}, "INC"},
-	0xF0: instruction{beq, addressMode{MODE_IMPLIED, REG_NONE}, "BEQ"},
+	0xF0: instruction{beq, addressMode{MODE_RELATIVE, REG_NONE}, "BEQ"},
 	0xF1: instruction{sbc, addressMode{MODE_INDIRECT_INDEX, REG_Y}, "SBC"},
 	0xF5: instruction{sbc, addressMode{MODE_ZERO_PAGE, REG_X}, "SBC"},
 	0xF6: instruction{inc, addressMode{MODE_ZERO_PAGE, REG_X}, "INC"},
@@ -7,6 +7,20 @@ import (
 
 const CPU_RAM_SZ int = 2048
 
+const (
+	NMI_VECTOR   uint16 = 0xFFFA
+	RESET_VECTOR uint16 = 0xFFFC
+	IRQ_VECTOR   uint16 = 0xFFFE
+)
+
+// Bits 4 and 5 of the status byte only exist on the stack. Bit 5 is always
+// set, and bit 4 (B) is set when pushed by BRK or PHP rather than an
+// interrupt.
+const (
+	STATUS_BREAK  byte = 0x10
+	STATUS_UNUSED byte = 0x20
+)
+
 type Cpu struct {
 	a, x, y, p, sp               byte
 	carryFl, zeroFl, interruptFl bool
@@ -14,7 +28,13 @@ type Cpu struct {
 	decimalFl                    bool
 	pc                           uint16
 	ram                          [CPU_RAM_SZ]byte
-	cartridge, ppu, apu          types.MappedHardware
+	cartridge, ppu, apu, input   types.MappedHardware
+	executeHook                  func(address uint16)
+	irqLine                      func() bool
+	busHook                      func(cycle uint64)
+	nmiPending                   bool
+	cycles                       uint64
+	dataBus                      byte // Last byte read or written, for open bus
 }
 
 func (cpu *Cpu) String() string {
@@ -26,35 +46,148 @@ func (cpu *Cpu) LoadProgram(cartridge types.MappedHardware) {
 	cpu.pc = 0x8000
 }
 
+// Reset jumps through the reset vector with interrupts disabled. Like the
+// real CPU it goes through the motions of an interrupt without writing to
+// the stack, so the stack pointer drops by three.
+func (cpu *Cpu) Reset() {
+	cpu.sp -= 3
+	cpu.interruptFl = true
+	cpu.pc = cpu.wordAt(RESET_VECTOR)
+	cpu.cycles += 7
+}
+
+func (cpu *Cpu) ConnectPpu(ppu types.MappedHardware) {
+	cpu.ppu = ppu
+}
+
+// ConnectApu attaches the audio registers at $4000-$4013, $4015 and $4017.
+func (cpu *Cpu) ConnectApu(apu types.MappedHardware) {
+	cpu.apu = apu
+}
+
+// ConnectInput attaches the controller ports at $4016 and $4017.
+func (cpu *Cpu) ConnectInput(input types.MappedHardware) {
+	cpu.input = input
+}
+
+// Ram returns the CPU's internal 2KB of RAM.
+func (cpu *Cpu) Ram() []byte {
+	return cpu.ram[:]
+}
+
+// Cycles returns the number of CPU cycles elapsed since power on.
+func (cpu *Cpu) Cycles() uint64 {
+	return cpu.cycles
+}
+
+// TriggerNmi signals a non-maskable interrupt, which is serviced before the
+// next instruction.
+func (cpu *Cpu) TriggerNmi() {
+	cpu.nmiPending = true
+}
+
+// SetIrqLine registers the function that reports whether any device is
+// holding the IRQ line low. IRQs are level triggered, so it's polled before
+// every instruction while interrupts are enabled.
+func (cpu *Cpu) SetIrqLine(line func() bool) {
+	cpu.irqLine = line
+}
+
+// Stall halts the CPU for a number of cycles, as when the DMC borrows the
+// bus to fetch a sample.
+func (cpu *Cpu) Stall(cycles int) {
+	cpu.cycles += uint64(cycles)
+}
+
+// ReadMemory reads a byte from the CPU bus on behalf of another device, with
+// the same side effects as a CPU read. Devices read while they're being
+// caught up, so the bus hook isn't called.
+func (cpu *Cpu) ReadMemory(address uint16) byte {
+	hook := cpu.busHook
+	cpu.busHook = nil
+	data := cpu.byteAt(address)
+	cpu.busHook = hook
+	return data
+}
+
+// SetBusHook registers a function that is called before the CPU reads or
+// writes a PPU, APU or I/O register, with the cycle the access happens on,
+// so that the other devices can be caught up to that cycle first. Cycles()
+// is already at the end of the instruction by then.
+func (cpu *Cpu) SetBusHook(hook func(cycle uint64)) {
+	cpu.busHook = hook
+}
+
+// Accesses happen on the last cycle of an instruction, except for the extra
+// cycles of an OAM DMA, which come after its write.
+func (cpu *Cpu) syncBus(address uint16) {
+	if cpu.busHook != nil && address >= 0x2000 && address < 0x4020 {
+		cpu.busHook(cpu.cycles - 1)
+	}
+}
+
+// SetExecuteHook registers a function that is called with the address of
+// every instruction before it is executed.
+func (cpu *Cpu) SetExecuteHook(hook func(address uint16)) {
+	cpu.executeHook = hook
+}
+
+// byteAt reads from whichever device decodes address. Reads that no device
+// answers see open bus, the last byte the CPU read or wrote.
 func (cpu *Cpu) byteAt(address uint16) byte {
+	cpu.syncBus(address)
+
 	if address < 0x2000 {
-		return cpu.ram[address%0x800]
+		cpu.dataBus = cpu.ram[address%0x800]
+		return cpu.dataBus
+	}
+
+	var device types.MappedHardware
+	if address < 0x4000 {
+		device = cpu.ppu
+	} else if address == 0x4015 {
+		device = cpu.apu
+	} else if address == 0x4016 || address == 0x4017 {
+		device = cpu.input
 	} else if address >= 0x4020 {
-		data, err := cpu.cartridge.ReadByte(address)
+		device = cpu.cartridge
+	} else {
+		// The APU's other registers are write only and $4018-$401F are
+		// disabled test registers, so nothing drives the bus
+		return cpu.dataBus
+	}
 
-		if err != nil {
-			fmt.Printf("Error reading byte at address 0x%x: %s. Returning 0x00\n", address, err)
-			return 0x00
+	if device != nil {
+		if data, err := device.ReadByte(address); err == nil {
+			cpu.dataBus = data
 		}
-
-		return data
-	} else {
-		fmt.Printf("Unmapped memory address 0x%x. Returning 0x00\n", address)
-		return 0x00
 	}
+	return cpu.dataBus
 }
 
 func (cpu *Cpu) wordAt(address uint16) uint16 {
-	dataHi := cpu.byteAt(address + 1)
+	// Low byte first, as the 6502 reads them
 	dataLo := cpu.byteAt(address)
+	dataHi := cpu.byteAt(address + 1)
 
 	return uint16(dataHi)<<8 | uint16(dataLo)
 }
 
 func (cpu *Cpu) writeByte(address uint16, data uint8) error {
+	cpu.syncBus(address)
+	cpu.dataBus = data
+
 	if address < 0x2000 {
 		cpu.ram[address%0x800] = data
 		return nil
+	} else if address < 0x4000 && cpu.ppu != nil {
+		return cpu.ppu.WriteByte(address, data)
+	} else if address == 0x4014 && cpu.ppu != nil {
+		return cpu.oamDma(data)
+	} else if address == 0x4016 && cpu.input != nil {
+		return cpu.input.WriteByte(address, data)
+	} else if ((address >= 0x4000 && address <= 0x4013) || address == 0x4015 || address == 0x4017) && cpu.apu != nil {
+		return cpu.apu.WriteByte(address, data)
 	} else if address >= 0x4020 {
 		return cpu.cartridge.WriteByte(address, data)
 	} else {
@@ -62,55 +195,48 @@ func (cpu *Cpu) writeByte(address uint16, data uint8) error {
 	}
 }
 
-func (cpu *Cpu) pushByteToStack(data byte) error {
-	if cpu.sp < 1 {
-		return fmt.Errorf("Cpu.pushByteToStack(): Stack Overflow")
-	}
+// oamDma copies a page of CPU memory to PPU OAM through OAMDATA, halting
+// the CPU for 513 cycles, or 514 when started on an odd cycle.
+func (cpu *Cpu) oamDma(page byte) error {
+	address := uint16(page) << 8
 
-	address := 0x100 + uint16(cpu.sp)
-	cpu.ram[address] = data
-	cpu.sp -= 1
+	for i := uint16(0); i < 256; i++ {
+		err := cpu.ppu.WriteByte(0x2004, cpu.byteAt(address+i))
+		if err != nil {
+			return err
+		}
+	}
 
+	cpu.cycles += 513 + cpu.cycles%2
 	return nil
 }
 
-func (cpu *Cpu) pullByteFromStack() (byte, error) {
-	if cpu.sp >= 0xFF {
-		return 0x00, fmt.Errorf("Cpu.pullByteFromStack(): Stack Underflow")
-	}
-
-	cpu.sp += 1
-	address := 0x100 + uint16(cpu.sp)
-	value := cpu.ram[address]
-	return value, nil
+// The stack lives in page 1 and wraps around within it.
+func (cpu *Cpu) pushByteToStack(data byte) {
+	cpu.ram[0x100+uint16(cpu.sp)] = data
+	cpu.sp--
 }
 
-func (cpu *Cpu) pushWordToStack(data uint16) error {
-	if cpu.sp < 2 {
-		return fmt.Errorf("Cpu.pushWordToStack(): Stack Overflow", cpu.sp)
-	}
-
-	address := 0x100 + uint16(cpu.sp)
-	cpu.ram[address-1] = byte(data) // Little Endian Order
-	cpu.ram[address] = byte((data & 0xFF00) >> 8)
-	cpu.sp -= 2
-
-	return nil
+func (cpu *Cpu) pullByteFromStack() byte {
+	cpu.sp++
+	return cpu.ram[0x100+uint16(cpu.sp)]
 }
 
-func (cpu *Cpu) pullWordFromStack() (uint16, error) {
-	if cpu.sp >= 0xFE {
-		return 0x00, fmt.Errorf("Cpu.pullWordFromStack(): Stack Underflow")
-	}
+// Words are pushed high byte first, so they sit on the stack in little
+// endian order.
+func (cpu *Cpu) pushWordToStack(data uint16) {
+	cpu.pushByteToStack(byte(data >> 8))
+	cpu.pushByteToStack(byte(data))
+}
 
-	cpu.sp += 2
-	address := 0x100 + uint16(cpu.sp)
-	value := (uint16(cpu.ram[address+1]) << 8) | uint16(cpu.ram[address])
-	return value, nil
+func (cpu *Cpu) pullWordFromStack() uint16 {
+	lo := cpu.pullByteFromStack()
+	hi := cpu.pullByteFromStack()
+	return uint16(hi)<<8 | uint16(lo)
 }
 
 func (cpu *Cpu) getStatusFlagsByte() byte {
-	var statusFlagsByte byte = 0x20
+	var statusFlagsByte byte = STATUS_UNUSED
 
 	if cpu.carryFl {
 		statusFlagsByte |= 0x01
@@ -143,90 +269,207 @@ func (cpu *Cpu) restoreStatusFlags(statusFlagsByte byte) {
 	cpu.signFl = (statusFlagsByte & 0x80) > 0
 }
 
-func (cpu *Cpu) getArgument(mode addressMode) (uint16, uint16) {
+// indexed adds the mode's index register to address, reporting whether
+// that crossed into another page.
+func (cpu *Cpu) indexed(address uint16, reg int) (uint16, bool) {
+	var index uint16
+	if reg == REG_X {
+		index = uint16(cpu.x)
+	} else if reg == REG_Y {
+		index = uint16(cpu.y)
+	}
+
+	result := address + index
+	return result, result&0xFF00 != address&0xFF00
+}
+
+// zeroPageWord reads a pointer from the zero page, wrapping around within it.
+func (cpu *Cpu) zeroPageWord(address byte) uint16 {
+	return uint16(cpu.byteAt(uint16(address+1)))<<8 | uint16(cpu.byteAt(uint16(address)))
+}
+
+// getArgument decodes the instruction's operand into an immediate value, a
+// jump target or an effective address, and returns the instruction's length
+// and whether indexing crossed a page.
+func (cpu *Cpu) getArgument(mode addressMode) (uint16, uint16, bool) {
 	switch mode.mode {
 	case MODE_IMMEDIATE:
 		arg := cpu.byteAt(cpu.pc + 1)
-		return uint16(arg), 2
+		return uint16(arg), 2, false
 
 	case MODE_ZERO_PAGE:
-		address := uint16(cpu.byteAt(cpu.pc + 1))
-
+		// Indexing wraps around within the zero page
+		address := cpu.byteAt(cpu.pc + 1)
 		if mode.reg == REG_X {
-			address += uint16(cpu.x)
+			address += cpu.x
 		} else if mode.reg == REG_Y {
-			address += uint16(cpu.y)
+			address += cpu.y
 		}
 
-		return uint16(cpu.byteAt(address)), 2
+		return uint16(address), 2, false
 
 	case MODE_ABSOLUTE:
-		address_hi := uint16(cpu.byteAt(cpu.pc + 2))
-		address_lo := uint16(cpu.byteAt(cpu.pc + 1))
-		address := address_hi | address_lo
-
-		if mode.reg == REG_X {
-			address += uint16(cpu.x)
-		} else if mode.reg == REG_Y {
-			address += uint16(cpu.y)
-		}
-
-		return address, 3
+		address, crossed := cpu.indexed(cpu.wordAt(cpu.pc+1), mode.reg)
+		return address, 3, crossed
 
 	case MODE_RELATIVE:
-		offset := int16(cpu.byteAt(cpu.pc + 1))
+		offset := int8(cpu.byteAt(cpu.pc + 1))
+		return cpu.pc + 2 + uint16(offset), 2, false
 
-		if offset < 0 {
-			return cpu.pc - uint16(-1*offset), 2
-		} else {
-			return cpu.pc + uint16(offset), 2
-		}
+	case MODE_INDIRECT:
+		// The pointer's high byte is read without carrying into the next
+		// page, so JMP ($10FF) reads $10FF and $1000
+		pointer := cpu.wordAt(cpu.pc + 1)
+		lo := cpu.byteAt(pointer)
+		hi := cpu.byteAt(pointer&0xFF00 | (pointer+1)&0x00FF)
+		return uint16(hi)<<8 | uint16(lo), 3, false
 
 	case MODE_INDEX_INDIRECT:
-		address := uint16(cpu.byteAt(cpu.pc+1)) + uint16(cpu.x)
-		return cpu.wordAt(address), 2
+		pointer := cpu.byteAt(cpu.pc+1) + cpu.x
+		return cpu.zeroPageWord(pointer), 2, false
 
 	case MODE_INDIRECT_INDEX:
-		address := cpu.wordAt(uint16(cpu.byteAt(cpu.pc + 1)))
-		return cpu.wordAt(address + uint16(cpu.y)), 2
+		address, crossed := cpu.indexed(cpu.zeroPageWord(cpu.byteAt(cpu.pc+1)), REG_Y)
+		return address, 2, crossed
 	}
 
-	return 0, 1 // No argument, use 0 as dummy value
+	return 0, 1, false // No argument, use 0 as dummy value
 }
 
-func (cpu *Cpu) disassemble(instruction instruction, incr uint16) {
-	if incr == 0 {
-		fmt.Printf(instruction.neumonic)
-	} else if incr == 1 {
-		fmt.Printf(instruction.neumonic, cpu.byteAt(cpu.pc+1))
-	} else {
-		fmt.Printf(instruction.neumonic, cpu.wordAt(cpu.pc+1))
+func (cpu *Cpu) disassemble() {
+	code := make([]byte, InstructionLength(cpu.byteAt(cpu.pc)))
+	for i := range code {
+		code[i] = cpu.byteAt(cpu.pc + uint16(i))
 	}
 
+	text, _ := Disassemble(cpu.pc, code)
+	fmt.Printf("%04X  %s\n", cpu.pc, text)
 }
 
-func (cpu *Cpu) Run(disassemble bool) error {
-	for {
-		opcode := cpu.byteAt(cpu.pc)
-		instruction := instructions[opcode]
+// serviceInterrupt saves the program counter and status and jumps through
+// the NMI ($FFFA) or IRQ ($FFFE) vector.
+func (cpu *Cpu) serviceInterrupt(vector uint16) {
+	cpu.pushWordToStack(cpu.pc)
+	cpu.pushByteToStack(cpu.getStatusFlagsByte())
 
-		if instruction.handler == nil {
-			return fmt.Errorf("Unrecognized opcode: %x\n", opcode)
-		}
+	cpu.interruptFl = true
+	cpu.pc = cpu.wordAt(vector)
+	cpu.cycles += 7
+}
+
+func (cpu *Cpu) Step(disassemble bool) error {
+	if cpu.nmiPending {
+		cpu.nmiPending = false
+		cpu.serviceInterrupt(NMI_VECTOR)
+		return nil
+	}
+
+	if cpu.irqLine != nil && !cpu.interruptFl && cpu.irqLine() {
+		cpu.serviceInterrupt(IRQ_VECTOR)
+		return nil
+	}
+
+	opcode := cpu.byteAt(cpu.pc)
+	instruction := instructions[opcode]
+
+	if instruction.handler == nil {
+		return fmt.Errorf("Unrecognized opcode: %x\n", opcode)
+	}
+
+	if cpu.executeHook != nil {
+		cpu.executeHook(cpu.pc)
+	}
 
-		arg, incr := cpu.getArgument(instruction.addressMode)
+	arg, incr, crossed := cpu.getArgument(instruction.addressMode)
 
-		if disassemble {
-			cpu.disassemble(instruction, incr)
+	if disassemble {
+		cpu.disassemble()
+	}
+
+	cpu.cycles += uint64(instructionCycles[opcode])
+	if crossed && pageCrossPenalty[instruction.neumonic] {
+		cpu.cycles++
+	}
+
+	cpu.pc += incr
+	return instruction.handler(cpu, arg, instruction.addressMode)
+}
+
+// Subroutines run by Call, Start and Interrupt return to CALL_SENTINEL,
+// where the CPU stops. RTS resumes one past the address on the stack.
+const CALL_SENTINEL uint16 = 0x5FF6
+
+// Call runs the subroutine at address with A and X set, the way music
+// drivers are called, and returns once it executes its final RTS. It starts
+// with a fresh stack, so it's for code that owns the whole CPU.
+func (cpu *Cpu) Call(address uint16, a byte, x byte, maxCycles uint64) error {
+	cpu.Start(address, a, x)
+
+	returned, err := cpu.RunUntil(cpu.cycles + maxCycles)
+	if err == nil && !returned {
+		return fmt.Errorf("Cpu.Call(): Subroutine at 0x%x didn't return within %d cycles.", address, maxCycles)
+	}
+	return err
+}
+
+// Start sets up a call like Call's but leaves running it to RunUntil, for
+// subroutines that take a long time to return or never do.
+func (cpu *Cpu) Start(address uint16, a byte, x byte) {
+	cpu.sp = 0xFF
+	cpu.pushWordToStack(CALL_SENTINEL - 1)
+
+	cpu.a, cpu.x = a, x
+	cpu.pc = address
+}
+
+// RunUntil runs the subroutine begun by Start until it returns, reporting
+// true, or until the CPU reaches cycle.
+func (cpu *Cpu) RunUntil(cycle uint64) (bool, error) {
+	for cpu.pc != CALL_SENTINEL {
+		if cpu.cycles >= cycle {
+			return false, nil
 		}
 
-		err := instruction.handler(cpu, arg, instruction.addressMode)
+		err := cpu.Step(false)
 		if err != nil {
-			return err
+			return false, err
 		}
+	}
+
+	return true, nil
+}
 
-		cpu.pc += incr
+// Interrupt runs the subroutine at address from an interrupt, the way an
+// NSF player's NMI handler calls play while init is still running. The
+// interrupted code carries on afterwards with its registers and flags as
+// they were.
+func (cpu *Cpu) Interrupt(address uint16, maxCycles uint64) error {
+	a, x, y := cpu.a, cpu.x, cpu.y
+	cpu.serviceInterrupt(NMI_VECTOR)
+	cpu.pushWordToStack(CALL_SENTINEL - 1)
+	cpu.pc = address
+
+	returned, err := cpu.RunUntil(cpu.cycles + maxCycles)
+	if err != nil {
+		return err
+	}
+	if !returned {
+		return fmt.Errorf("Cpu.Interrupt(): Subroutine at 0x%x didn't return within %d cycles.", address, maxCycles)
 	}
 
+	// RTI, after the handler has restored the registers
+	cpu.a, cpu.x, cpu.y = a, x, y
+	cpu.restoreStatusFlags(cpu.pullByteFromStack())
+	cpu.pc = cpu.pullWordFromStack()
+	cpu.cycles += 6
 	return nil
 }
+
+func (cpu *Cpu) Run(disassemble bool) error {
+	for {
+		err := cpu.Step(disassemble)
+		if err != nil {
+			return err
+		}
+	}
+}
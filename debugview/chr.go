@@ -0,0 +1,92 @@
+package debugview
+
+import (
+	"github.com/tjarjoura/nes-emulator/palette"
+	"github.com/tjarjoura/nes-emulator/ppu"
+	"image"
+	"image/color"
+)
+
+const (
+	TILE_SIZE      int = 8
+	TILE_BYTES     int = 16
+	TILES_PER_ROW  int = 16
+	PATTERN_TABLES int = 2
+)
+
+// Colours for viewing tiles without a palette, from dark to light
+var GREYSCALE = [4]color.RGBA{
+	{0x00, 0x00, 0x00, 0xFF},
+	{0x55, 0x55, 0x55, 0xFF},
+	{0xAA, 0xAA, 0xAA, 0xFF},
+	{0xFF, 0xFF, 0xFF, 0xFF},
+}
+
+// drawTile draws the tile whose 16 bytes are returned by fetch at (x, y).
+func drawTile(img *image.RGBA, x int, y int, fetch func(i int) byte, colors [4]color.RGBA) {
+	for row := 0; row < TILE_SIZE; row++ {
+		lo := fetch(row)
+		hi := fetch(row + 8)
+
+		for column := 0; column < TILE_SIZE; column++ {
+			bit := byte(0x80) >> uint(column)
+
+			var index int
+			if lo&bit > 0 {
+				index |= 0x01
+			}
+			if hi&bit > 0 {
+				index |= 0x02
+			}
+
+			img.SetRGBA(x+column, y+row, colors[index])
+		}
+	}
+}
+
+// ChrSheet draws every tile in a block of CHR data, sixteen tiles to a row.
+func ChrSheet(chr []byte, colors [4]color.RGBA) *image.RGBA {
+	tiles := len(chr) / TILE_BYTES
+	rows := (tiles + TILES_PER_ROW - 1) / TILES_PER_ROW
+	img := image.NewRGBA(image.Rect(0, 0, TILES_PER_ROW*TILE_SIZE, rows*TILE_SIZE))
+
+	for tile := 0; tile < tiles; tile++ {
+		tileData := chr[tile*TILE_BYTES : (tile+1)*TILE_BYTES]
+		x := (tile % TILES_PER_ROW) * TILE_SIZE
+		y := (tile / TILES_PER_ROW) * TILE_SIZE
+		drawTile(img, x, y, func(i int) byte { return tileData[i] }, colors)
+	}
+
+	return img
+}
+
+// PatternTables draws both pattern tables side by side as the PPU currently
+// sees them, so it follows the mapper's CHR banking and CHR RAM contents.
+func PatternTables(p *ppu.Ppu, colors [4]color.RGBA) *image.RGBA {
+	tableWidth := TILES_PER_ROW * TILE_SIZE
+	img := image.NewRGBA(image.Rect(0, 0, PATTERN_TABLES*tableWidth, tableWidth))
+
+	for table := 0; table < PATTERN_TABLES; table++ {
+		for tile := 0; tile < TILES_PER_ROW*TILES_PER_ROW; tile++ {
+			address := uint16(table*0x1000 + tile*TILE_BYTES)
+			x := table*tableWidth + (tile%TILES_PER_ROW)*TILE_SIZE
+			y := (tile / TILES_PER_ROW) * TILE_SIZE
+			drawTile(img, x, y, func(i int) byte { return p.PeekVram(address + uint16(i)) }, colors)
+		}
+	}
+
+	return img
+}
+
+// SubpaletteColors returns the colours of one of the eight subpalettes
+// currently in palette RAM, 0-3 for the background and 4-7 for sprites.
+func SubpaletteColors(p *ppu.Ppu, pal *palette.Palette, subpalette int) [4]color.RGBA {
+	var colors [4]color.RGBA
+
+	for i := range colors {
+		index := p.PeekVram(0x3F00 + uint16(subpalette*4+i))
+		colors[i] = pal.Color(uint16(index))
+	}
+
+	return colors
+}
@@ -0,0 +1,26 @@
+package debugview
+
+import (
+	"encoding/json"
+	"github.com/tjarjoura/nes-emulator/ppu"
+	"io"
+)
+
+// PpuState is the JSON form of the nametable and OAM views.
+type PpuState struct {
+	ScrollX    int                                       `json:"scroll_x"`
+	ScrollY    int                                       `json:"scroll_y"`
+	Attributes [4][ATTRIBUTE_ROWS][ATTRIBUTE_COLUMNS]int `json:"attributes"`
+	Sprites    []Sprite                                  `json:"sprites"`
+}
+
+func WriteJson(w io.Writer, p *ppu.Ppu) error {
+	var state PpuState
+	state.ScrollX, state.ScrollY = p.Scroll()
+	state.Attributes = Attributes(p)
+	state.Sprites = Sprites(p)
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(state)
+}
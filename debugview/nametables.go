@@ -0,0 +1,132 @@
+package debugview
+
+import (
+	"github.com/tjarjoura/nes-emulator/palette"
+	"github.com/tjarjoura/nes-emulator/ppu"
+	"image"
+	"image/color"
+)
+
+const (
+	NAMETABLE_COLUMNS int = 32
+	NAMETABLE_ROWS    int = 30
+	ATTRIBUTE_COLUMNS int = 16 // Each attribute covers 2x2 tiles
+	ATTRIBUTE_ROWS    int = 15
+)
+
+var VIEWPORT_COLOR = color.RGBA{0xFF, 0x00, 0xFF, 0xFF}
+
+// Colours identifying the four background subpalettes in the attribute view
+var ATTRIBUTE_COLORS = [4]color.RGBA{
+	{0x30, 0x30, 0x30, 0xFF},
+	{0xD0, 0x40, 0x40, 0xFF},
+	{0x40, 0xC0, 0x40, 0xFF},
+	{0x40, 0x60, 0xE0, 0xFF},
+}
+
+func nametableAddress(nametable int) uint16 {
+	return 0x2000 + uint16(nametable)*0x400
+}
+
+// Attribute returns the subpalette used by a tile of one of the four logical
+// nametables.
+func Attribute(p *ppu.Ppu, nametable int, column int, row int) int {
+	address := nametableAddress(nametable) + 0x3C0 + uint16(row/4*8+column/4)
+	shift := uint((row%4)/2*4 + (column%4)/2*2)
+	return int(p.PeekVram(address)>>shift) & 0x03
+}
+
+// Attributes returns the subpalette of every 16x16 pixel area of the four
+// nametables, indexed by nametable, row and column.
+func Attributes(p *ppu.Ppu) [4][ATTRIBUTE_ROWS][ATTRIBUTE_COLUMNS]int {
+	var attributes [4][ATTRIBUTE_ROWS][ATTRIBUTE_COLUMNS]int
+
+	for nametable := range attributes {
+		for row := 0; row < ATTRIBUTE_ROWS; row++ {
+			for column := 0; column < ATTRIBUTE_COLUMNS; column++ {
+				attributes[nametable][row][column] = Attribute(p, nametable, column*2, row*2)
+			}
+		}
+	}
+
+	return attributes
+}
+
+// outlineViewport draws the visible screen area, wrapping around the edges
+// of the 512x480 nametable space as scrolling does.
+func outlineViewport(img *image.RGBA, p *ppu.Ppu) {
+	scrollX, scrollY := p.Scroll()
+	width, height := img.Bounds().Dx(), img.Bounds().Dy()
+
+	plot := func(x int, y int) {
+		img.SetRGBA((scrollX+x)%width, (scrollY+y)%height, VIEWPORT_COLOR)
+	}
+
+	for x := 0; x < ppu.SCREEN_WIDTH; x++ {
+		plot(x, 0)
+		plot(x, ppu.SCREEN_HEIGHT-1)
+	}
+	for y := 0; y < ppu.SCREEN_HEIGHT; y++ {
+		plot(0, y)
+		plot(ppu.SCREEN_WIDTH-1, y)
+	}
+}
+
+// Nametables draws the four logical nametables in a 2x2 grid as the PPU
+// currently sees them, with mirroring applied, and outlines the area the
+// scroll registers select.
+func Nametables(p *ppu.Ppu, pal *palette.Palette) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, 2*ppu.SCREEN_WIDTH, 2*ppu.SCREEN_HEIGHT))
+	table := p.BackgroundTable()
+
+	backdrop := pal.Color(uint16(p.PeekVram(0x3F00)))
+
+	for nametable := 0; nametable < 4; nametable++ {
+		originX := (nametable % 2) * ppu.SCREEN_WIDTH
+		originY := (nametable / 2) * ppu.SCREEN_HEIGHT
+
+		for row := 0; row < NAMETABLE_ROWS; row++ {
+			for column := 0; column < NAMETABLE_COLUMNS; column++ {
+				tile := p.PeekVram(nametableAddress(nametable) + uint16(row*NAMETABLE_COLUMNS+column))
+				colors := SubpaletteColors(p, pal, Attribute(p, nametable, column, row))
+				colors[0] = backdrop // The PPU never draws $3F04/$3F08/$3F0C
+				address := table + uint16(tile)*uint16(TILE_BYTES)
+
+				drawTile(img, originX+column*TILE_SIZE, originY+row*TILE_SIZE,
+					func(i int) byte { return p.PeekVram(address + uint16(i)) }, colors)
+			}
+		}
+	}
+
+	outlineViewport(img, p)
+	return img
+}
+
+// AttributeMap draws the four nametables with every 16x16 area filled in a
+// colour identifying its subpalette.
+func AttributeMap(p *ppu.Ppu) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, 2*ppu.SCREEN_WIDTH, 2*ppu.SCREEN_HEIGHT))
+	attributeSize := 2 * TILE_SIZE
+
+	for nametable, rows := range Attributes(p) {
+		originX := (nametable % 2) * ppu.SCREEN_WIDTH
+		originY := (nametable / 2) * ppu.SCREEN_HEIGHT
+
+		for row, columns := range rows {
+			for column, attribute := range columns {
+				for y := 0; y < attributeSize; y++ {
+					for x := 0; x < attributeSize; x++ {
+						// Leave a one pixel gap so neighbouring areas stay distinct
+						if x == attributeSize-1 || y == attributeSize-1 {
+							continue
+						}
+						img.SetRGBA(originX+column*attributeSize+x, originY+row*attributeSize+y, ATTRIBUTE_COLORS[attribute])
+					}
+				}
+			}
+		}
+	}
+
+	outlineViewport(img, p)
+	return img
+}
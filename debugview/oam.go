@@ -0,0 +1,108 @@
+package debugview
+
+import (
+	"github.com/tjarjoura/nes-emulator/palette"
+	"github.com/tjarjoura/nes-emulator/ppu"
+	"image"
+	"image/color"
+)
+
+const (
+	SPRITES            int = 64
+	SPRITES_PER_ROW    int = 8
+	SPRITE_CELL_WIDTH  int = 10 // Room for an 8x16 sprite and a border
+	SPRITE_CELL_HEIGHT int = 18
+)
+
+var SPRITE_BACKGROUND = color.RGBA{0x40, 0x40, 0x40, 0xFF}
+
+// Sprite is one decoded OAM entry.
+type Sprite struct {
+	Index            int  `json:"index"`
+	X                int  `json:"x"`
+	Y                int  `json:"y"`
+	Tile             int  `json:"tile"`
+	Palette          int  `json:"palette"`
+	BehindBackground bool `json:"behind_background"`
+	FlipHorizontal   bool `json:"flip_horizontal"`
+	FlipVertical     bool `json:"flip_vertical"`
+}
+
+// Sprites decodes all 64 entries of OAM.
+func Sprites(p *ppu.Ppu) []Sprite {
+	oam := p.Oam()
+	sprites := make([]Sprite, SPRITES)
+
+	for i := range sprites {
+		entry := oam[i*4 : i*4+4]
+		sprites[i] = Sprite{
+			Index:            i,
+			X:                int(entry[3]),
+			Y:                int(entry[0]),
+			Tile:             int(entry[1]),
+			Palette:          int(entry[2]&ppu.SPRITE_PALETTE) + 4,
+			BehindBackground: entry[2]&ppu.SPRITE_BEHIND > 0,
+			FlipHorizontal:   entry[2]&ppu.SPRITE_FLIP_HORIZONTAL > 0,
+			FlipVertical:     entry[2]&ppu.SPRITE_FLIP_VERTICAL > 0,
+		}
+	}
+
+	return sprites
+}
+
+// spritePatternAddress finds the pattern of one tile of a sprite the way the
+// PPU does, including the pattern table selection of 8x16 sprites.
+func spritePatternAddress(p *ppu.Ppu, sprite Sprite, half int) uint16 {
+	if p.SpriteHeight() == 8 {
+		return p.SpriteTable() + uint16(sprite.Tile)*uint16(TILE_BYTES)
+	}
+
+	table := uint16(sprite.Tile&0x01) * 0x1000
+	tile := sprite.Tile&0xFE + half
+	return table + uint16(tile)*uint16(TILE_BYTES)
+}
+
+// SpritePreviews draws each OAM entry with its palette and flipping, eight
+// to a row, leaving colour 0 transparent on a grey background.
+func SpritePreviews(p *ppu.Ppu, pal *palette.Palette) *image.RGBA {
+	rows := SPRITES / SPRITES_PER_ROW
+	img := image.NewRGBA(image.Rect(0, 0, SPRITES_PER_ROW*SPRITE_CELL_WIDTH, rows*SPRITE_CELL_HEIGHT))
+
+	for _, sprite := range Sprites(p) {
+		originX := (sprite.Index%SPRITES_PER_ROW)*SPRITE_CELL_WIDTH + 1
+		originY := (sprite.Index/SPRITES_PER_ROW)*SPRITE_CELL_HEIGHT + 1
+		colors := SubpaletteColors(p, pal, sprite.Palette)
+		colors[0] = SPRITE_BACKGROUND
+
+		height := p.SpriteHeight()
+		for row := 0; row < height; row++ {
+			sourceRow := row
+			if sprite.FlipVertical {
+				sourceRow = height - 1 - row
+			}
+
+			address := spritePatternAddress(p, sprite, sourceRow/8) + uint16(sourceRow%8)
+			lo := p.PeekVram(address)
+			hi := p.PeekVram(address + 8)
+
+			for column := 0; column < TILE_SIZE; column++ {
+				bit := byte(0x80) >> uint(column)
+				if sprite.FlipHorizontal {
+					bit = byte(0x01) << uint(column)
+				}
+
+				var index int
+				if lo&bit > 0 {
+					index |= 0x01
+				}
+				if hi&bit > 0 {
+					index |= 0x02
+				}
+
+				img.SetRGBA(originX+column, originY+row, colors[index])
+			}
+		}
+	}
+
+	return img
+}
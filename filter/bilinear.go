@@ -0,0 +1,63 @@
+package filter
+
+import (
+	"fmt"
+	"image"
+	"math"
+)
+
+// Bilinear scales an image by any factor, interpolating between the four
+// nearest source pixels.
+type Bilinear struct {
+	Scale float64
+}
+
+func NewBilinear(options Options) (Filter, error) {
+	scale, err := options.Float("scale", 2)
+	if err != nil {
+		return nil, err
+	}
+	if scale <= 0 {
+		return nil, fmt.Errorf("scale must be positive")
+	}
+
+	return Bilinear{scale}, nil
+}
+
+// sample returns the colour at the fractional position (x, y), where pixel
+// centres lie at whole coordinates.
+func (p *pixels) sample(x float64, y float64) uint32 {
+	x0, y0 := math.Floor(x), math.Floor(y)
+	fx, fy := x-x0, y-y0
+	left, top := int(x0), int(y0)
+
+	weights := []int{
+		int((1 - fx) * (1 - fy) * 256),
+		int(fx * (1 - fy) * 256),
+		int((1 - fx) * fy * 256),
+		int(fx * fy * 256),
+	}
+	colors := []uint32{p.at(left, top), p.at(left+1, top), p.at(left, top+1), p.at(left+1, top+1)}
+
+	if weights[0]+weights[1]+weights[2]+weights[3] == 0 {
+		return colors[0]
+	}
+	return mix(weights, colors)
+}
+
+func (bilinear Bilinear) Apply(src *image.RGBA) *image.RGBA {
+	p := unpack(src)
+	width := int(math.Round(float64(p.width) * bilinear.Scale))
+	height := int(math.Round(float64(p.height) * bilinear.Scale))
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+
+	for y := 0; y < height; y++ {
+		sourceY := (float64(y)+0.5)/bilinear.Scale - 0.5
+		for x := 0; x < width; x++ {
+			sourceX := (float64(x)+0.5)/bilinear.Scale - 0.5
+			setPixel(dst, x, y, p.sample(sourceX, sourceY))
+		}
+	}
+
+	return dst
+}
@@ -0,0 +1,94 @@
+package filter
+
+import (
+	"image"
+	"math"
+)
+
+// Crt imitates a television tube on an already scaled image: dark gaps
+// between scanlines, the red, green and blue stripes of an aperture grille and
+// the curvature of the glass. Each effect is off at 0.
+type Crt struct {
+	Scanlines float64 // How dark the gaps between scanlines are, 0-1
+	Mask      float64 // Strength of the aperture grille, 0-1
+	Curvature float64 // Barrel distortion, 0.1 is a gentle curve
+
+	// Output rows per emulated scanline; 0 guesses from a 240 line picture
+	LineHeight float64
+}
+
+var DEFAULT_CRT Crt = Crt{Scanlines: 0.5, Mask: 0.25, Curvature: 0.05}
+
+func NewCrt(options Options) (Filter, error) {
+	crt := DEFAULT_CRT
+	var err error
+
+	for name, target := range map[string]*float64{
+		"scanlines":  &crt.Scanlines,
+		"mask":       &crt.Mask,
+		"curvature":  &crt.Curvature,
+		"lineheight": &crt.LineHeight,
+	} {
+		*target, err = options.Float(name, *target)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return crt, nil
+}
+
+func (crt Crt) Apply(src *image.RGBA) *image.RGBA {
+	p := unpack(src)
+	dst := image.NewRGBA(image.Rect(0, 0, p.width, p.height))
+
+	lineHeight := crt.LineHeight
+	if lineHeight <= 0 {
+		lineHeight = math.Max(1, float64(p.height)/240)
+	}
+
+	for y := 0; y < p.height; y++ {
+		for x := 0; x < p.width; x++ {
+			sourceX, sourceY := float64(x), float64(y)
+
+			if crt.Curvature > 0 {
+				// Distort around the centre, with coordinates in -1..1
+				u := 2*(float64(x)+0.5)/float64(p.width) - 1
+				v := 2*(float64(y)+0.5)/float64(p.height) - 1
+				r := u*u + v*v
+				u *= 1 + crt.Curvature*r
+				v *= 1 + crt.Curvature*r
+
+				if u < -1 || u > 1 || v < -1 || v > 1 {
+					setPixel(dst, x, y, 0x000000FF)
+					continue
+				}
+
+				sourceX = (u+1)*float64(p.width)/2 - 0.5
+				sourceY = (v+1)*float64(p.height)/2 - 0.5
+			}
+
+			pixel := p.sample(sourceX, sourceY)
+			channels := [3]float64{float64(pixel>>24&0xFF) / 255, float64(pixel>>16&0xFF) / 255, float64(pixel>>8&0xFF) / 255}
+
+			brightness := 1.0
+			if crt.Scanlines > 0 {
+				// A raised cosine across each scanline, darkest at its edges
+				phase := math.Mod(sourceY+0.5, lineHeight) / lineHeight
+				beam := 0.5 - 0.5*math.Cos(2*math.Pi*phase)
+				brightness *= 1 - crt.Scanlines*(1-beam)
+			}
+
+			for channel := range channels {
+				channels[channel] *= brightness
+				if crt.Mask > 0 && x%3 != channel {
+					channels[channel] *= 1 - crt.Mask
+				}
+			}
+
+			setPixel(dst, x, y, uint32(clamp(channels[0]))<<24|uint32(clamp(channels[1]))<<16|uint32(clamp(channels[2]))<<8|0xFF)
+		}
+	}
+
+	return dst
+}
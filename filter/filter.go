@@ -0,0 +1,149 @@
+package filter
+
+import (
+	"fmt"
+	"github.com/tjarjoura/nes-emulator/palette"
+	"github.com/tjarjoura/nes-emulator/ppu"
+	"image"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Filter transforms an image, usually into a larger one.
+type Filter interface {
+	Apply(src *image.RGBA) *image.RGBA
+}
+
+type Chain []Filter
+
+func (chain Chain) Apply(src *image.RGBA) *image.RGBA {
+	for _, filter := range chain {
+		src = filter.Apply(src)
+	}
+	return src
+}
+
+// Options are the key=value settings given to a filter on the command line.
+type Options map[string]string
+
+func (options Options) Float(name string, fallback float64) (float64, error) {
+	value, ok := options[name]
+	if !ok {
+		return fallback, nil
+	}
+
+	number, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return fallback, fmt.Errorf("Bad value for %s: %q", name, value)
+	}
+	return number, nil
+}
+
+var constructors = map[string]func(options Options) (Filter, error){
+	"scale2x":  func(options Options) (Filter, error) { return Scale2x{}, nil },
+	"scale3x":  func(options Options) (Filter, error) { return Scale3x{}, nil },
+	"hq2x":     func(options Options) (Filter, error) { return Hqx{2}, nil },
+	"hq3x":     func(options Options) (Filter, error) { return Hqx{3}, nil },
+	"xbr":      func(options Options) (Filter, error) { return Xbr{}, nil },
+	"bilinear": NewBilinear,
+	"crt":      NewCrt,
+}
+
+// FilterNames lists everything ParseChain accepts, including ntsc.
+func FilterNames() []string {
+	names := []string{"ntsc"}
+	for name := range constructors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func splitFilter(spec string) (string, string) {
+	name, options, _ := strings.Cut(strings.TrimSpace(spec), ":")
+	return strings.ToLower(name), options
+}
+
+func parseOptions(spec string) (Options, error) {
+	options := make(Options)
+	if spec == "" {
+		return options, nil
+	}
+
+	for _, option := range strings.Split(spec, ",") {
+		name, value, ok := strings.Cut(option, "=")
+		if !ok {
+			return nil, fmt.Errorf("Expected name=value, got %q", option)
+		}
+		options[strings.ToLower(strings.TrimSpace(name))] = strings.TrimSpace(value)
+	}
+
+	return options, nil
+}
+
+// Pipeline turns PPU frames into images: it colours them with a palette or
+// the NTSC filter, then runs them through a chain of filters.
+type Pipeline struct {
+	palette *palette.Palette
+	ntsc    *NtscFilter
+	chain   Chain
+}
+
+// NewPipeline builds a pipeline from a description such as
+// "ntsc:merge+scale2x+crt:scanlines=0.5,mask=0.2", where filters are
+// separated by '+' and their options follow a colon. ntsc may only be the
+// first filter.
+func NewPipeline(spec string, pal *palette.Palette) (*Pipeline, error) {
+	pipeline := &Pipeline{palette: pal}
+	if spec == "" {
+		return pipeline, nil
+	}
+
+	for i, filterSpec := range strings.Split(spec, "+") {
+		name, optionSpec := splitFilter(filterSpec)
+
+		if name == "ntsc" {
+			if i > 0 {
+				return nil, fmt.Errorf("ntsc must be the first filter")
+			}
+
+			settings, err := ParseNtscSettings(optionSpec)
+			if err != nil {
+				return nil, err
+			}
+			pipeline.ntsc = NewNtscFilter(settings)
+			continue
+		}
+
+		constructor, ok := constructors[name]
+		if !ok {
+			return nil, fmt.Errorf("Unknown filter %q, expected one of %s", name, strings.Join(FilterNames(), ", "))
+		}
+
+		options, err := parseOptions(optionSpec)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", name, err)
+		}
+
+		filter, err := constructor(options)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", name, err)
+		}
+		pipeline.chain = append(pipeline.chain, filter)
+	}
+
+	return pipeline, nil
+}
+
+// Apply filters a PPU frame, using frameNumber for the NTSC dot crawl.
+func (pipeline *Pipeline) Apply(frame []uint16, frameNumber uint64) *image.RGBA {
+	var img *image.RGBA
+	if pipeline.ntsc != nil {
+		img = pipeline.ntsc.Apply(frame, frameNumber)
+	} else {
+		img = pipeline.palette.Image(frame, ppu.SCREEN_WIDTH)
+	}
+
+	return pipeline.chain.Apply(img)
+}
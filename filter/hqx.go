@@ -0,0 +1,598 @@
+package filter
+
+import "image"
+
+// Thresholds used by hqx to decide that two colours differ, in YUV space.
+const (
+	HQX_THRESHOLD_Y int = 48
+	HQX_THRESHOLD_U int = 7
+	HQX_THRESHOLD_V int = 6
+)
+
+func yuv(pixel uint32) (int, int, int) {
+	r, g, b := int(pixel>>24&0xFF), int(pixel>>16&0xFF), int(pixel>>8&0xFF)
+
+	y := (299*r + 587*g + 114*b) / 1000
+	u := (-169*r-331*g+500*b)/1000 + 128
+	v := (500*r-419*g-81*b)/1000 + 128
+	return y, u, v
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+func differ(a uint32, b uint32) bool {
+	if a == b {
+		return false
+	}
+
+	y1, u1, v1 := yuv(a)
+	y2, u2, v2 := yuv(b)
+	return abs(y1-y2) > HQX_THRESHOLD_Y || abs(u1-u2) > HQX_THRESHOLD_U || abs(v1-v2) > HQX_THRESHOLD_V
+}
+
+// Hqx is Maxim Stepin's hq2x or hq3x. Each pixel e is compared with its
+// eight neighbours, numbered as in hqx:
+//
+//	w1 w2 w3
+//	w4 w5 w6
+//	w7 w8 w9
+//
+// and the pattern of neighbours that differ from it picks a rule for every
+// output pixel. The rule tables are written for the top left corner, where
+// a is w1, b is w2, d is w4, f is w6 and h is w8; the other corners use the
+// same tables on a turned neighbourhood, as hqx's rules are symmetric.
+type Hqx struct {
+	Scale int
+}
+
+// hqxTest is the extra comparison of two neighbours some rules make before
+// choosing a blend, usually whether b and d form a diagonal edge.
+type hqxTest byte
+
+const (
+	HQX_ALWAYS hqxTest = iota
+	HQX_IF_D_B
+	HQX_IF_B_F
+	HQX_IF_D_H
+)
+
+// hqxBlend is one of hqx's interpolations, named after its weights of e, a,
+// b and d.
+type hqxBlend byte
+
+const (
+	HQX_E hqxBlend = iota
+	HQX_3E_A
+	HQX_3E_B
+	HQX_3E_D
+	HQX_2E_D_B
+	HQX_2E_A_B
+	HQX_2E_A_D
+	HQX_5E_2B_D
+	HQX_5E_2D_B
+	HQX_6E_D_B
+	HQX_2E_3D_3B
+	HQX_14E_D_B
+	HQX_2E_7D_7B
+	HQX_D_B
+	HQX_7E_B
+	HQX_E_3B
+)
+
+var hqxWeights = [...][4]int{
+	HQX_E:        {1, 0, 0, 0},
+	HQX_3E_A:     {3, 1, 0, 0},
+	HQX_3E_B:     {3, 0, 1, 0},
+	HQX_3E_D:     {3, 0, 0, 1},
+	HQX_2E_D_B:   {2, 0, 1, 1},
+	HQX_2E_A_B:   {2, 1, 1, 0},
+	HQX_2E_A_D:   {2, 1, 0, 1},
+	HQX_5E_2B_D:  {5, 0, 2, 1},
+	HQX_5E_2D_B:  {5, 0, 1, 2},
+	HQX_6E_D_B:   {6, 0, 1, 1},
+	HQX_2E_3D_3B: {2, 0, 3, 3},
+	HQX_14E_D_B:  {14, 0, 1, 1},
+	HQX_2E_7D_7B: {2, 0, 7, 7},
+	HQX_D_B:      {0, 0, 1, 1},
+	HQX_7E_B:     {7, 0, 1, 0},
+	HQX_E_3B:     {1, 0, 3, 0},
+}
+
+// hqxRule blends with ifDiffer when the test's two neighbours differ, or
+// always does when there is no test, and with ifSame otherwise.
+type hqxRule struct {
+	test     hqxTest
+	ifDiffer hqxBlend
+	ifSame   hqxBlend
+}
+
+// hqxTurns[turn][i] is the neighbour that plays the part of wi once the
+// neighbourhood has been turned anticlockwise by turn quarter turns, which
+// brings the top right, bottom right and bottom left corners to the top
+// left in turn.
+var hqxTurns = [4][10]int{
+	{0, 1, 2, 3, 4, 5, 6, 7, 8, 9},
+	{0, 3, 6, 9, 2, 5, 8, 1, 4, 7},
+	{0, 9, 8, 7, 6, 5, 4, 3, 2, 1},
+	{0, 7, 4, 1, 8, 5, 2, 9, 6, 3},
+}
+
+// hqxBits are the neighbours in the order of their bits in a pattern.
+var hqxBits = [8]int{1, 2, 3, 4, 6, 7, 8, 9}
+
+// Where each turn's corner and edge pixels go in the scaled pixel.
+var (
+	hq2xCornerOffsets = [4][2]int{{0, 0}, {1, 0}, {1, 1}, {0, 1}}
+	hq3xCornerOffsets = [4][2]int{{0, 0}, {2, 0}, {2, 2}, {0, 2}}
+	hq3xEdgeOffsets   = [4][2]int{{1, 0}, {2, 1}, {1, 2}, {0, 1}}
+)
+
+// apply blends the turned neighbourhood w, with w[5] the centre, by rule.
+func (rule hqxRule) apply(w *[10]uint32, turn *[10]int) uint32 {
+	var first, second int
+	switch rule.test {
+	case HQX_IF_D_B:
+		first, second = 4, 2
+	case HQX_IF_B_F:
+		first, second = 2, 6
+	case HQX_IF_D_H:
+		first, second = 4, 8
+	}
+
+	blend := rule.ifDiffer
+	if rule.test != HQX_ALWAYS && !differ(w[turn[first]], w[turn[second]]) {
+		blend = rule.ifSame
+	}
+
+	if blend == HQX_E {
+		return w[5]
+	}
+	weights := hqxWeights[blend]
+	return mix(weights[:], []uint32{w[5], w[turn[1]], w[turn[2]], w[turn[4]]})
+}
+
+func (hqx Hqx) Apply(src *image.RGBA) *image.RGBA {
+	p := unpack(src)
+	scale := hqx.Scale
+	dst := image.NewRGBA(image.Rect(0, 0, p.width*scale, p.height*scale))
+
+	var w [10]uint32
+	var differs [10]bool
+	for y := 0; y < p.height; y++ {
+		for x := 0; x < p.width; x++ {
+			for i := 1; i <= 9; i++ {
+				w[i] = p.at(x+(i-1)%3-1, y+(i-1)/3-1)
+			}
+			for i := 1; i <= 9; i++ {
+				differs[i] = differ(w[5], w[i])
+			}
+
+			for turn := range hqxTurns {
+				var pattern int
+				for bit, i := range hqxBits {
+					if differs[hqxTurns[turn][i]] {
+						pattern |= 1 << uint(bit)
+					}
+				}
+
+				if scale == 2 {
+					offset := hq2xCornerOffsets[turn]
+					setPixel(dst, x*2+offset[0], y*2+offset[1], hq2xCorners[pattern].apply(&w, &hqxTurns[turn]))
+					continue
+				}
+
+				offset := hq3xCornerOffsets[turn]
+				setPixel(dst, x*3+offset[0], y*3+offset[1], hq3xCorners[pattern].apply(&w, &hqxTurns[turn]))
+				offset = hq3xEdgeOffsets[turn]
+				setPixel(dst, x*3+offset[0], y*3+offset[1], hq3xEdges[pattern].apply(&w, &hqxTurns[turn]))
+			}
+
+			if scale == 3 {
+				setPixel(dst, x*3+1, y*3+1, w[5])
+			}
+		}
+	}
+
+	return dst
+}
+
+// The rule tables, indexed by the pattern of the turned neighbourhood. Each
+// is hqx's rule for the top left output pixel, or for hq3x's top edge pixel,
+// in the case of the pattern in its switch statement.
+var hq2xCorners = [256]hqxRule{
+	/* 0x00 */ {HQX_ALWAYS, HQX_2E_D_B, HQX_2E_D_B}, {HQX_ALWAYS, HQX_2E_D_B, HQX_2E_D_B},
+	/* 0x02 */ {HQX_ALWAYS, HQX_2E_A_D, HQX_2E_A_D}, {HQX_ALWAYS, HQX_3E_D, HQX_3E_D},
+	/* 0x04 */ {HQX_ALWAYS, HQX_2E_D_B, HQX_2E_D_B}, {HQX_ALWAYS, HQX_2E_D_B, HQX_2E_D_B},
+	/* 0x06 */ {HQX_ALWAYS, HQX_2E_A_D, HQX_2E_A_D}, {HQX_ALWAYS, HQX_3E_D, HQX_3E_D},
+	/* 0x08 */ {HQX_ALWAYS, HQX_2E_A_B, HQX_2E_A_B}, {HQX_ALWAYS, HQX_3E_B, HQX_3E_B},
+	/* 0x0A */ {HQX_IF_D_B, HQX_3E_A, HQX_2E_D_B}, {HQX_IF_D_B, HQX_E, HQX_2E_D_B},
+	/* 0x0C */ {HQX_ALWAYS, HQX_2E_A_B, HQX_2E_A_B}, {HQX_ALWAYS, HQX_3E_B, HQX_3E_B},
+	/* 0x0E */ {HQX_IF_D_B, HQX_3E_A, HQX_2E_3D_3B}, {HQX_IF_D_B, HQX_E, HQX_2E_3D_3B},
+	/* 0x10 */ {HQX_ALWAYS, HQX_2E_D_B, HQX_2E_D_B}, {HQX_ALWAYS, HQX_2E_D_B, HQX_2E_D_B},
+	/* 0x12 */ {HQX_ALWAYS, HQX_2E_A_D, HQX_2E_A_D}, {HQX_IF_B_F, HQX_3E_D, HQX_5E_2B_D},
+	/* 0x14 */ {HQX_ALWAYS, HQX_2E_D_B, HQX_2E_D_B}, {HQX_ALWAYS, HQX_2E_D_B, HQX_2E_D_B},
+	/* 0x16 */ {HQX_ALWAYS, HQX_2E_A_D, HQX_2E_A_D}, {HQX_IF_B_F, HQX_3E_D, HQX_5E_2B_D},
+	/* 0x18 */ {HQX_ALWAYS, HQX_2E_A_B, HQX_2E_A_B}, {HQX_ALWAYS, HQX_3E_B, HQX_3E_B},
+	/* 0x1A */ {HQX_IF_D_B, HQX_E, HQX_2E_D_B}, {HQX_IF_D_B, HQX_E, HQX_2E_D_B},
+	/* 0x1C */ {HQX_ALWAYS, HQX_2E_A_B, HQX_2E_A_B}, {HQX_ALWAYS, HQX_3E_B, HQX_3E_B},
+	/* 0x1E */ {HQX_ALWAYS, HQX_3E_A, HQX_3E_A}, {HQX_IF_D_B, HQX_E, HQX_2E_D_B},
+	/* 0x20 */ {HQX_ALWAYS, HQX_2E_D_B, HQX_2E_D_B}, {HQX_ALWAYS, HQX_2E_D_B, HQX_2E_D_B},
+	/* 0x22 */ {HQX_ALWAYS, HQX_2E_A_D, HQX_2E_A_D}, {HQX_ALWAYS, HQX_3E_D, HQX_3E_D},
+	/* 0x24 */ {HQX_ALWAYS, HQX_2E_D_B, HQX_2E_D_B}, {HQX_ALWAYS, HQX_2E_D_B, HQX_2E_D_B},
+	/* 0x26 */ {HQX_ALWAYS, HQX_2E_A_D, HQX_2E_A_D}, {HQX_ALWAYS, HQX_3E_D, HQX_3E_D},
+	/* 0x28 */ {HQX_ALWAYS, HQX_2E_A_B, HQX_2E_A_B}, {HQX_ALWAYS, HQX_3E_B, HQX_3E_B},
+	/* 0x2A */ {HQX_IF_D_B, HQX_3E_A, HQX_2E_3D_3B}, {HQX_IF_D_B, HQX_E, HQX_2E_3D_3B},
+	/* 0x2C */ {HQX_ALWAYS, HQX_2E_A_B, HQX_2E_A_B}, {HQX_ALWAYS, HQX_3E_B, HQX_3E_B},
+	/* 0x2E */ {HQX_IF_D_B, HQX_3E_A, HQX_6E_D_B}, {HQX_IF_D_B, HQX_E, HQX_14E_D_B},
+	/* 0x30 */ {HQX_ALWAYS, HQX_2E_D_B, HQX_2E_D_B}, {HQX_ALWAYS, HQX_2E_D_B, HQX_2E_D_B},
+	/* 0x32 */ {HQX_ALWAYS, HQX_2E_A_D, HQX_2E_A_D}, {HQX_IF_B_F, HQX_3E_D, HQX_5E_2B_D},
+	/* 0x34 */ {HQX_ALWAYS, HQX_2E_D_B, HQX_2E_D_B}, {HQX_ALWAYS, HQX_2E_D_B, HQX_2E_D_B},
+	/* 0x36 */ {HQX_ALWAYS, HQX_2E_A_D, HQX_2E_A_D}, {HQX_IF_B_F, HQX_3E_D, HQX_5E_2B_D},
+	/* 0x38 */ {HQX_ALWAYS, HQX_2E_A_B, HQX_2E_A_B}, {HQX_ALWAYS, HQX_3E_B, HQX_3E_B},
+	/* 0x3A */ {HQX_IF_D_B, HQX_3E_A, HQX_6E_D_B}, {HQX_IF_D_B, HQX_E, HQX_2E_D_B},
+	/* 0x3C */ {HQX_ALWAYS, HQX_2E_A_B, HQX_2E_A_B}, {HQX_ALWAYS, HQX_3E_B, HQX_3E_B},
+	/* 0x3E */ {HQX_ALWAYS, HQX_3E_A, HQX_3E_A}, {HQX_IF_D_B, HQX_E, HQX_14E_D_B},
+	/* 0x40 */ {HQX_ALWAYS, HQX_2E_D_B, HQX_2E_D_B}, {HQX_ALWAYS, HQX_2E_D_B, HQX_2E_D_B},
+	/* 0x42 */ {HQX_ALWAYS, HQX_2E_A_D, HQX_2E_A_D}, {HQX_ALWAYS, HQX_3E_D, HQX_3E_D},
+	/* 0x44 */ {HQX_ALWAYS, HQX_2E_D_B, HQX_2E_D_B}, {HQX_ALWAYS, HQX_2E_D_B, HQX_2E_D_B},
+	/* 0x46 */ {HQX_ALWAYS, HQX_2E_A_D, HQX_2E_A_D}, {HQX_ALWAYS, HQX_3E_D, HQX_3E_D},
+	/* 0x48 */ {HQX_ALWAYS, HQX_2E_A_B, HQX_2E_A_B}, {HQX_IF_D_H, HQX_3E_B, HQX_5E_2D_B},
+	/* 0x4A */ {HQX_IF_D_B, HQX_E, HQX_2E_D_B}, {HQX_IF_D_B, HQX_E, HQX_2E_D_B},
+	/* 0x4C */ {HQX_ALWAYS, HQX_2E_A_B, HQX_2E_A_B}, {HQX_IF_D_H, HQX_3E_B, HQX_5E_2D_B},
+	/* 0x4E */ {HQX_IF_D_B, HQX_3E_A, HQX_6E_D_B}, {HQX_IF_D_B, HQX_E, HQX_2E_D_B},
+	/* 0x50 */ {HQX_ALWAYS, HQX_2E_D_B, HQX_2E_D_B}, {HQX_ALWAYS, HQX_2E_D_B, HQX_2E_D_B},
+	/* 0x52 */ {HQX_ALWAYS, HQX_2E_A_D, HQX_2E_A_D}, {HQX_ALWAYS, HQX_3E_D, HQX_3E_D},
+	/* 0x54 */ {HQX_ALWAYS, HQX_2E_D_B, HQX_2E_D_B}, {HQX_ALWAYS, HQX_2E_D_B, HQX_2E_D_B},
+	/* 0x56 */ {HQX_ALWAYS, HQX_2E_A_D, HQX_2E_A_D}, {HQX_ALWAYS, HQX_3E_D, HQX_3E_D},
+	/* 0x58 */ {HQX_ALWAYS, HQX_2E_A_B, HQX_2E_A_B}, {HQX_ALWAYS, HQX_3E_B, HQX_3E_B},
+	/* 0x5A */ {HQX_IF_D_B, HQX_3E_A, HQX_6E_D_B}, {HQX_IF_D_B, HQX_E, HQX_2E_D_B},
+	/* 0x5C */ {HQX_ALWAYS, HQX_2E_A_B, HQX_2E_A_B}, {HQX_ALWAYS, HQX_3E_B, HQX_3E_B},
+	/* 0x5E */ {HQX_IF_D_B, HQX_3E_A, HQX_6E_D_B}, {HQX_IF_D_B, HQX_E, HQX_2E_D_B},
+	/* 0x60 */ {HQX_ALWAYS, HQX_2E_D_B, HQX_2E_D_B}, {HQX_ALWAYS, HQX_2E_D_B, HQX_2E_D_B},
+	/* 0x62 */ {HQX_ALWAYS, HQX_2E_A_D, HQX_2E_A_D}, {HQX_ALWAYS, HQX_3E_D, HQX_3E_D},
+	/* 0x64 */ {HQX_ALWAYS, HQX_2E_D_B, HQX_2E_D_B}, {HQX_ALWAYS, HQX_2E_D_B, HQX_2E_D_B},
+	/* 0x66 */ {HQX_ALWAYS, HQX_2E_A_D, HQX_2E_A_D}, {HQX_ALWAYS, HQX_3E_D, HQX_3E_D},
+	/* 0x68 */ {HQX_ALWAYS, HQX_2E_A_B, HQX_2E_A_B}, {HQX_IF_D_H, HQX_3E_B, HQX_5E_2D_B},
+	/* 0x6A */ {HQX_ALWAYS, HQX_3E_A, HQX_3E_A}, {HQX_IF_D_B, HQX_E, HQX_2E_D_B},
+	/* 0x6C */ {HQX_ALWAYS, HQX_2E_A_B, HQX_2E_A_B}, {HQX_IF_D_H, HQX_3E_B, HQX_5E_2D_B},
+	/* 0x6E */ {HQX_ALWAYS, HQX_3E_A, HQX_3E_A}, {HQX_IF_D_B, HQX_E, HQX_14E_D_B},
+	/* 0x70 */ {HQX_ALWAYS, HQX_2E_D_B, HQX_2E_D_B}, {HQX_ALWAYS, HQX_2E_D_B, HQX_2E_D_B},
+	/* 0x72 */ {HQX_ALWAYS, HQX_2E_A_D, HQX_2E_A_D}, {HQX_ALWAYS, HQX_3E_D, HQX_3E_D},
+	/* 0x74 */ {HQX_ALWAYS, HQX_2E_D_B, HQX_2E_D_B}, {HQX_ALWAYS, HQX_2E_D_B, HQX_2E_D_B},
+	/* 0x76 */ {HQX_ALWAYS, HQX_2E_A_D, HQX_2E_A_D}, {HQX_IF_B_F, HQX_3E_D, HQX_5E_2B_D},
+	/* 0x78 */ {HQX_ALWAYS, HQX_2E_A_B, HQX_2E_A_B}, {HQX_ALWAYS, HQX_3E_B, HQX_3E_B},
+	/* 0x7A */ {HQX_IF_D_B, HQX_3E_A, HQX_6E_D_B}, {HQX_IF_D_B, HQX_E, HQX_2E_D_B},
+	/* 0x7C */ {HQX_ALWAYS, HQX_2E_A_B, HQX_2E_A_B}, {HQX_IF_D_H, HQX_3E_B, HQX_5E_2D_B},
+	/* 0x7E */ {HQX_ALWAYS, HQX_3E_A, HQX_3E_A}, {HQX_IF_D_B, HQX_E, HQX_14E_D_B},
+	/* 0x80 */ {HQX_ALWAYS, HQX_2E_D_B, HQX_2E_D_B}, {HQX_ALWAYS, HQX_2E_D_B, HQX_2E_D_B},
+	/* 0x82 */ {HQX_ALWAYS, HQX_2E_A_D, HQX_2E_A_D}, {HQX_ALWAYS, HQX_3E_D, HQX_3E_D},
+	/* 0x84 */ {HQX_ALWAYS, HQX_2E_D_B, HQX_2E_D_B}, {HQX_ALWAYS, HQX_2E_D_B, HQX_2E_D_B},
+	/* 0x86 */ {HQX_ALWAYS, HQX_2E_A_D, HQX_2E_A_D}, {HQX_ALWAYS, HQX_3E_D, HQX_3E_D},
+	/* 0x88 */ {HQX_ALWAYS, HQX_2E_A_B, HQX_2E_A_B}, {HQX_ALWAYS, HQX_3E_B, HQX_3E_B},
+	/* 0x8A */ {HQX_IF_D_B, HQX_3E_A, HQX_2E_D_B}, {HQX_IF_D_B, HQX_E, HQX_2E_D_B},
+	/* 0x8C */ {HQX_ALWAYS, HQX_2E_A_B, HQX_2E_A_B}, {HQX_ALWAYS, HQX_3E_B, HQX_3E_B},
+	/* 0x8E */ {HQX_IF_D_B, HQX_3E_A, HQX_2E_3D_3B}, {HQX_IF_D_B, HQX_E, HQX_2E_3D_3B},
+	/* 0x90 */ {HQX_ALWAYS, HQX_2E_D_B, HQX_2E_D_B}, {HQX_ALWAYS, HQX_2E_D_B, HQX_2E_D_B},
+	/* 0x92 */ {HQX_ALWAYS, HQX_2E_A_D, HQX_2E_A_D}, {HQX_ALWAYS, HQX_3E_D, HQX_3E_D},
+	/* 0x94 */ {HQX_ALWAYS, HQX_2E_D_B, HQX_2E_D_B}, {HQX_ALWAYS, HQX_2E_D_B, HQX_2E_D_B},
+	/* 0x96 */ {HQX_ALWAYS, HQX_2E_A_D, HQX_2E_A_D}, {HQX_ALWAYS, HQX_3E_D, HQX_3E_D},
+	/* 0x98 */ {HQX_ALWAYS, HQX_2E_A_B, HQX_2E_A_B}, {HQX_ALWAYS, HQX_3E_B, HQX_3E_B},
+	/* 0x9A */ {HQX_IF_D_B, HQX_3E_A, HQX_6E_D_B}, {HQX_IF_D_B, HQX_E, HQX_2E_D_B},
+	/* 0x9C */ {HQX_ALWAYS, HQX_2E_A_B, HQX_2E_A_B}, {HQX_ALWAYS, HQX_3E_B, HQX_3E_B},
+	/* 0x9E */ {HQX_IF_D_B, HQX_3E_A, HQX_6E_D_B}, {HQX_IF_D_B, HQX_E, HQX_2E_D_B},
+	/* 0xA0 */ {HQX_ALWAYS, HQX_2E_D_B, HQX_2E_D_B}, {HQX_ALWAYS, HQX_2E_D_B, HQX_2E_D_B},
+	/* 0xA2 */ {HQX_ALWAYS, HQX_2E_A_D, HQX_2E_A_D}, {HQX_ALWAYS, HQX_3E_D, HQX_3E_D},
+	/* 0xA4 */ {HQX_ALWAYS, HQX_2E_D_B, HQX_2E_D_B}, {HQX_ALWAYS, HQX_2E_D_B, HQX_2E_D_B},
+	/* 0xA6 */ {HQX_ALWAYS, HQX_2E_A_D, HQX_2E_A_D}, {HQX_ALWAYS, HQX_3E_D, HQX_3E_D},
+	/* 0xA8 */ {HQX_ALWAYS, HQX_2E_A_B, HQX_2E_A_B}, {HQX_ALWAYS, HQX_3E_B, HQX_3E_B},
+	/* 0xAA */ {HQX_IF_D_B, HQX_3E_A, HQX_2E_3D_3B}, {HQX_IF_D_B, HQX_E, HQX_2E_3D_3B},
+	/* 0xAC */ {HQX_ALWAYS, HQX_2E_A_B, HQX_2E_A_B}, {HQX_ALWAYS, HQX_3E_B, HQX_3E_B},
+	/* 0xAE */ {HQX_IF_D_B, HQX_3E_A, HQX_6E_D_B}, {HQX_IF_D_B, HQX_E, HQX_14E_D_B},
+	/* 0xB0 */ {HQX_ALWAYS, HQX_2E_D_B, HQX_2E_D_B}, {HQX_ALWAYS, HQX_2E_D_B, HQX_2E_D_B},
+	/* 0xB2 */ {HQX_ALWAYS, HQX_2E_A_D, HQX_2E_A_D}, {HQX_ALWAYS, HQX_3E_D, HQX_3E_D},
+	/* 0xB4 */ {HQX_ALWAYS, HQX_2E_D_B, HQX_2E_D_B}, {HQX_ALWAYS, HQX_2E_D_B, HQX_2E_D_B},
+	/* 0xB6 */ {HQX_ALWAYS, HQX_2E_A_D, HQX_2E_A_D}, {HQX_ALWAYS, HQX_3E_D, HQX_3E_D},
+	/* 0xB8 */ {HQX_ALWAYS, HQX_2E_A_B, HQX_2E_A_B}, {HQX_ALWAYS, HQX_3E_B, HQX_3E_B},
+	/* 0xBA */ {HQX_IF_D_B, HQX_3E_A, HQX_6E_D_B}, {HQX_IF_D_B, HQX_E, HQX_2E_3D_3B},
+	/* 0xBC */ {HQX_ALWAYS, HQX_2E_A_B, HQX_2E_A_B}, {HQX_ALWAYS, HQX_3E_B, HQX_3E_B},
+	/* 0xBE */ {HQX_ALWAYS, HQX_3E_A, HQX_3E_A}, {HQX_IF_D_B, HQX_E, HQX_14E_D_B},
+	/* 0xC0 */ {HQX_ALWAYS, HQX_2E_D_B, HQX_2E_D_B}, {HQX_ALWAYS, HQX_2E_D_B, HQX_2E_D_B},
+	/* 0xC2 */ {HQX_ALWAYS, HQX_2E_A_D, HQX_2E_A_D}, {HQX_ALWAYS, HQX_3E_D, HQX_3E_D},
+	/* 0xC4 */ {HQX_ALWAYS, HQX_2E_D_B, HQX_2E_D_B}, {HQX_ALWAYS, HQX_2E_D_B, HQX_2E_D_B},
+	/* 0xC6 */ {HQX_ALWAYS, HQX_2E_A_D, HQX_2E_A_D}, {HQX_ALWAYS, HQX_3E_D, HQX_3E_D},
+	/* 0xC8 */ {HQX_ALWAYS, HQX_2E_A_B, HQX_2E_A_B}, {HQX_ALWAYS, HQX_3E_B, HQX_3E_B},
+	/* 0xCA */ {HQX_IF_D_B, HQX_3E_A, HQX_6E_D_B}, {HQX_IF_D_B, HQX_E, HQX_2E_D_B},
+	/* 0xCC */ {HQX_ALWAYS, HQX_2E_A_B, HQX_2E_A_B}, {HQX_ALWAYS, HQX_3E_B, HQX_3E_B},
+	/* 0xCE */ {HQX_IF_D_B, HQX_3E_A, HQX_6E_D_B}, {HQX_IF_D_B, HQX_E, HQX_2E_3D_3B},
+	/* 0xD0 */ {HQX_ALWAYS, HQX_2E_D_B, HQX_2E_D_B}, {HQX_ALWAYS, HQX_2E_D_B, HQX_2E_D_B},
+	/* 0xD2 */ {HQX_ALWAYS, HQX_2E_A_D, HQX_2E_A_D}, {HQX_ALWAYS, HQX_3E_D, HQX_3E_D},
+	/* 0xD4 */ {HQX_ALWAYS, HQX_2E_D_B, HQX_2E_D_B}, {HQX_ALWAYS, HQX_2E_D_B, HQX_2E_D_B},
+	/* 0xD6 */ {HQX_ALWAYS, HQX_2E_A_D, HQX_2E_A_D}, {HQX_ALWAYS, HQX_3E_D, HQX_3E_D},
+	/* 0xD8 */ {HQX_ALWAYS, HQX_2E_A_B, HQX_2E_A_B}, {HQX_ALWAYS, HQX_3E_B, HQX_3E_B},
+	/* 0xDA */ {HQX_IF_D_B, HQX_3E_A, HQX_6E_D_B}, {HQX_IF_D_B, HQX_E, HQX_2E_D_B},
+	/* 0xDC */ {HQX_ALWAYS, HQX_2E_A_B, HQX_2E_A_B}, {HQX_ALWAYS, HQX_3E_B, HQX_3E_B},
+	/* 0xDE */ {HQX_ALWAYS, HQX_3E_A, HQX_3E_A}, {HQX_IF_D_B, HQX_E, HQX_2E_D_B},
+	/* 0xE0 */ {HQX_ALWAYS, HQX_2E_D_B, HQX_2E_D_B}, {HQX_ALWAYS, HQX_2E_D_B, HQX_2E_D_B},
+	/* 0xE2 */ {HQX_ALWAYS, HQX_2E_A_D, HQX_2E_A_D}, {HQX_ALWAYS, HQX_3E_D, HQX_3E_D},
+	/* 0xE4 */ {HQX_ALWAYS, HQX_2E_D_B, HQX_2E_D_B}, {HQX_ALWAYS, HQX_2E_D_B, HQX_2E_D_B},
+	/* 0xE6 */ {HQX_ALWAYS, HQX_2E_A_D, HQX_2E_A_D}, {HQX_ALWAYS, HQX_3E_D, HQX_3E_D},
+	/* 0xE8 */ {HQX_ALWAYS, HQX_2E_A_B, HQX_2E_A_B}, {HQX_ALWAYS, HQX_3E_B, HQX_3E_B},
+	/* 0xEA */ {HQX_IF_D_B, HQX_3E_A, HQX_6E_D_B}, {HQX_IF_D_B, HQX_E, HQX_2E_D_B},
+	/* 0xEC */ {HQX_ALWAYS, HQX_2E_A_B, HQX_2E_A_B}, {HQX_ALWAYS, HQX_3E_B, HQX_3E_B},
+	/* 0xEE */ {HQX_ALWAYS, HQX_3E_A, HQX_3E_A}, {HQX_IF_D_B, HQX_E, HQX_14E_D_B},
+	/* 0xF0 */ {HQX_ALWAYS, HQX_2E_D_B, HQX_2E_D_B}, {HQX_ALWAYS, HQX_2E_D_B, HQX_2E_D_B},
+	/* 0xF2 */ {HQX_ALWAYS, HQX_2E_A_D, HQX_2E_A_D}, {HQX_ALWAYS, HQX_3E_D, HQX_3E_D},
+	/* 0xF4 */ {HQX_ALWAYS, HQX_2E_D_B, HQX_2E_D_B}, {HQX_ALWAYS, HQX_2E_D_B, HQX_2E_D_B},
+	/* 0xF6 */ {HQX_ALWAYS, HQX_2E_A_D, HQX_2E_A_D}, {HQX_ALWAYS, HQX_3E_D, HQX_3E_D},
+	/* 0xF8 */ {HQX_ALWAYS, HQX_2E_A_B, HQX_2E_A_B}, {HQX_ALWAYS, HQX_3E_B, HQX_3E_B},
+	/* 0xFA */ {HQX_ALWAYS, HQX_3E_A, HQX_3E_A}, {HQX_IF_D_B, HQX_E, HQX_2E_D_B},
+	/* 0xFC */ {HQX_ALWAYS, HQX_2E_A_B, HQX_2E_A_B}, {HQX_ALWAYS, HQX_3E_B, HQX_3E_B},
+	/* 0xFE */ {HQX_ALWAYS, HQX_3E_A, HQX_3E_A}, {HQX_IF_D_B, HQX_E, HQX_14E_D_B},
+}
+
+var hq3xCorners = [256]hqxRule{
+	/* 0x00 */ {HQX_ALWAYS, HQX_2E_D_B, HQX_2E_D_B}, {HQX_ALWAYS, HQX_2E_D_B, HQX_2E_D_B},
+	/* 0x02 */ {HQX_ALWAYS, HQX_3E_A, HQX_3E_A}, {HQX_ALWAYS, HQX_3E_D, HQX_3E_D},
+	/* 0x04 */ {HQX_ALWAYS, HQX_2E_D_B, HQX_2E_D_B}, {HQX_ALWAYS, HQX_2E_D_B, HQX_2E_D_B},
+	/* 0x06 */ {HQX_ALWAYS, HQX_3E_A, HQX_3E_A}, {HQX_ALWAYS, HQX_3E_D, HQX_3E_D},
+	/* 0x08 */ {HQX_ALWAYS, HQX_3E_A, HQX_3E_A}, {HQX_ALWAYS, HQX_3E_B, HQX_3E_B},
+	/* 0x0A */ {HQX_IF_D_B, HQX_3E_A, HQX_2E_7D_7B}, {HQX_IF_D_B, HQX_E, HQX_2E_7D_7B},
+	/* 0x0C */ {HQX_ALWAYS, HQX_3E_A, HQX_3E_A}, {HQX_ALWAYS, HQX_3E_B, HQX_3E_B},
+	/* 0x0E */ {HQX_IF_D_B, HQX_3E_A, HQX_D_B}, {HQX_IF_D_B, HQX_E, HQX_D_B},
+	/* 0x10 */ {HQX_ALWAYS, HQX_2E_D_B, HQX_2E_D_B}, {HQX_ALWAYS, HQX_2E_D_B, HQX_2E_D_B},
+	/* 0x12 */ {HQX_ALWAYS, HQX_3E_A, HQX_3E_A}, {HQX_IF_B_F, HQX_3E_D, HQX_2E_D_B},
+	/* 0x14 */ {HQX_ALWAYS, HQX_2E_D_B, HQX_2E_D_B}, {HQX_ALWAYS, HQX_2E_D_B, HQX_2E_D_B},
+	/* 0x16 */ {HQX_ALWAYS, HQX_3E_A, HQX_3E_A}, {HQX_IF_B_F, HQX_3E_D, HQX_2E_D_B},
+	/* 0x18 */ {HQX_ALWAYS, HQX_3E_A, HQX_3E_A}, {HQX_ALWAYS, HQX_3E_B, HQX_3E_B},
+	/* 0x1A */ {HQX_IF_D_B, HQX_E, HQX_2E_7D_7B}, {HQX_IF_D_B, HQX_E, HQX_2E_7D_7B},
+	/* 0x1C */ {HQX_ALWAYS, HQX_3E_A, HQX_3E_A}, {HQX_ALWAYS, HQX_3E_B, HQX_3E_B},
+	/* 0x1E */ {HQX_ALWAYS, HQX_3E_A, HQX_3E_A}, {HQX_IF_D_B, HQX_E, HQX_2E_7D_7B},
+	/* 0x20 */ {HQX_ALWAYS, HQX_2E_D_B, HQX_2E_D_B}, {HQX_ALWAYS, HQX_2E_D_B, HQX_2E_D_B},
+	/* 0x22 */ {HQX_ALWAYS, HQX_3E_A, HQX_3E_A}, {HQX_ALWAYS, HQX_3E_D, HQX_3E_D},
+	/* 0x24 */ {HQX_ALWAYS, HQX_2E_D_B, HQX_2E_D_B}, {HQX_ALWAYS, HQX_2E_D_B, HQX_2E_D_B},
+	/* 0x26 */ {HQX_ALWAYS, HQX_3E_A, HQX_3E_A}, {HQX_ALWAYS, HQX_3E_D, HQX_3E_D},
+	/* 0x28 */ {HQX_ALWAYS, HQX_3E_A, HQX_3E_A}, {HQX_ALWAYS, HQX_3E_B, HQX_3E_B},
+	/* 0x2A */ {HQX_IF_D_B, HQX_3E_A, HQX_D_B}, {HQX_IF_D_B, HQX_E, HQX_D_B},
+	/* 0x2C */ {HQX_ALWAYS, HQX_3E_A, HQX_3E_A}, {HQX_ALWAYS, HQX_3E_B, HQX_3E_B},
+	/* 0x2E */ {HQX_IF_D_B, HQX_3E_A, HQX_2E_D_B}, {HQX_IF_D_B, HQX_E, HQX_2E_D_B},
+	/* 0x30 */ {HQX_ALWAYS, HQX_2E_D_B, HQX_2E_D_B}, {HQX_ALWAYS, HQX_2E_D_B, HQX_2E_D_B},
+	/* 0x32 */ {HQX_ALWAYS, HQX_3E_A, HQX_3E_A}, {HQX_IF_B_F, HQX_3E_D, HQX_2E_D_B},
+	/* 0x34 */ {HQX_ALWAYS, HQX_2E_D_B, HQX_2E_D_B}, {HQX_ALWAYS, HQX_2E_D_B, HQX_2E_D_B},
+	/* 0x36 */ {HQX_ALWAYS, HQX_3E_A, HQX_3E_A}, {HQX_IF_B_F, HQX_3E_D, HQX_2E_D_B},
+	/* 0x38 */ {HQX_ALWAYS, HQX_3E_A, HQX_3E_A}, {HQX_ALWAYS, HQX_3E_B, HQX_3E_B},
+	/* 0x3A */ {HQX_IF_D_B, HQX_3E_A, HQX_2E_D_B}, {HQX_IF_D_B, HQX_E, HQX_2E_7D_7B},
+	/* 0x3C */ {HQX_ALWAYS, HQX_3E_A, HQX_3E_A}, {HQX_ALWAYS, HQX_3E_B, HQX_3E_B},
+	/* 0x3E */ {HQX_ALWAYS, HQX_3E_A, HQX_3E_A}, {HQX_IF_D_B, HQX_E, HQX_2E_D_B},
+	/* 0x40 */ {HQX_ALWAYS, HQX_2E_D_B, HQX_2E_D_B}, {HQX_ALWAYS, HQX_2E_D_B, HQX_2E_D_B},
+	/* 0x42 */ {HQX_ALWAYS, HQX_3E_A, HQX_3E_A}, {HQX_ALWAYS, HQX_3E_D, HQX_3E_D},
+	/* 0x44 */ {HQX_ALWAYS, HQX_2E_D_B, HQX_2E_D_B}, {HQX_ALWAYS, HQX_2E_D_B, HQX_2E_D_B},
+	/* 0x46 */ {HQX_ALWAYS, HQX_3E_A, HQX_3E_A}, {HQX_ALWAYS, HQX_3E_D, HQX_3E_D},
+	/* 0x48 */ {HQX_ALWAYS, HQX_3E_A, HQX_3E_A}, {HQX_IF_D_H, HQX_3E_B, HQX_2E_D_B},
+	/* 0x4A */ {HQX_IF_D_B, HQX_E, HQX_2E_7D_7B}, {HQX_IF_D_B, HQX_E, HQX_2E_7D_7B},
+	/* 0x4C */ {HQX_ALWAYS, HQX_3E_A, HQX_3E_A}, {HQX_IF_D_H, HQX_3E_B, HQX_2E_D_B},
+	/* 0x4E */ {HQX_IF_D_B, HQX_3E_A, HQX_2E_D_B}, {HQX_IF_D_B, HQX_E, HQX_2E_7D_7B},
+	/* 0x50 */ {HQX_ALWAYS, HQX_2E_D_B, HQX_2E_D_B}, {HQX_ALWAYS, HQX_2E_D_B, HQX_2E_D_B},
+	/* 0x52 */ {HQX_ALWAYS, HQX_3E_A, HQX_3E_A}, {HQX_ALWAYS, HQX_3E_D, HQX_3E_D},
+	/* 0x54 */ {HQX_ALWAYS, HQX_2E_D_B, HQX_2E_D_B}, {HQX_ALWAYS, HQX_2E_D_B, HQX_2E_D_B},
+	/* 0x56 */ {HQX_ALWAYS, HQX_3E_A, HQX_3E_A}, {HQX_ALWAYS, HQX_3E_D, HQX_3E_D},
+	/* 0x58 */ {HQX_ALWAYS, HQX_3E_A, HQX_3E_A}, {HQX_ALWAYS, HQX_3E_B, HQX_3E_B},
+	/* 0x5A */ {HQX_IF_D_B, HQX_3E_A, HQX_2E_D_B}, {HQX_IF_D_B, HQX_E, HQX_2E_7D_7B},
+	/* 0x5C */ {HQX_ALWAYS, HQX_3E_A, HQX_3E_A}, {HQX_ALWAYS, HQX_3E_B, HQX_3E_B},
+	/* 0x5E */ {HQX_IF_D_B, HQX_3E_A, HQX_2E_D_B}, {HQX_IF_D_B, HQX_E, HQX_2E_7D_7B},
+	/* 0x60 */ {HQX_ALWAYS, HQX_2E_D_B, HQX_2E_D_B}, {HQX_ALWAYS, HQX_2E_D_B, HQX_2E_D_B},
+	/* 0x62 */ {HQX_ALWAYS, HQX_3E_A, HQX_3E_A}, {HQX_ALWAYS, HQX_3E_D, HQX_3E_D},
+	/* 0x64 */ {HQX_ALWAYS, HQX_2E_D_B, HQX_2E_D_B}, {HQX_ALWAYS, HQX_2E_D_B, HQX_2E_D_B},
+	/* 0x66 */ {HQX_ALWAYS, HQX_3E_A, HQX_3E_A}, {HQX_ALWAYS, HQX_3E_D, HQX_3E_D},
+	/* 0x68 */ {HQX_ALWAYS, HQX_3E_A, HQX_3E_A}, {HQX_IF_D_H, HQX_3E_B, HQX_2E_D_B},
+	/* 0x6A */ {HQX_ALWAYS, HQX_3E_A, HQX_3E_A}, {HQX_IF_D_B, HQX_E, HQX_2E_7D_7B},
+	/* 0x6C */ {HQX_ALWAYS, HQX_3E_A, HQX_3E_A}, {HQX_IF_D_H, HQX_3E_B, HQX_2E_D_B},
+	/* 0x6E */ {HQX_ALWAYS, HQX_3E_A, HQX_3E_A}, {HQX_IF_D_B, HQX_E, HQX_2E_D_B},
+	/* 0x70 */ {HQX_ALWAYS, HQX_2E_D_B, HQX_2E_D_B}, {HQX_ALWAYS, HQX_2E_D_B, HQX_2E_D_B},
+	/* 0x72 */ {HQX_ALWAYS, HQX_3E_A, HQX_3E_A}, {HQX_ALWAYS, HQX_3E_D, HQX_3E_D},
+	/* 0x74 */ {HQX_ALWAYS, HQX_2E_D_B, HQX_2E_D_B}, {HQX_ALWAYS, HQX_2E_D_B, HQX_2E_D_B},
+	/* 0x76 */ {HQX_ALWAYS, HQX_3E_A, HQX_3E_A}, {HQX_IF_B_F, HQX_3E_D, HQX_2E_D_B},
+	/* 0x78 */ {HQX_ALWAYS, HQX_3E_A, HQX_3E_A}, {HQX_ALWAYS, HQX_3E_B, HQX_3E_B},
+	/* 0x7A */ {HQX_IF_D_B, HQX_3E_A, HQX_2E_D_B}, {HQX_IF_D_B, HQX_E, HQX_2E_7D_7B},
+	/* 0x7C */ {HQX_ALWAYS, HQX_3E_A, HQX_3E_A}, {HQX_IF_D_H, HQX_3E_B, HQX_2E_D_B},
+	/* 0x7E */ {HQX_ALWAYS, HQX_3E_A, HQX_3E_A}, {HQX_IF_D_B, HQX_E, HQX_2E_D_B},
+	/* 0x80 */ {HQX_ALWAYS, HQX_2E_D_B, HQX_2E_D_B}, {HQX_ALWAYS, HQX_2E_D_B, HQX_2E_D_B},
+	/* 0x82 */ {HQX_ALWAYS, HQX_3E_A, HQX_3E_A}, {HQX_ALWAYS, HQX_3E_D, HQX_3E_D},
+	/* 0x84 */ {HQX_ALWAYS, HQX_2E_D_B, HQX_2E_D_B}, {HQX_ALWAYS, HQX_2E_D_B, HQX_2E_D_B},
+	/* 0x86 */ {HQX_ALWAYS, HQX_3E_A, HQX_3E_A}, {HQX_ALWAYS, HQX_3E_D, HQX_3E_D},
+	/* 0x88 */ {HQX_ALWAYS, HQX_3E_A, HQX_3E_A}, {HQX_ALWAYS, HQX_3E_B, HQX_3E_B},
+	/* 0x8A */ {HQX_IF_D_B, HQX_3E_A, HQX_2E_7D_7B}, {HQX_IF_D_B, HQX_E, HQX_2E_7D_7B},
+	/* 0x8C */ {HQX_ALWAYS, HQX_3E_A, HQX_3E_A}, {HQX_ALWAYS, HQX_3E_B, HQX_3E_B},
+	/* 0x8E */ {HQX_IF_D_B, HQX_3E_A, HQX_D_B}, {HQX_IF_D_B, HQX_E, HQX_D_B},
+	/* 0x90 */ {HQX_ALWAYS, HQX_2E_D_B, HQX_2E_D_B}, {HQX_ALWAYS, HQX_2E_D_B, HQX_2E_D_B},
+	/* 0x92 */ {HQX_ALWAYS, HQX_3E_A, HQX_3E_A}, {HQX_ALWAYS, HQX_3E_D, HQX_3E_D},
+	/* 0x94 */ {HQX_ALWAYS, HQX_2E_D_B, HQX_2E_D_B}, {HQX_ALWAYS, HQX_2E_D_B, HQX_2E_D_B},
+	/* 0x96 */ {HQX_ALWAYS, HQX_3E_A, HQX_3E_A}, {HQX_ALWAYS, HQX_3E_D, HQX_3E_D},
+	/* 0x98 */ {HQX_ALWAYS, HQX_3E_A, HQX_3E_A}, {HQX_ALWAYS, HQX_3E_B, HQX_3E_B},
+	/* 0x9A */ {HQX_IF_D_B, HQX_3E_A, HQX_2E_D_B}, {HQX_IF_D_B, HQX_E, HQX_2E_7D_7B},
+	/* 0x9C */ {HQX_ALWAYS, HQX_3E_A, HQX_3E_A}, {HQX_ALWAYS, HQX_3E_B, HQX_3E_B},
+	/* 0x9E */ {HQX_IF_D_B, HQX_3E_A, HQX_2E_D_B}, {HQX_IF_D_B, HQX_E, HQX_2E_7D_7B},
+	/* 0xA0 */ {HQX_ALWAYS, HQX_2E_D_B, HQX_2E_D_B}, {HQX_ALWAYS, HQX_2E_D_B, HQX_2E_D_B},
+	/* 0xA2 */ {HQX_ALWAYS, HQX_3E_A, HQX_3E_A}, {HQX_ALWAYS, HQX_3E_D, HQX_3E_D},
+	/* 0xA4 */ {HQX_ALWAYS, HQX_2E_D_B, HQX_2E_D_B}, {HQX_ALWAYS, HQX_2E_D_B, HQX_2E_D_B},
+	/* 0xA6 */ {HQX_ALWAYS, HQX_3E_A, HQX_3E_A}, {HQX_ALWAYS, HQX_3E_D, HQX_3E_D},
+	/* 0xA8 */ {HQX_ALWAYS, HQX_3E_A, HQX_3E_A}, {HQX_ALWAYS, HQX_3E_B, HQX_3E_B},
+	/* 0xAA */ {HQX_IF_D_B, HQX_3E_A, HQX_D_B}, {HQX_IF_D_B, HQX_E, HQX_D_B},
+	/* 0xAC */ {HQX_ALWAYS, HQX_3E_A, HQX_3E_A}, {HQX_ALWAYS, HQX_3E_B, HQX_3E_B},
+	/* 0xAE */ {HQX_IF_D_B, HQX_3E_A, HQX_2E_D_B}, {HQX_IF_D_B, HQX_E, HQX_2E_D_B},
+	/* 0xB0 */ {HQX_ALWAYS, HQX_2E_D_B, HQX_2E_D_B}, {HQX_ALWAYS, HQX_2E_D_B, HQX_2E_D_B},
+	/* 0xB2 */ {HQX_ALWAYS, HQX_3E_A, HQX_3E_A}, {HQX_ALWAYS, HQX_3E_D, HQX_3E_D},
+	/* 0xB4 */ {HQX_ALWAYS, HQX_2E_D_B, HQX_2E_D_B}, {HQX_ALWAYS, HQX_2E_D_B, HQX_2E_D_B},
+	/* 0xB6 */ {HQX_ALWAYS, HQX_3E_A, HQX_3E_A}, {HQX_ALWAYS, HQX_3E_D, HQX_3E_D},
+	/* 0xB8 */ {HQX_ALWAYS, HQX_3E_A, HQX_3E_A}, {HQX_ALWAYS, HQX_3E_B, HQX_3E_B},
+	/* 0xBA */ {HQX_IF_D_B, HQX_3E_A, HQX_2E_D_B}, {HQX_IF_D_B, HQX_E, HQX_D_B},
+	/* 0xBC */ {HQX_ALWAYS, HQX_3E_A, HQX_3E_A}, {HQX_ALWAYS, HQX_3E_B, HQX_3E_B},
+	/* 0xBE */ {HQX_ALWAYS, HQX_3E_A, HQX_3E_A}, {HQX_IF_D_B, HQX_E, HQX_2E_D_B},
+	/* 0xC0 */ {HQX_ALWAYS, HQX_2E_D_B, HQX_2E_D_B}, {HQX_ALWAYS, HQX_2E_D_B, HQX_2E_D_B},
+	/* 0xC2 */ {HQX_ALWAYS, HQX_3E_A, HQX_3E_A}, {HQX_ALWAYS, HQX_3E_D, HQX_3E_D},
+	/* 0xC4 */ {HQX_ALWAYS, HQX_2E_D_B, HQX_2E_D_B}, {HQX_ALWAYS, HQX_2E_D_B, HQX_2E_D_B},
+	/* 0xC6 */ {HQX_ALWAYS, HQX_3E_A, HQX_3E_A}, {HQX_ALWAYS, HQX_3E_D, HQX_3E_D},
+	/* 0xC8 */ {HQX_ALWAYS, HQX_3E_A, HQX_3E_A}, {HQX_ALWAYS, HQX_3E_B, HQX_3E_B},
+	/* 0xCA */ {HQX_IF_D_B, HQX_3E_A, HQX_2E_D_B}, {HQX_IF_D_B, HQX_E, HQX_2E_7D_7B},
+	/* 0xCC */ {HQX_ALWAYS, HQX_3E_A, HQX_3E_A}, {HQX_ALWAYS, HQX_3E_B, HQX_3E_B},
+	/* 0xCE */ {HQX_IF_D_B, HQX_3E_A, HQX_2E_D_B}, {HQX_IF_D_B, HQX_E, HQX_D_B},
+	/* 0xD0 */ {HQX_ALWAYS, HQX_2E_D_B, HQX_2E_D_B}, {HQX_ALWAYS, HQX_2E_D_B, HQX_2E_D_B},
+	/* 0xD2 */ {HQX_ALWAYS, HQX_3E_A, HQX_3E_A}, {HQX_ALWAYS, HQX_3E_D, HQX_3E_D},
+	/* 0xD4 */ {HQX_ALWAYS, HQX_2E_D_B, HQX_2E_D_B}, {HQX_ALWAYS, HQX_2E_D_B, HQX_2E_D_B},
+	/* 0xD6 */ {HQX_ALWAYS, HQX_3E_A, HQX_3E_A}, {HQX_ALWAYS, HQX_3E_D, HQX_3E_D},
+	/* 0xD8 */ {HQX_ALWAYS, HQX_3E_A, HQX_3E_A}, {HQX_ALWAYS, HQX_3E_B, HQX_3E_B},
+	/* 0xDA */ {HQX_IF_D_B, HQX_3E_A, HQX_2E_D_B}, {HQX_IF_D_B, HQX_E, HQX_2E_7D_7B},
+	/* 0xDC */ {HQX_ALWAYS, HQX_3E_A, HQX_3E_A}, {HQX_ALWAYS, HQX_3E_B, HQX_3E_B},
+	/* 0xDE */ {HQX_ALWAYS, HQX_3E_A, HQX_3E_A}, {HQX_IF_D_B, HQX_E, HQX_2E_7D_7B},
+	/* 0xE0 */ {HQX_ALWAYS, HQX_2E_D_B, HQX_2E_D_B}, {HQX_ALWAYS, HQX_2E_D_B, HQX_2E_D_B},
+	/* 0xE2 */ {HQX_ALWAYS, HQX_3E_A, HQX_3E_A}, {HQX_ALWAYS, HQX_3E_D, HQX_3E_D},
+	/* 0xE4 */ {HQX_ALWAYS, HQX_2E_D_B, HQX_2E_D_B}, {HQX_ALWAYS, HQX_2E_D_B, HQX_2E_D_B},
+	/* 0xE6 */ {HQX_ALWAYS, HQX_3E_A, HQX_3E_A}, {HQX_ALWAYS, HQX_3E_D, HQX_3E_D},
+	/* 0xE8 */ {HQX_ALWAYS, HQX_3E_A, HQX_3E_A}, {HQX_ALWAYS, HQX_3E_B, HQX_3E_B},
+	/* 0xEA */ {HQX_IF_D_B, HQX_3E_A, HQX_2E_D_B}, {HQX_IF_D_B, HQX_E, HQX_2E_7D_7B},
+	/* 0xEC */ {HQX_ALWAYS, HQX_3E_A, HQX_3E_A}, {HQX_ALWAYS, HQX_3E_B, HQX_3E_B},
+	/* 0xEE */ {HQX_ALWAYS, HQX_3E_A, HQX_3E_A}, {HQX_IF_D_B, HQX_E, HQX_2E_D_B},
+	/* 0xF0 */ {HQX_ALWAYS, HQX_2E_D_B, HQX_2E_D_B}, {HQX_ALWAYS, HQX_2E_D_B, HQX_2E_D_B},
+	/* 0xF2 */ {HQX_ALWAYS, HQX_3E_A, HQX_3E_A}, {HQX_ALWAYS, HQX_3E_D, HQX_3E_D},
+	/* 0xF4 */ {HQX_ALWAYS, HQX_2E_D_B, HQX_2E_D_B}, {HQX_ALWAYS, HQX_2E_D_B, HQX_2E_D_B},
+	/* 0xF6 */ {HQX_ALWAYS, HQX_3E_A, HQX_3E_A}, {HQX_ALWAYS, HQX_3E_D, HQX_3E_D},
+	/* 0xF8 */ {HQX_ALWAYS, HQX_3E_A, HQX_3E_A}, {HQX_ALWAYS, HQX_3E_B, HQX_3E_B},
+	/* 0xFA */ {HQX_ALWAYS, HQX_3E_A, HQX_3E_A}, {HQX_IF_D_B, HQX_E, HQX_2E_7D_7B},
+	/* 0xFC */ {HQX_ALWAYS, HQX_3E_A, HQX_3E_A}, {HQX_ALWAYS, HQX_3E_B, HQX_3E_B},
+	/* 0xFE */ {HQX_ALWAYS, HQX_3E_A, HQX_3E_A}, {HQX_IF_D_B, HQX_E, HQX_2E_D_B},
+}
+
+var hq3xEdges = [256]hqxRule{
+	/* 0x00 */ {HQX_ALWAYS, HQX_3E_B, HQX_3E_B}, {HQX_ALWAYS, HQX_3E_B, HQX_3E_B},
+	/* 0x02 */ {HQX_ALWAYS, HQX_E, HQX_E}, {HQX_ALWAYS, HQX_E, HQX_E},
+	/* 0x04 */ {HQX_ALWAYS, HQX_3E_B, HQX_3E_B}, {HQX_ALWAYS, HQX_3E_B, HQX_3E_B},
+	/* 0x06 */ {HQX_ALWAYS, HQX_E, HQX_E}, {HQX_ALWAYS, HQX_E, HQX_E},
+	/* 0x08 */ {HQX_ALWAYS, HQX_3E_B, HQX_3E_B}, {HQX_ALWAYS, HQX_3E_B, HQX_3E_B},
+	/* 0x0A */ {HQX_IF_D_B, HQX_E, HQX_7E_B}, {HQX_IF_D_B, HQX_E, HQX_7E_B},
+	/* 0x0C */ {HQX_ALWAYS, HQX_3E_B, HQX_3E_B}, {HQX_ALWAYS, HQX_3E_B, HQX_3E_B},
+	/* 0x0E */ {HQX_IF_D_B, HQX_E, HQX_E_3B}, {HQX_IF_D_B, HQX_E, HQX_E_3B},
+	/* 0x10 */ {HQX_ALWAYS, HQX_3E_B, HQX_3E_B}, {HQX_ALWAYS, HQX_3E_B, HQX_3E_B},
+	/* 0x12 */ {HQX_IF_B_F, HQX_E, HQX_7E_B}, {HQX_IF_B_F, HQX_E, HQX_E_3B},
+	/* 0x14 */ {HQX_ALWAYS, HQX_3E_B, HQX_3E_B}, {HQX_ALWAYS, HQX_3E_B, HQX_3E_B},
+	/* 0x16 */ {HQX_IF_B_F, HQX_E, HQX_7E_B}, {HQX_IF_B_F, HQX_E, HQX_E_3B},
+	/* 0x18 */ {HQX_ALWAYS, HQX_3E_B, HQX_3E_B}, {HQX_ALWAYS, HQX_3E_B, HQX_3E_B},
+	/* 0x1A */ {HQX_ALWAYS, HQX_E, HQX_E}, {HQX_IF_D_B, HQX_E, HQX_7E_B},
+	/* 0x1C */ {HQX_ALWAYS, HQX_3E_B, HQX_3E_B}, {HQX_ALWAYS, HQX_3E_B, HQX_3E_B},
+	/* 0x1E */ {HQX_IF_B_F, HQX_E, HQX_7E_B}, {HQX_ALWAYS, HQX_E, HQX_E},
+	/* 0x20 */ {HQX_ALWAYS, HQX_3E_B, HQX_3E_B}, {HQX_ALWAYS, HQX_3E_B, HQX_3E_B},
+	/* 0x22 */ {HQX_ALWAYS, HQX_E, HQX_E}, {HQX_ALWAYS, HQX_E, HQX_E},
+	/* 0x24 */ {HQX_ALWAYS, HQX_3E_B, HQX_3E_B}, {HQX_ALWAYS, HQX_3E_B, HQX_3E_B},
+	/* 0x26 */ {HQX_ALWAYS, HQX_E, HQX_E}, {HQX_ALWAYS, HQX_E, HQX_E},
+	/* 0x28 */ {HQX_ALWAYS, HQX_3E_B, HQX_3E_B}, {HQX_ALWAYS, HQX_3E_B, HQX_3E_B},
+	/* 0x2A */ {HQX_IF_D_B, HQX_E, HQX_3E_B}, {HQX_IF_D_B, HQX_E, HQX_3E_B},
+	/* 0x2C */ {HQX_ALWAYS, HQX_3E_B, HQX_3E_B}, {HQX_ALWAYS, HQX_3E_B, HQX_3E_B},
+	/* 0x2E */ {HQX_ALWAYS, HQX_E, HQX_E}, {HQX_ALWAYS, HQX_E, HQX_E},
+	/* 0x30 */ {HQX_ALWAYS, HQX_3E_B, HQX_3E_B}, {HQX_ALWAYS, HQX_3E_B, HQX_3E_B},
+	/* 0x32 */ {HQX_IF_B_F, HQX_E, HQX_7E_B}, {HQX_IF_B_F, HQX_E, HQX_E_3B},
+	/* 0x34 */ {HQX_ALWAYS, HQX_3E_B, HQX_3E_B}, {HQX_ALWAYS, HQX_3E_B, HQX_3E_B},
+	/* 0x36 */ {HQX_IF_B_F, HQX_E, HQX_7E_B}, {HQX_IF_B_F, HQX_E, HQX_E_3B},
+	/* 0x38 */ {HQX_ALWAYS, HQX_3E_B, HQX_3E_B}, {HQX_ALWAYS, HQX_3E_B, HQX_3E_B},
+	/* 0x3A */ {HQX_ALWAYS, HQX_E, HQX_E}, {HQX_IF_D_B, HQX_E, HQX_7E_B},
+	/* 0x3C */ {HQX_ALWAYS, HQX_3E_B, HQX_3E_B}, {HQX_ALWAYS, HQX_3E_B, HQX_3E_B},
+	/* 0x3E */ {HQX_IF_B_F, HQX_E, HQX_7E_B}, {HQX_ALWAYS, HQX_E, HQX_E},
+	/* 0x40 */ {HQX_ALWAYS, HQX_3E_B, HQX_3E_B}, {HQX_ALWAYS, HQX_3E_B, HQX_3E_B},
+	/* 0x42 */ {HQX_ALWAYS, HQX_E, HQX_E}, {HQX_ALWAYS, HQX_E, HQX_E},
+	/* 0x44 */ {HQX_ALWAYS, HQX_3E_B, HQX_3E_B}, {HQX_ALWAYS, HQX_3E_B, HQX_3E_B},
+	/* 0x46 */ {HQX_ALWAYS, HQX_E, HQX_E}, {HQX_ALWAYS, HQX_E, HQX_E},
+	/* 0x48 */ {HQX_ALWAYS, HQX_3E_B, HQX_3E_B}, {HQX_ALWAYS, HQX_3E_B, HQX_3E_B},
+	/* 0x4A */ {HQX_IF_D_B, HQX_E, HQX_7E_B}, {HQX_IF_D_B, HQX_E, HQX_7E_B},
+	/* 0x4C */ {HQX_ALWAYS, HQX_3E_B, HQX_3E_B}, {HQX_ALWAYS, HQX_3E_B, HQX_3E_B},
+	/* 0x4E */ {HQX_ALWAYS, HQX_E, HQX_E}, {HQX_IF_D_B, HQX_E, HQX_7E_B},
+	/* 0x50 */ {HQX_ALWAYS, HQX_3E_B, HQX_3E_B}, {HQX_ALWAYS, HQX_3E_B, HQX_3E_B},
+	/* 0x52 */ {HQX_IF_B_F, HQX_E, HQX_7E_B}, {HQX_ALWAYS, HQX_E, HQX_E},
+	/* 0x54 */ {HQX_ALWAYS, HQX_3E_B, HQX_3E_B}, {HQX_ALWAYS, HQX_3E_B, HQX_3E_B},
+	/* 0x56 */ {HQX_IF_B_F, HQX_E, HQX_7E_B}, {HQX_IF_B_F, HQX_E, HQX_7E_B},
+	/* 0x58 */ {HQX_ALWAYS, HQX_3E_B, HQX_3E_B}, {HQX_ALWAYS, HQX_3E_B, HQX_3E_B},
+	/* 0x5A */ {HQX_ALWAYS, HQX_E, HQX_E}, {HQX_IF_D_B, HQX_E, HQX_7E_B},
+	/* 0x5C */ {HQX_ALWAYS, HQX_3E_B, HQX_3E_B}, {HQX_ALWAYS, HQX_3E_B, HQX_3E_B},
+	/* 0x5E */ {HQX_IF_B_F, HQX_E, HQX_7E_B}, {HQX_ALWAYS, HQX_E, HQX_E},
+	/* 0x60 */ {HQX_ALWAYS, HQX_3E_B, HQX_3E_B}, {HQX_ALWAYS, HQX_3E_B, HQX_3E_B},
+	/* 0x62 */ {HQX_ALWAYS, HQX_E, HQX_E}, {HQX_ALWAYS, HQX_E, HQX_E},
+	/* 0x64 */ {HQX_ALWAYS, HQX_3E_B, HQX_3E_B}, {HQX_ALWAYS, HQX_3E_B, HQX_3E_B},
+	/* 0x66 */ {HQX_ALWAYS, HQX_E, HQX_E}, {HQX_ALWAYS, HQX_E, HQX_E},
+	/* 0x68 */ {HQX_ALWAYS, HQX_3E_B, HQX_3E_B}, {HQX_ALWAYS, HQX_3E_B, HQX_3E_B},
+	/* 0x6A */ {HQX_ALWAYS, HQX_E, HQX_E}, {HQX_IF_D_B, HQX_E, HQX_7E_B},
+	/* 0x6C */ {HQX_ALWAYS, HQX_3E_B, HQX_3E_B}, {HQX_ALWAYS, HQX_3E_B, HQX_3E_B},
+	/* 0x6E */ {HQX_ALWAYS, HQX_E, HQX_E}, {HQX_ALWAYS, HQX_E, HQX_E},
+	/* 0x70 */ {HQX_ALWAYS, HQX_3E_B, HQX_3E_B}, {HQX_ALWAYS, HQX_3E_B, HQX_3E_B},
+	/* 0x72 */ {HQX_ALWAYS, HQX_E, HQX_E}, {HQX_ALWAYS, HQX_E, HQX_E},
+	/* 0x74 */ {HQX_ALWAYS, HQX_3E_B, HQX_3E_B}, {HQX_ALWAYS, HQX_3E_B, HQX_3E_B},
+	/* 0x76 */ {HQX_IF_B_F, HQX_E, HQX_7E_B}, {HQX_IF_B_F, HQX_E, HQX_E_3B},
+	/* 0x78 */ {HQX_ALWAYS, HQX_3E_B, HQX_3E_B}, {HQX_ALWAYS, HQX_3E_B, HQX_3E_B},
+	/* 0x7A */ {HQX_ALWAYS, HQX_E, HQX_E}, {HQX_IF_D_B, HQX_E, HQX_7E_B},
+	/* 0x7C */ {HQX_ALWAYS, HQX_3E_B, HQX_3E_B}, {HQX_ALWAYS, HQX_3E_B, HQX_3E_B},
+	/* 0x7E */ {HQX_IF_B_F, HQX_E, HQX_7E_B}, {HQX_ALWAYS, HQX_E, HQX_E},
+	/* 0x80 */ {HQX_ALWAYS, HQX_3E_B, HQX_3E_B}, {HQX_ALWAYS, HQX_3E_B, HQX_3E_B},
+	/* 0x82 */ {HQX_ALWAYS, HQX_E, HQX_E}, {HQX_ALWAYS, HQX_E, HQX_E},
+	/* 0x84 */ {HQX_ALWAYS, HQX_3E_B, HQX_3E_B}, {HQX_ALWAYS, HQX_3E_B, HQX_3E_B},
+	/* 0x86 */ {HQX_ALWAYS, HQX_E, HQX_E}, {HQX_ALWAYS, HQX_E, HQX_E},
+	/* 0x88 */ {HQX_ALWAYS, HQX_3E_B, HQX_3E_B}, {HQX_ALWAYS, HQX_3E_B, HQX_3E_B},
+	/* 0x8A */ {HQX_IF_D_B, HQX_E, HQX_7E_B}, {HQX_IF_D_B, HQX_E, HQX_7E_B},
+	/* 0x8C */ {HQX_ALWAYS, HQX_3E_B, HQX_3E_B}, {HQX_ALWAYS, HQX_3E_B, HQX_3E_B},
+	/* 0x8E */ {HQX_IF_D_B, HQX_E, HQX_E_3B}, {HQX_IF_D_B, HQX_E, HQX_E_3B},
+	/* 0x90 */ {HQX_ALWAYS, HQX_3E_B, HQX_3E_B}, {HQX_ALWAYS, HQX_3E_B, HQX_3E_B},
+	/* 0x92 */ {HQX_IF_B_F, HQX_E, HQX_3E_B}, {HQX_ALWAYS, HQX_E, HQX_E},
+	/* 0x94 */ {HQX_ALWAYS, HQX_3E_B, HQX_3E_B}, {HQX_ALWAYS, HQX_3E_B, HQX_3E_B},
+	/* 0x96 */ {HQX_IF_B_F, HQX_E, HQX_3E_B}, {HQX_ALWAYS, HQX_E, HQX_E},
+	/* 0x98 */ {HQX_ALWAYS, HQX_3E_B, HQX_3E_B}, {HQX_ALWAYS, HQX_3E_B, HQX_3E_B},
+	/* 0x9A */ {HQX_ALWAYS, HQX_E, HQX_E}, {HQX_IF_D_B, HQX_E, HQX_7E_B},
+	/* 0x9C */ {HQX_ALWAYS, HQX_3E_B, HQX_3E_B}, {HQX_ALWAYS, HQX_3E_B, HQX_3E_B},
+	/* 0x9E */ {HQX_IF_B_F, HQX_E, HQX_7E_B}, {HQX_ALWAYS, HQX_E, HQX_E},
+	/* 0xA0 */ {HQX_ALWAYS, HQX_3E_B, HQX_3E_B}, {HQX_ALWAYS, HQX_3E_B, HQX_3E_B},
+	/* 0xA2 */ {HQX_ALWAYS, HQX_E, HQX_E}, {HQX_ALWAYS, HQX_E, HQX_E},
+	/* 0xA4 */ {HQX_ALWAYS, HQX_3E_B, HQX_3E_B}, {HQX_ALWAYS, HQX_3E_B, HQX_3E_B},
+	/* 0xA6 */ {HQX_ALWAYS, HQX_E, HQX_E}, {HQX_ALWAYS, HQX_E, HQX_E},
+	/* 0xA8 */ {HQX_ALWAYS, HQX_3E_B, HQX_3E_B}, {HQX_ALWAYS, HQX_3E_B, HQX_3E_B},
+	/* 0xAA */ {HQX_IF_D_B, HQX_E, HQX_3E_B}, {HQX_IF_D_B, HQX_E, HQX_3E_B},
+	/* 0xAC */ {HQX_ALWAYS, HQX_3E_B, HQX_3E_B}, {HQX_ALWAYS, HQX_3E_B, HQX_3E_B},
+	/* 0xAE */ {HQX_ALWAYS, HQX_E, HQX_E}, {HQX_ALWAYS, HQX_E, HQX_E},
+	/* 0xB0 */ {HQX_ALWAYS, HQX_3E_B, HQX_3E_B}, {HQX_ALWAYS, HQX_3E_B, HQX_3E_B},
+	/* 0xB2 */ {HQX_IF_B_F, HQX_E, HQX_3E_B}, {HQX_ALWAYS, HQX_E, HQX_E},
+	/* 0xB4 */ {HQX_ALWAYS, HQX_3E_B, HQX_3E_B}, {HQX_ALWAYS, HQX_3E_B, HQX_3E_B},
+	/* 0xB6 */ {HQX_IF_B_F, HQX_E, HQX_3E_B}, {HQX_ALWAYS, HQX_E, HQX_E},
+	/* 0xB8 */ {HQX_ALWAYS, HQX_3E_B, HQX_3E_B}, {HQX_ALWAYS, HQX_3E_B, HQX_3E_B},
+	/* 0xBA */ {HQX_ALWAYS, HQX_E, HQX_E}, {HQX_IF_D_B, HQX_E, HQX_3E_B},
+	/* 0xBC */ {HQX_ALWAYS, HQX_3E_B, HQX_3E_B}, {HQX_ALWAYS, HQX_3E_B, HQX_3E_B},
+	/* 0xBE */ {HQX_IF_B_F, HQX_E, HQX_3E_B}, {HQX_ALWAYS, HQX_E, HQX_E},
+	/* 0xC0 */ {HQX_ALWAYS, HQX_3E_B, HQX_3E_B}, {HQX_ALWAYS, HQX_3E_B, HQX_3E_B},
+	/* 0xC2 */ {HQX_ALWAYS, HQX_E, HQX_E}, {HQX_ALWAYS, HQX_E, HQX_E},
+	/* 0xC4 */ {HQX_ALWAYS, HQX_3E_B, HQX_3E_B}, {HQX_ALWAYS, HQX_3E_B, HQX_3E_B},
+	/* 0xC6 */ {HQX_ALWAYS, HQX_E, HQX_E}, {HQX_ALWAYS, HQX_E, HQX_E},
+	/* 0xC8 */ {HQX_ALWAYS, HQX_3E_B, HQX_3E_B}, {HQX_ALWAYS, HQX_3E_B, HQX_3E_B},
+	/* 0xCA */ {HQX_ALWAYS, HQX_E, HQX_E}, {HQX_IF_D_B, HQX_E, HQX_7E_B},
+	/* 0xCC */ {HQX_ALWAYS, HQX_3E_B, HQX_3E_B}, {HQX_ALWAYS, HQX_3E_B, HQX_3E_B},
+	/* 0xCE */ {HQX_ALWAYS, HQX_E, HQX_E}, {HQX_IF_D_B, HQX_E, HQX_E_3B},
+	/* 0xD0 */ {HQX_ALWAYS, HQX_3E_B, HQX_3E_B}, {HQX_ALWAYS, HQX_3E_B, HQX_3E_B},
+	/* 0xD2 */ {HQX_ALWAYS, HQX_E, HQX_E}, {HQX_ALWAYS, HQX_E, HQX_E},
+	/* 0xD4 */ {HQX_ALWAYS, HQX_3E_B, HQX_3E_B}, {HQX_ALWAYS, HQX_3E_B, HQX_3E_B},
+	/* 0xD6 */ {HQX_IF_B_F, HQX_E, HQX_7E_B}, {HQX_ALWAYS, HQX_E, HQX_E},
+	/* 0xD8 */ {HQX_ALWAYS, HQX_3E_B, HQX_3E_B}, {HQX_ALWAYS, HQX_3E_B, HQX_3E_B},
+	/* 0xDA */ {HQX_ALWAYS, HQX_E, HQX_E}, {HQX_IF_D_B, HQX_E, HQX_7E_B},
+	/* 0xDC */ {HQX_ALWAYS, HQX_3E_B, HQX_3E_B}, {HQX_ALWAYS, HQX_3E_B, HQX_3E_B},
+	/* 0xDE */ {HQX_IF_B_F, HQX_E, HQX_7E_B}, {HQX_ALWAYS, HQX_E, HQX_E},
+	/* 0xE0 */ {HQX_ALWAYS, HQX_3E_B, HQX_3E_B}, {HQX_ALWAYS, HQX_3E_B, HQX_3E_B},
+	/* 0xE2 */ {HQX_ALWAYS, HQX_E, HQX_E}, {HQX_ALWAYS, HQX_E, HQX_E},
+	/* 0xE4 */ {HQX_ALWAYS, HQX_3E_B, HQX_3E_B}, {HQX_ALWAYS, HQX_3E_B, HQX_3E_B},
+	/* 0xE6 */ {HQX_ALWAYS, HQX_E, HQX_E}, {HQX_ALWAYS, HQX_E, HQX_E},
+	/* 0xE8 */ {HQX_ALWAYS, HQX_3E_B, HQX_3E_B}, {HQX_ALWAYS, HQX_3E_B, HQX_3E_B},
+	/* 0xEA */ {HQX_ALWAYS, HQX_E, HQX_E}, {HQX_IF_D_B, HQX_E, HQX_7E_B},
+	/* 0xEC */ {HQX_ALWAYS, HQX_3E_B, HQX_3E_B}, {HQX_ALWAYS, HQX_3E_B, HQX_3E_B},
+	/* 0xEE */ {HQX_ALWAYS, HQX_E, HQX_E}, {HQX_ALWAYS, HQX_E, HQX_E},
+	/* 0xF0 */ {HQX_ALWAYS, HQX_3E_B, HQX_3E_B}, {HQX_ALWAYS, HQX_3E_B, HQX_3E_B},
+	/* 0xF2 */ {HQX_ALWAYS, HQX_E, HQX_E}, {HQX_ALWAYS, HQX_E, HQX_E},
+	/* 0xF4 */ {HQX_ALWAYS, HQX_3E_B, HQX_3E_B}, {HQX_ALWAYS, HQX_3E_B, HQX_3E_B},
+	/* 0xF6 */ {HQX_IF_B_F, HQX_E, HQX_7E_B}, {HQX_ALWAYS, HQX_E, HQX_E},
+	/* 0xF8 */ {HQX_ALWAYS, HQX_3E_B, HQX_3E_B}, {HQX_ALWAYS, HQX_3E_B, HQX_3E_B},
+	/* 0xFA */ {HQX_ALWAYS, HQX_3E_B, HQX_3E_B}, {HQX_IF_D_B, HQX_E, HQX_7E_B},
+	/* 0xFC */ {HQX_ALWAYS, HQX_3E_B, HQX_3E_B}, {HQX_ALWAYS, HQX_3E_B, HQX_3E_B},
+	/* 0xFE */ {HQX_IF_B_F, HQX_E, HQX_7E_B}, {HQX_ALWAYS, HQX_E, HQX_E},
+}
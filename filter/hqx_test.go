@@ -0,0 +1,149 @@
+package filter
+
+import (
+	"image"
+	"testing"
+)
+
+// dotImage is a 3x3 black image with a white pixel in the middle.
+func dotImage() *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, 3, 3))
+	for y := 0; y < 3; y++ {
+		for x := 0; x < 3; x++ {
+			setPixel(img, x, y, 0x000000FF)
+		}
+	}
+	setPixel(img, 1, 1, 0xFFFFFFFF)
+	return img
+}
+
+func pixelAt(img *image.RGBA, x int, y int) uint32 {
+	return unpack(img).at(x, y)
+}
+
+func TestHqxFlat(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 4, 3))
+	for y := 0; y < 3; y++ {
+		for x := 0; x < 4; x++ {
+			setPixel(img, x, y, 0x336699FF)
+		}
+	}
+
+	for _, scale := range []int{2, 3} {
+		out := Hqx{scale}.Apply(img)
+		if size := out.Bounds().Size(); size != image.Pt(4*scale, 3*scale) {
+			t.Fatalf("hq%dx output is %v, want %dx%d", scale, size, 4*scale, 3*scale)
+		}
+
+		for y := 0; y < 3*scale; y++ {
+			for x := 0; x < 4*scale; x++ {
+				if got := pixelAt(out, x, y); got != 0x336699FF {
+					t.Errorf("hq%dx pixel (%d, %d) = %08X, want 336699FF", scale, x, y, got)
+				}
+			}
+		}
+	}
+}
+
+func TestHqxDot(t *testing.T) {
+	tests := []struct {
+		scale int
+		x, y  int
+		want  uint32
+	}{
+		// hq2x case 255 with no diagonal edges blends 14:1:1 in each corner
+		{2, 2, 2, 0xDFDFDFFF},
+		{2, 3, 2, 0xDFDFDFFF},
+		{2, 2, 3, 0xDFDFDFFF},
+		{2, 3, 3, 0xDFDFDFFF},
+		{2, 1, 1, 0x000000FF},
+		// hq3x keeps the edges and blends the corners 2:1:1
+		{3, 3, 3, 0x808080FF},
+		{3, 5, 5, 0x808080FF},
+		{3, 4, 3, 0xFFFFFFFF},
+		{3, 3, 4, 0xFFFFFFFF},
+		{3, 4, 4, 0xFFFFFFFF},
+		{3, 2, 2, 0x000000FF},
+	}
+
+	img := dotImage()
+	for _, test := range tests {
+		out := Hqx{test.scale}.Apply(img)
+		if got := pixelAt(out, test.x, test.y); got != test.want {
+			t.Errorf("hq%dx pixel (%d, %d) = %08X, want %08X", test.scale, test.x, test.y, got, test.want)
+		}
+	}
+}
+
+func TestHqxDiagonal(t *testing.T) {
+	// A white line from the top left to the bottom right: the middle pixel
+	// keeps its corners on the line and blends the other two with the black
+	img := image.NewRGBA(image.Rect(0, 0, 3, 3))
+	for y := 0; y < 3; y++ {
+		for x := 0; x < 3; x++ {
+			pixel := uint32(0x000000FF)
+			if x == y {
+				pixel = 0xFFFFFFFF
+			}
+			setPixel(img, x, y, pixel)
+		}
+	}
+
+	out := Hqx{2}.Apply(img)
+	for _, test := range []struct {
+		x, y int
+		want uint32
+	}{
+		{2, 2, 0xFFFFFFFF},
+		{3, 3, 0xFFFFFFFF},
+		{3, 2, 0x808080FF}, // Case 126: Interp2(w5, w2, w6), as w2 and w6 are alike
+		{2, 3, 0x808080FF},
+	} {
+		if got := pixelAt(out, test.x, test.y); got != test.want {
+			t.Errorf("hq2x pixel (%d, %d) = %08X, want %08X", test.x, test.y, got, test.want)
+		}
+	}
+}
+
+// mirrorRule reflects a top left corner rule across the diagonal through
+// that corner, which swaps b with d and f with h.
+func mirrorRule(rule hqxRule) hqxRule {
+	tests := map[hqxTest]hqxTest{HQX_IF_B_F: HQX_IF_D_H, HQX_IF_D_H: HQX_IF_B_F}
+	blends := map[hqxBlend]hqxBlend{
+		HQX_3E_B: HQX_3E_D, HQX_3E_D: HQX_3E_B,
+		HQX_2E_A_B: HQX_2E_A_D, HQX_2E_A_D: HQX_2E_A_B,
+		HQX_5E_2B_D: HQX_5E_2D_B, HQX_5E_2D_B: HQX_5E_2B_D,
+	}
+
+	mirror := rule
+	if test, ok := tests[rule.test]; ok {
+		mirror.test = test
+	}
+	if blend, ok := blends[rule.ifDiffer]; ok {
+		mirror.ifDiffer = blend
+	}
+	if blend, ok := blends[rule.ifSame]; ok {
+		mirror.ifSame = blend
+	}
+	return mirror
+}
+
+func TestHqxCornersSymmetric(t *testing.T) {
+	// Bits of w1 to w9, less w5, after swapping w2/w4, w3/w7 and w6/w8
+	mirrorBits := [8]int{0, 3, 5, 1, 6, 2, 4, 7}
+
+	for name, table := range map[string]*[256]hqxRule{"hq2x": &hq2xCorners, "hq3x": &hq3xCorners} {
+		for pattern, rule := range table {
+			var mirrored int
+			for bit, to := range mirrorBits {
+				if pattern&(1<<uint(bit)) != 0 {
+					mirrored |= 1 << uint(to)
+				}
+			}
+
+			if got, want := table[mirrored], mirrorRule(rule); got != want {
+				t.Errorf("%s rule for %02X = %v, want %v mirroring %02X", name, mirrored, got, want, pattern)
+			}
+		}
+	}
+}
@@ -0,0 +1,269 @@
+package filter
+
+import (
+	"fmt"
+	"github.com/tjarjoura/nes-emulator/ppu"
+	"image"
+	"math"
+	"strconv"
+	"strings"
+)
+
+const (
+	NTSC_WIDTH         int     = 602
+	SAMPLES_PER_PIXEL  int     = 8
+	SAMPLES_PER_CYCLE  int     = 12 // One cycle of the colour subcarrier
+	SCANLINE_PHASE     int     = 4  // 341 dots of 8 samples, modulo 12
+	FRAME_PHASE        int     = 4  // Frames alternate between two phases
+	EMPHASIS_ATTENUATE float64 = 0.746
+)
+
+// Composite signal voltages for each luma level, for the low part of the
+// wave and then the high part. Emphasis is applied on top of these by
+// scaling with EMPHASIS_ATTENUATE.
+var signalLevels = [2][4]float64{
+	{0.228, 0.312, 0.552, 0.880},
+	{0.616, 0.840, 1.100, 1.100},
+}
+
+const (
+	SIGNAL_BLACK float64 = 0.312
+	SIGNAL_WHITE float64 = 1.100
+)
+
+type NtscSettings struct {
+	Sharpness   float64 // -1 blurs, 1 sharpens the luma
+	Saturation  float64 // -1 is greyscale, 1 doubles the saturation
+	Hue         float64 // In degrees
+	Artifacts   float64 // 0 filters the subcarrier out of the luma, 1 keeps it
+	Bleed       float64 // 0 keeps the chroma sharp, 1 smears it over 3 cycles
+	MergeFields bool    // Average both dot crawl phases to hide the crawl
+}
+
+var DEFAULT_NTSC_SETTINGS = NtscSettings{Artifacts: 0.5, Bleed: 0.3}
+
+// ParseNtscSettings reads settings written as a comma separated list such as
+// "sharpness=0.5,hue=-10,merge", starting from DEFAULT_NTSC_SETTINGS. The
+// word "default" on its own leaves them unchanged.
+func ParseNtscSettings(spec string) (NtscSettings, error) {
+	settings := DEFAULT_NTSC_SETTINGS
+	if spec == "" || spec == "default" {
+		return settings, nil
+	}
+
+	fields := map[string]*float64{
+		"sharpness":  &settings.Sharpness,
+		"saturation": &settings.Saturation,
+		"hue":        &settings.Hue,
+		"artifacts":  &settings.Artifacts,
+		"bleed":      &settings.Bleed,
+	}
+
+	for _, setting := range strings.Split(spec, ",") {
+		name, value, hasValue := strings.Cut(strings.TrimSpace(setting), "=")
+		if name == "merge" && !hasValue {
+			settings.MergeFields = true
+			continue
+		}
+
+		field, ok := fields[name]
+		if !ok || !hasValue {
+			return settings, fmt.Errorf("Unknown NTSC setting %q", setting)
+		}
+
+		number, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return settings, fmt.Errorf("Bad value for NTSC setting %s: %q", name, value)
+		}
+		*field = number
+	}
+
+	return settings, nil
+}
+
+// NtscFilter encodes PPU output as the composite signal the NES generates,
+// then decodes it as a TV would. Colour is carried at a frequency close to
+// the pixel rate, so sharp luma edges turn into artifact colours and the
+// pattern crawls from frame to frame.
+type NtscFilter struct {
+	settings NtscSettings
+	width    int
+	cos, sin [SAMPLES_PER_CYCLE]float64
+	signal   []float64
+}
+
+func NewNtscFilter(settings NtscSettings) *NtscFilter {
+	filter := &NtscFilter{
+		settings: settings,
+		width:    NTSC_WIDTH,
+		signal:   make([]float64, ppu.SCREEN_WIDTH*SAMPLES_PER_PIXEL),
+	}
+
+	hue := (settings.Hue + NTSC_HUE_OFFSET) * math.Pi / 180
+	for phase := range filter.cos {
+		angle := math.Pi*float64(phase)/6 + hue
+		filter.cos[phase] = math.Cos(angle)
+		filter.sin[phase] = math.Sin(angle)
+	}
+
+	return filter
+}
+
+// Rotates the decoded hue so that the reference colours come out right
+const NTSC_HUE_OFFSET float64 = 123
+
+func inColorPhase(color int, phase int) bool {
+	return (color+phase)%SAMPLES_PER_CYCLE < 6
+}
+
+// signalSample returns the composite voltage of a pixel at a subcarrier
+// phase. Colours 0-12 are square waves between two levels, with hue given by
+// the wave's phase; 0 and 13-15 are flat.
+func signalSample(pixel uint16, phase int) float64 {
+	color := int(pixel & 0x0F)
+	level := int(pixel>>4) & 0x03
+	emphasis := int(pixel >> 6)
+
+	if color > 13 {
+		level = 1
+	}
+
+	low, high := signalLevels[0][level], signalLevels[1][level]
+	if color == 0 {
+		low = high
+	}
+	if color > 12 {
+		high = low
+	}
+
+	signal := low
+	if inColorPhase(color, phase) {
+		signal = high
+	}
+
+	// Each emphasis bit attenuates the signal for a third of the cycle
+	if (emphasis&0x01 > 0 && inColorPhase(0, phase)) ||
+		(emphasis&0x02 > 0 && inColorPhase(4, phase)) ||
+		(emphasis&0x04 > 0 && inColorPhase(8, phase)) {
+		signal *= EMPHASIS_ATTENUATE
+	}
+
+	return signal
+}
+
+func clamp(value float64) uint8 {
+	if value <= 0 {
+		return 0
+	}
+	if value >= 1 {
+		return 0xFF
+	}
+	return uint8(value*255 + 0.5)
+}
+
+// gammaCorrect converts the decoded signal from the NES's roughly 1.8 gamma
+// to that of a modern display.
+func gammaCorrect(value float64) float64 {
+	if value <= 0 {
+		return 0
+	}
+	return math.Pow(value, 2.2/1.8)
+}
+
+// average averages the weighted samples centred on position over a window of the given
+// size, clipped at the ends of the line.
+func (filter *NtscFilter) average(center int, size int, weight func(sample float64, position int) float64) float64 {
+	var sum float64
+	var count int
+
+	for position := center - size/2; position < center-size/2+size; position++ {
+		if position < 0 || position >= len(filter.signal) {
+			continue
+		}
+		sum += weight(filter.signal[position], position)
+		count++
+	}
+
+	if count == 0 {
+		return 0
+	}
+	return sum / float64(count)
+}
+
+func (filter *NtscFilter) decodeLine(img *image.RGBA, row int, phase int, blend bool) {
+	settings := filter.settings
+	lumaSharp := SAMPLES_PER_CYCLE / 3
+	chromaWindow := SAMPLES_PER_CYCLE + int(settings.Bleed*float64(2*SAMPLES_PER_CYCLE)+0.5)
+	saturation := 2 * (1 + settings.Saturation)
+
+	identity := func(sample float64, position int) float64 { return sample }
+
+	for x := 0; x < filter.width; x++ {
+		center := (2*x + 1) * len(filter.signal) / (2 * filter.width)
+
+		// A full cycle removes the subcarrier from the luma; a shorter window
+		// lets some through as artifacts, and sharpness trades the two off
+		// against each other
+		y := filter.average(center, SAMPLES_PER_CYCLE, identity)
+		narrow := filter.average(center, lumaSharp, identity)
+		y += (narrow - y) * settings.Artifacts
+		y += (narrow - y) * settings.Sharpness * 0.5
+		y = (y - SIGNAL_BLACK) / (SIGNAL_WHITE - SIGNAL_BLACK)
+
+		// Windows that aren't whole cycles would let the average level leak
+		// into the chroma, so take it out first
+		mean := filter.average(center, chromaWindow, identity)
+		i := filter.average(center, chromaWindow, func(sample float64, position int) float64 {
+			return (sample - mean) * filter.cos[(phase+position)%SAMPLES_PER_CYCLE]
+		})
+		q := filter.average(center, chromaWindow, func(sample float64, position int) float64 {
+			return (sample - mean) * filter.sin[(phase+position)%SAMPLES_PER_CYCLE]
+		})
+		i *= saturation / (SIGNAL_WHITE - SIGNAL_BLACK)
+		q *= saturation / (SIGNAL_WHITE - SIGNAL_BLACK)
+
+		r := clamp(gammaCorrect(y + 0.946882*i + 0.623557*q))
+		g := clamp(gammaCorrect(y - 0.274788*i - 0.635691*q))
+		b := clamp(gammaCorrect(y - 1.108545*i + 1.709007*q))
+
+		offset := img.PixOffset(x, row)
+		if blend {
+			r = uint8((uint16(img.Pix[offset]) + uint16(r) + 1) / 2)
+			g = uint8((uint16(img.Pix[offset+1]) + uint16(g) + 1) / 2)
+			b = uint8((uint16(img.Pix[offset+2]) + uint16(b) + 1) / 2)
+		}
+		img.Pix[offset], img.Pix[offset+1], img.Pix[offset+2], img.Pix[offset+3] = r, g, b, 0xFF
+	}
+}
+
+func (filter *NtscFilter) renderField(img *image.RGBA, frame []uint16, framePhase int, blend bool) {
+	for row := 0; row < len(frame)/ppu.SCREEN_WIDTH; row++ {
+		phase := (framePhase + row*SCANLINE_PHASE) % SAMPLES_PER_CYCLE
+		line := frame[row*ppu.SCREEN_WIDTH : (row+1)*ppu.SCREEN_WIDTH]
+
+		for x, pixel := range line {
+			for sample := 0; sample < SAMPLES_PER_PIXEL; sample++ {
+				position := x*SAMPLES_PER_PIXEL + sample
+				filter.signal[position] = signalSample(pixel, (phase+position)%SAMPLES_PER_CYCLE)
+			}
+		}
+
+		filter.decodeLine(img, row, phase, blend)
+	}
+}
+
+// Apply filters a frame of PPU output into an image NTSC_WIDTH pixels wide.
+// frameNumber selects which of the two alternating phases the frame was
+// generated in, which is what makes the artifacts crawl.
+func (filter *NtscFilter) Apply(frame []uint16, frameNumber uint64) *image.RGBA {
+	height := len(frame) / ppu.SCREEN_WIDTH
+	img := image.NewRGBA(image.Rect(0, 0, filter.width, height))
+	framePhase := int(frameNumber%2) * FRAME_PHASE
+
+	filter.renderField(img, frame, framePhase, false)
+	if filter.settings.MergeFields {
+		filter.renderField(img, frame, (framePhase+FRAME_PHASE)%SAMPLES_PER_CYCLE, true)
+	}
+
+	return img
+}
@@ -0,0 +1,68 @@
+package filter
+
+import "image"
+
+// pixels is an image unpacked into one 0xRRGGBBAA word per pixel, which
+// makes the neighbour comparisons of the pixel art scalers cheap.
+type pixels struct {
+	width, height int
+	data          []uint32
+}
+
+func unpack(img *image.RGBA) *pixels {
+	bounds := img.Bounds()
+	p := &pixels{bounds.Dx(), bounds.Dy(), make([]uint32, bounds.Dx()*bounds.Dy())}
+
+	for y := 0; y < p.height; y++ {
+		for x := 0; x < p.width; x++ {
+			offset := img.PixOffset(bounds.Min.X+x, bounds.Min.Y+y)
+			rgba := img.Pix[offset : offset+4]
+			p.data[y*p.width+x] = uint32(rgba[0])<<24 | uint32(rgba[1])<<16 | uint32(rgba[2])<<8 | uint32(rgba[3])
+		}
+	}
+
+	return p
+}
+
+// at returns the pixel at (x, y), repeating the edges outside the image.
+func (p *pixels) at(x int, y int) uint32 {
+	if x < 0 {
+		x = 0
+	} else if x >= p.width {
+		x = p.width - 1
+	}
+	if y < 0 {
+		y = 0
+	} else if y >= p.height {
+		y = p.height - 1
+	}
+
+	return p.data[y*p.width+x]
+}
+
+func setPixel(img *image.RGBA, x int, y int, pixel uint32) {
+	offset := img.PixOffset(x, y)
+	img.Pix[offset] = uint8(pixel >> 24)
+	img.Pix[offset+1] = uint8(pixel >> 16)
+	img.Pix[offset+2] = uint8(pixel >> 8)
+	img.Pix[offset+3] = uint8(pixel)
+}
+
+// mix blends pixels with integer weights.
+func mix(weights []int, colors []uint32) uint32 {
+	var total int
+	var channels [4]int
+
+	for i, weight := range weights {
+		total += weight
+		for channel := 0; channel < 4; channel++ {
+			channels[channel] += weight * int(colors[i]>>uint(24-8*channel)&0xFF)
+		}
+	}
+
+	var result uint32
+	for channel := 0; channel < 4; channel++ {
+		result |= uint32((channels[channel]+total/2)/total) << uint(24-8*channel)
+	}
+	return result
+}
@@ -0,0 +1,99 @@
+package filter
+
+import "image"
+
+// Scale2x is the EPX/AdvMAME2x scaler, which doubles an image and rounds off
+// diagonal edges without introducing new colours.
+type Scale2x struct{}
+
+func (Scale2x) Apply(src *image.RGBA) *image.RGBA {
+	p := unpack(src)
+	dst := image.NewRGBA(image.Rect(0, 0, p.width*2, p.height*2))
+
+	for y := 0; y < p.height; y++ {
+		for x := 0; x < p.width; x++ {
+			//   A
+			// C P B
+			//   D
+			a, b, c, d := p.at(x, y-1), p.at(x+1, y), p.at(x-1, y), p.at(x, y+1)
+			e := p.at(x, y)
+			e0, e1, e2, e3 := e, e, e, e
+
+			if a != d && c != b {
+				if c == a {
+					e0 = a
+				}
+				if a == b {
+					e1 = b
+				}
+				if d == c {
+					e2 = c
+				}
+				if b == d {
+					e3 = d
+				}
+			}
+
+			setPixel(dst, x*2, y*2, e0)
+			setPixel(dst, x*2+1, y*2, e1)
+			setPixel(dst, x*2, y*2+1, e2)
+			setPixel(dst, x*2+1, y*2+1, e3)
+		}
+	}
+
+	return dst
+}
+
+// Scale3x is the AdvMAME3x scaler, the three times version of Scale2x.
+type Scale3x struct{}
+
+func (Scale3x) Apply(src *image.RGBA) *image.RGBA {
+	p := unpack(src)
+	dst := image.NewRGBA(image.Rect(0, 0, p.width*3, p.height*3))
+
+	for y := 0; y < p.height; y++ {
+		for x := 0; x < p.width; x++ {
+			// A B C
+			// D E F
+			// G H I
+			a, b, c := p.at(x-1, y-1), p.at(x, y-1), p.at(x+1, y-1)
+			d, e, f := p.at(x-1, y), p.at(x, y), p.at(x+1, y)
+			g, h, i := p.at(x-1, y+1), p.at(x, y+1), p.at(x+1, y+1)
+
+			out := [9]uint32{e, e, e, e, e, e, e, e, e}
+
+			if b != h && d != f {
+				if d == b {
+					out[0] = d
+				}
+				if (d == b && e != c) || (b == f && e != a) {
+					out[1] = b
+				}
+				if b == f {
+					out[2] = f
+				}
+				if (d == b && e != g) || (d == h && e != a) {
+					out[3] = d
+				}
+				if (b == f && e != i) || (h == f && e != c) {
+					out[5] = f
+				}
+				if d == h {
+					out[6] = d
+				}
+				if (d == h && e != i) || (h == f && e != g) {
+					out[7] = h
+				}
+				if h == f {
+					out[8] = f
+				}
+			}
+
+			for subpixel, color := range out {
+				setPixel(dst, x*3+subpixel%3, y*3+subpixel/3, color)
+			}
+		}
+	}
+
+	return dst
+}
@@ -0,0 +1,78 @@
+package filter
+
+import "image"
+
+func distance(a uint32, b uint32) int {
+	y1, u1, v1 := yuv(a)
+	y2, u2, v2 := yuv(b)
+	return 48*abs(y1-y2) + 7*abs(u1-u2) + 6*abs(v1-v2)
+}
+
+// Xbr is Hyllian's xBR 2x scaler. For every corner of a pixel it weighs the
+// colour differences along the two diagonals of a 5x5 neighbourhood and
+// blends the corner towards the neighbour when an edge runs across it.
+type Xbr struct{}
+
+// xbrCorner works on the bottom right corner of e; the other corners are
+// handled by passing a rotated neighbourhood. n is a 5x5 neighbourhood
+// indexed as n[row][column] with e at n[2][2].
+func xbrCorner(n *[5][5]uint32) uint32 {
+	e, f, h, i := n[2][2], n[2][3], n[3][2], n[3][3]
+	c, g, d, b := n[1][3], n[3][1], n[2][1], n[1][2]
+	f4, h5, i4, i5 := n[2][4], n[4][2], n[3][4], n[4][3]
+
+	if e == f || e == h {
+		return e
+	}
+
+	across := distance(e, c) + distance(e, g) + distance(i, f4) + distance(i, h5) + 4*distance(h, f)
+	along := distance(h, d) + distance(h, i5) + distance(f, i4) + distance(f, b) + 4*distance(e, i)
+	if across >= along {
+		return e
+	}
+
+	neighbour := h
+	if distance(e, f) <= distance(e, h) {
+		neighbour = f
+	}
+	return mix([]int{1, 1}, []uint32{e, neighbour})
+}
+
+// rotate turns a neighbourhood a quarter turn clockwise.
+func rotate(n *[5][5]uint32) {
+	var rotated [5][5]uint32
+	for row := 0; row < 5; row++ {
+		for column := 0; column < 5; column++ {
+			rotated[column][4-row] = n[row][column]
+		}
+	}
+	*n = rotated
+}
+
+func (Xbr) Apply(src *image.RGBA) *image.RGBA {
+	p := unpack(src)
+	dst := image.NewRGBA(image.Rect(0, 0, p.width*2, p.height*2))
+
+	var n [5][5]uint32
+	for y := 0; y < p.height; y++ {
+		for x := 0; x < p.width; x++ {
+			for row := 0; row < 5; row++ {
+				for column := 0; column < 5; column++ {
+					n[row][column] = p.at(x+column-2, y+row-2)
+				}
+			}
+
+			// Bottom right, then each quarter turn brings the next corner
+			// clockwise into the bottom right position
+			setPixel(dst, x*2+1, y*2+1, xbrCorner(&n))
+			rotate(&n)
+			setPixel(dst, x*2+1, y*2, xbrCorner(&n))
+			rotate(&n)
+			setPixel(dst, x*2, y*2, xbrCorner(&n))
+			rotate(&n)
+			setPixel(dst, x*2, y*2+1, xbrCorner(&n))
+		}
+	}
+
+	return dst
+}
@@ -0,0 +1,271 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"flag"
+	"fmt"
+	"github.com/tjarjoura/nes-emulator/cartridge"
+	"github.com/tjarjoura/nes-emulator/console"
+	"github.com/tjarjoura/nes-emulator/debugview"
+	"github.com/tjarjoura/nes-emulator/filter"
+	"github.com/tjarjoura/nes-emulator/input"
+	"github.com/tjarjoura/nes-emulator/palette"
+	"github.com/tjarjoura/nes-emulator/record"
+	"image"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+func parseFrameList(list string) (map[uint64]bool, error) {
+	frames := make(map[uint64]bool)
+	if list == "" {
+		return frames, nil
+	}
+
+	for _, field := range strings.Split(list, ",") {
+		frame, err := strconv.ParseUint(strings.TrimSpace(field), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("bad frame number %q", field)
+		}
+		frames[frame] = true
+	}
+
+	return frames, nil
+}
+
+// writeViews saves the nametable, attribute and OAM debug views of the
+// current PPU state next to a screenshot.
+func writeViews(prefix string, console *console.Console, pal *palette.Palette) error {
+	views := map[string]image.Image{
+		"nametables": debugview.Nametables(console.Ppu, pal),
+		"attributes": debugview.AttributeMap(console.Ppu),
+		"oam":        debugview.SpritePreviews(console.Ppu, pal),
+	}
+
+	for name, img := range views {
+		err := writePng(prefix+"_"+name+".png", img)
+		if err != nil {
+			return err
+		}
+	}
+
+	jsonFile, err := os.Create(prefix + "_ppu.json")
+	if err != nil {
+		return err
+	}
+	defer jsonFile.Close()
+
+	return debugview.WriteJson(jsonFile, console.Ppu)
+}
+
+// frameHashes returns SHA-256 digests of the framebuffer, CPU RAM and both
+// together, which stay the same from run to run of a deterministic ROM.
+func frameHashes(console *console.Console) (string, string, string) {
+	framebuffer := make([]byte, 2*len(console.Ppu.Framebuffer()))
+	for i, pixel := range console.Ppu.Framebuffer() {
+		binary.LittleEndian.PutUint16(framebuffer[i*2:], pixel)
+	}
+	ram := console.Cpu.Ram()
+
+	combined := sha256.New()
+	combined.Write(framebuffer)
+	combined.Write(ram)
+
+	return fmt.Sprintf("%x", sha256.Sum256(framebuffer)), fmt.Sprintf("%x", sha256.Sum256(ram)), fmt.Sprintf("%x", combined.Sum(nil))
+}
+
+func headlessMain(args []string) {
+	flags := flag.NewFlagSet("headless", flag.ExitOnError)
+	frames := flags.Uint64("frames", 60, "number of frames to run")
+	inputFile := flags.String("input", "", "input script to play back, with frames counted from 1 like -screenshots")
+	screenshots := flags.String("screenshots", "", "comma separated list of frames to save as PNG")
+	outputDir := flags.String("out", ".", "directory screenshots are written to")
+	paletteFile := flags.String("palette", "", ".pal file used for screenshots instead of the built-in palette")
+	regionName := flags.String("region", "auto", "console region: ntsc, pal, dendy or auto to follow the ROM header")
+	filterChain := flags.String("filter", "", "filters applied to screenshots, separated by '+' with options after a colon, e.g. \"ntsc:artifacts=1,merge+crt:scanlines=0.6\" or \"hq3x\". One of: "+strings.Join(filter.FilterNames(), ", "))
+	recordFile := flags.String("record", "", "record every frame, through -filter, to a .gif, .y4m or .avi file, with audio for .avi, and for .y4m in a .wav of the same name unless -audio is given")
+	audioFile := flags.String("audio", "", "write the audio to a 16 bit PCM .wav file")
+	sampleRate := flags.Int("sample-rate", 44100, "audio sample rate in Hz for -audio, -record, -stems and the audio hash")
+	stemsDir := flags.String("stems", "", "also write each sound channel to its own .wav file in this directory, ignoring -mute and -solo")
+	mute := flags.String("mute", "", "comma separated list of sound channels to leave out of -audio and -record, e.g. \"triangle,noise\"")
+	solo := flags.String("solo", "", "comma separated list of the only sound channels to keep in -audio and -record")
+	noSpriteLimit := flags.Bool("no-sprite-limit", false, "draw every sprite on a scanline instead of the first eight, to reduce flicker")
+	views := flags.Bool("views", false, "also write nametable, attribute and OAM views (PNG and JSON) with each screenshot")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s headless [flags] FILENAME\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if flags.NArg() < 1 {
+		flags.Usage()
+		os.Exit(1)
+	}
+
+	screenshotFrames, err := parseFrameList(*screenshots)
+	if err != nil {
+		log.Fatalf("-screenshots: %s\n", err)
+	}
+	if *sampleRate <= 0 {
+		log.Fatalf("-sample-rate: %d Hz isn't above 0\n", *sampleRate)
+	}
+	if *recordFile != "" && filepath.Clean(*recordFile) == filepath.Clean(*audioFile) {
+		log.Fatalf("-audio: %s is also the -record file\n", *audioFile)
+	}
+
+	pal := palette.NewPalette()
+	if *paletteFile != "" {
+		pal, err = palette.PaletteFromFile(*paletteFile)
+		if err != nil {
+			log.Fatalf("PaletteFromFile(): %s\n", err)
+		}
+	}
+
+	pipeline, err := filter.NewPipeline(*filterChain, pal)
+	if err != nil {
+		log.Fatalf("-filter: %s\n", err)
+	}
+
+	var script *input.Script
+	if *inputFile != "" {
+		script, err = input.ScriptFromFile(*inputFile)
+		if err != nil {
+			log.Fatalf("ScriptFromFile(): %s\n", err)
+		}
+	}
+
+	rom, err := cartridge.RomFromFile(flags.Arg(0))
+	if err != nil {
+		log.Fatalf("RomFromFile(): %s\n", err)
+	}
+
+	console, err := loadConsole(rom, *regionName)
+	if err != nil {
+		log.Fatalf("loadConsole(): %s\n", err)
+	}
+
+	console.Ppu.SetSpriteLimit(!*noSpriteLimit)
+
+	err = setMutes(console.Apu, *mute, *solo)
+	if err != nil {
+		log.Fatalf("setMutes(): %s\n", err)
+	}
+
+	// The audio is always generated, so that it can be hashed
+	err = console.Apu.SetSampleRate(*sampleRate)
+	if err != nil {
+		log.Fatalf("-sample-rate: %s\n", err)
+	}
+
+	// A .y4m recording writes its audio to a .wav of the same name, unless
+	// -audio gives one
+	recordRate := *sampleRate
+	if *audioFile != "" && strings.ToLower(filepath.Ext(*recordFile)) == ".y4m" {
+		recordRate = 0
+	}
+
+	var recorders []record.Recorder
+	outputs := []struct {
+		filename   string
+		sampleRate int
+	}{{*recordFile, recordRate}, {*audioFile, *sampleRate}}
+	for _, output := range outputs {
+		if output.filename == "" {
+			continue
+		}
+
+		recorder, err := record.RecorderFromFile(output.filename, console.Region().FrameRate(), output.sampleRate)
+		if err != nil {
+			log.Fatalf("RecorderFromFile(): %s\n", err)
+		}
+		recorders = append(recorders, recorder)
+	}
+
+	var stems *stemRecorder
+	if *stemsDir != "" {
+		stems, err = newStemRecorder(*stemsDir, "", console.Apu.Channels(), *sampleRate)
+		if err != nil {
+			log.Fatalf("newStemRecorder(): %s\n", err)
+		}
+	}
+
+	console.Apu.SetStems(stems != nil)
+	audioHash := sha256.New()
+
+	for frame := uint64(1); frame <= *frames; frame++ {
+		if script != nil {
+			script.Apply(frame, console.Input)
+		}
+
+		err = console.RunFrame()
+		if err != nil {
+			log.Fatalf("Frame %d: console.RunFrame(): %s\n", frame, err)
+		}
+
+		samples := console.Apu.Samples()
+		binary.Write(audioHash, binary.LittleEndian, samples)
+		if stems != nil {
+			err = stems.AddAudio(console.Apu.StemSamples())
+			if err != nil {
+				log.Fatalf("Frame %d: stems.AddAudio(): %s\n", frame, err)
+			}
+		}
+
+		if len(recorders) > 0 {
+			img := pipeline.Apply(console.Ppu.Framebuffer(), console.Ppu.Frame())
+
+			for _, recorder := range recorders {
+				err = recorder.AddFrame(img)
+				if err != nil {
+					log.Fatalf("Frame %d: recorder.AddFrame(): %s\n", frame, err)
+				}
+
+				err = recorder.AddAudio(samples)
+				if err != nil {
+					log.Fatalf("Frame %d: recorder.AddAudio(): %s\n", frame, err)
+				}
+			}
+		}
+
+		if screenshotFrames[frame] {
+			prefix := filepath.Join(*outputDir, fmt.Sprintf("frame%06d", frame))
+			screenshot := pipeline.Apply(console.Ppu.Framebuffer(), console.Ppu.Frame())
+			err = writePng(prefix+".png", screenshot)
+			if err != nil {
+				log.Fatalf("writePng(): %s\n", err)
+			}
+
+			if *views {
+				err = writeViews(prefix, console, pal)
+				if err != nil {
+					log.Fatalf("writeViews(): %s\n", err)
+				}
+			}
+		}
+	}
+
+	for _, recorder := range recorders {
+		err = recorder.Close()
+		if err != nil {
+			log.Fatalf("recorder.Close(): %s\n", err)
+		}
+	}
+
+	if stems != nil {
+		err = stems.Close()
+		if err != nil {
+			log.Fatalf("stems.Close(): %s\n", err)
+		}
+	}
+
+	framebufferHash, ramHash, combinedHash := frameHashes(console)
+	fmt.Printf("frames: %d\n", *frames)
+	fmt.Printf("framebuffer: %s\n", framebufferHash)
+	fmt.Printf("ram: %s\n", ramHash)
+	fmt.Printf("audio: %x\n", audioHash.Sum(nil))
+	fmt.Printf("hash: %s\n", combinedHash)
+}
@@ -0,0 +1,70 @@
+package input
+
+import "fmt"
+
+// Buttons in the order the controller shifts them out
+const (
+	BUTTON_A byte = 1 << iota
+	BUTTON_B
+	BUTTON_SELECT
+	BUTTON_START
+	BUTTON_UP
+	BUTTON_DOWN
+	BUTTON_LEFT
+	BUTTON_RIGHT
+)
+
+// Controller is a standard NES joypad.
+type Controller struct {
+	buttons, shift byte
+	strobe         bool
+}
+
+func (controller *Controller) SetButtons(buttons byte) {
+	controller.buttons = buttons
+	if controller.strobe {
+		controller.shift = buttons
+	}
+}
+
+func (controller *Controller) read() byte {
+	if controller.strobe {
+		return controller.buttons & BUTTON_A
+	}
+
+	// After all eight buttons have been read, official controllers return 1
+	data := controller.shift & 0x01
+	controller.shift = controller.shift>>1 | 0x80
+	return data
+}
+
+// Ports connects two controllers to $4016 and $4017.
+type Ports struct {
+	Controllers [2]Controller
+}
+
+func (ports *Ports) ReadByte(address uint16) (byte, error) {
+	if address != 0x4016 && address != 0x4017 {
+		return 0x00, fmt.Errorf("Ports.ReadByte(): Unmapped memory address 0x%x.", address)
+	}
+
+	// The upper bits are left over on the data bus from the address high byte
+	return 0x40 | ports.Controllers[address-0x4016].read(), nil
+}
+
+// WriteByte handles the strobe at $4016, which latches both controllers.
+func (ports *Ports) WriteByte(address uint16, data byte) error {
+	if address != 0x4016 {
+		return fmt.Errorf("Ports.WriteByte(): Unmapped memory address 0x%x.", address)
+	}
+
+	for i := range ports.Controllers {
+		controller := &ports.Controllers[i]
+		controller.strobe = data&0x01 > 0
+		if controller.strobe {
+			controller.shift = controller.buttons
+		}
+	}
+
+	return nil
+}
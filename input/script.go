@@ -0,0 +1,122 @@
+package input
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var buttonNames = map[string]byte{
+	"a":      BUTTON_A,
+	"b":      BUTTON_B,
+	"select": BUTTON_SELECT,
+	"start":  BUTTON_START,
+	"up":     BUTTON_UP,
+	"down":   BUTTON_DOWN,
+	"left":   BUTTON_LEFT,
+	"right":  BUTTON_RIGHT,
+}
+
+type scriptEvent struct {
+	frame   uint64
+	buttons [2]byte
+}
+
+// Script is a list of controller states, each held from the frame it is
+// given for until the next one. Frames are counted from 1, the first frame
+// the console runs, as with headless -screenshots. Buttons given for frame 0
+// are held from the start too.
+type Script struct {
+	events []scriptEvent
+}
+
+func parseButtons(field string) (byte, error) {
+	var buttons byte
+	if field == "-" {
+		return buttons, nil
+	}
+
+	for _, name := range strings.Split(field, ",") {
+		button, ok := buttonNames[strings.ToLower(name)]
+		if !ok {
+			return 0, fmt.Errorf("unknown button %q", name)
+		}
+		buttons |= button
+	}
+
+	return buttons, nil
+}
+
+// ScriptFromFile reads an input script. Each line holds a frame number and
+// the buttons held on controller 1 and, optionally, controller 2 from then
+// on, for example "120 start" or "300 a,right -". A single dash releases
+// all buttons and # starts a comment.
+func ScriptFromFile(filename string) (*Script, error) {
+	scriptFile, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer scriptFile.Close()
+
+	script := new(Script)
+	scanner := bufio.NewScanner(scriptFile)
+	lineNumber := 0
+
+	for scanner.Scan() {
+		lineNumber++
+
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) > 3 {
+			return nil, fmt.Errorf("%s:%d: expected a frame and at most two controllers", filename, lineNumber)
+		}
+
+		var event scriptEvent
+		event.frame, err = strconv.ParseUint(fields[0], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: bad frame number %q", filename, lineNumber, fields[0])
+		}
+
+		for i, field := range fields[1:] {
+			event.buttons[i], err = parseButtons(field)
+			if err != nil {
+				return nil, fmt.Errorf("%s:%d: %s", filename, lineNumber, err)
+			}
+		}
+
+		script.events = append(script.events, event)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(script.events, func(i, j int) bool {
+		return script.events[i].frame < script.events[j].frame
+	})
+
+	return script, nil
+}
+
+// Apply sets the controllers to the state the script gives for a frame,
+// counted from 1. It is called before the frame runs.
+func (script *Script) Apply(frame uint64, ports *Ports) {
+	var buttons [2]byte
+
+	for _, event := range script.events {
+		if event.frame > frame {
+			break
+		}
+		buttons = event.buttons
+	}
+
+	for i := range ports.Controllers {
+		ports.Controllers[i].SetButtons(buttons[i])
+	}
+}
@@ -1,33 +1,165 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"github.com/tjarjoura/nes-emulator/cartridge"
-	"github.com/tjarjoura/nes-emulator/cpu"
+	"github.com/tjarjoura/nes-emulator/console"
+	"github.com/tjarjoura/nes-emulator/coverage"
+	"github.com/tjarjoura/nes-emulator/region"
 	"log"
 	"os"
+	"path/filepath"
+	"regexp"
 )
 
+var (
+	maxSteps    = flag.Int("steps", 0, "stop after executing this many instructions (0 runs until an error)")
+	lcovFile    = flag.String("lcov", "", "write an lcov coverage report to this file")
+	listingFile = flag.String("listing", "", "write a disassembly listing annotated with hit counts to this file")
+	dbgFile     = flag.String("dbg", "", "ca65 debug info used to map lcov coverage back to source lines")
+	regionName  = flag.String("region", "auto", "console region: ntsc, pal, dendy or auto to follow the ROM header")
+)
+
+// lcov test names may only contain letters, digits and underscores
+var lcovUnsafe = regexp.MustCompile(`[^A-Za-z0-9_]`)
+
+// loadConsole builds a console around a ROM that runs in the named region,
+// or in the one given by the ROM header for "auto".
+func loadConsole(rom *cartridge.Rom, regionName string) (*console.Console, error) {
+	consoleRegion := rom.Region
+	if regionName != "auto" {
+		var err error
+		consoleRegion, err = region.RegionFromString(regionName)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	cartridge, err := cartridge.CartridgeFromRom(rom)
+	if err != nil {
+		return nil, err
+	}
+
+	console := console.NewConsole(cartridge)
+	console.SetRegion(consoleRegion)
+	return console, nil
+}
+
+func lcovTestName(filename string) string {
+	name := filepath.Base(filename)
+	name = name[:len(name)-len(filepath.Ext(name))]
+	return lcovUnsafe.ReplaceAllString(name, "_")
+}
+
+func writeCoverage(recorder *coverage.Recorder, testName string) error {
+	if *listingFile != "" {
+		listing, err := os.Create(*listingFile)
+		if err != nil {
+			return err
+		}
+		defer listing.Close()
+
+		if err = recorder.WriteListing(listing); err != nil {
+			return err
+		}
+	}
+
+	if *lcovFile != "" {
+		var info *coverage.DebugInfo
+		if *dbgFile != "" {
+			var err error
+			info, err = coverage.DebugInfoFromFile(*dbgFile)
+			if err != nil {
+				return err
+			}
+		}
+
+		lcov, err := os.Create(*lcovFile)
+		if err != nil {
+			return err
+		}
+		defer lcov.Close()
+
+		if err = recorder.WriteLcov(lcov, testName, info, *listingFile); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 func main() {
 	log.SetFlags(0)
 
-	if len(os.Args) < 2 {
-		log.Fatalf("Usage: %s FILENAME\n", os.Args[0])
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "headless":
+			headlessMain(os.Args[2:])
+			return
+		case "chr":
+			chrMain(os.Args[2:])
+			return
+		case "nsf":
+			nsfMain(os.Args[2:])
+			return
+		case "apu-bench":
+			apuBenchMain(os.Args[2:])
+			return
+		}
 	}
 
-	filename := os.Args[1]
-	cartridge, err := cartridge.CartridgeFromFile(filename)
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s [flags] FILENAME\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s headless [flags] FILENAME\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s chr [flags] FILENAME\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s nsf [flags] FILENAME\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s apu-bench [flags]\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
 
+	if flag.NArg() < 1 {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	filename := flag.Arg(0)
+	rom, err := cartridge.RomFromFile(filename)
 	if err != nil {
-		log.Fatalf("CartridgeFromFile(): %s\n", err)
+		log.Fatalf("RomFromFile(): %s\n", err)
+	}
+
+	if *lcovFile != "" && *listingFile == "" && *dbgFile == "" {
+		log.Fatalf("-lcov needs either -dbg or -listing to refer to\n")
 	}
 
-	cpu := new(cpu.Cpu)
-	cpu.LoadProgram(cartridge)
-	fmt.Printf("%s\n", cpu.String())
+	console, err := loadConsole(rom, *regionName)
+	if err != nil {
+		log.Fatalf("loadConsole(): %s\n", err)
+	}
+	fmt.Printf("%s\n", console.Cpu.String())
+
+	var recorder *coverage.Recorder
+	if *lcovFile != "" || *listingFile != "" {
+		recorder = coverage.NewRecorder(console.Cartridge, rom)
+		console.Cpu.SetExecuteHook(recorder.Record)
+	}
+
+	for steps := 0; *maxSteps == 0 || steps < *maxSteps; steps++ {
+		err = console.Step()
+		if err != nil {
+			break
+		}
+	}
+
+	if recorder != nil {
+		if err := writeCoverage(recorder, lcovTestName(filename)); err != nil {
+			log.Fatalf("writeCoverage(): %s\n", err)
+		}
+	}
 
-	err = cpu.Run(false)
 	if err != nil {
-		log.Fatalf("cpu.Run(): %s\n", err)
+		log.Fatalf("console.Step(): %s\n", err)
 	}
 }
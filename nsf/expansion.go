@@ -0,0 +1,133 @@
+package nsf
+
+import (
+	"fmt"
+	"github.com/tjarjoura/nes-emulator/apu"
+	"github.com/tjarjoura/nes-emulator/types"
+)
+
+// Header bits for the expansion chips a file uses
+const (
+	NSF_EXPANSION_VRC6      byte = 0x01
+	NSF_EXPANSION_VRC7      byte = 0x02
+	NSF_EXPANSION_FDS       byte = 0x04
+	NSF_EXPANSION_MMC5      byte = 0x08
+	NSF_EXPANSION_N163      byte = 0x10
+	NSF_EXPANSION_SUNSOFT5B byte = 0x20
+)
+
+// expansionChip is a chip along with the addresses it answers to. Boards
+// with a single chip can let it decode addresses loosely, but on an NSF
+// player the chips share the bus, so each only sees its own registers.
+type expansionChip struct {
+	types.ExpansionAudio
+	decodes func(address uint16) bool
+}
+
+func vrc6Registers(address uint16) bool {
+	switch address & 0xF000 {
+	case 0x9000:
+		return address&0x0FFF <= 0x003
+	case 0xA000, 0xB000:
+		return address&0x0FFF <= 0x002
+	}
+	return false
+}
+
+func vrc7Registers(address uint16) bool {
+	return address == apu.VRC7_ADDRESS || address == apu.VRC7_DATA
+}
+
+func fdsRegisters(address uint16) bool {
+	return address >= 0x4040 && address <= 0x4092
+}
+
+func mmc5Registers(address uint16) bool {
+	return address >= 0x5000 && address <= 0x5015
+}
+
+func n163Registers(address uint16) bool {
+	return (address >= 0x4800 && address <= 0x4FFF) || (address >= 0xE000 && address <= 0xE7FF) || address >= 0xF800
+}
+
+func sunsoft5bRegisters(address uint16) bool {
+	return address >= 0xC000
+}
+
+// chips lets a file use several expansion chips at once, passing each
+// register access on to the chips that decode its address.
+type chips []expansionChip
+
+func newChips(expansion byte, cpuClockRate float64) chips {
+	var chips chips
+
+	if expansion&NSF_EXPANSION_VRC6 > 0 {
+		chips = append(chips, expansionChip{apu.NewVrc6(), vrc6Registers})
+	}
+	if expansion&NSF_EXPANSION_VRC7 > 0 {
+		chips = append(chips, expansionChip{apu.NewVrc7(), vrc7Registers})
+	}
+	if expansion&NSF_EXPANSION_FDS > 0 {
+		chips = append(chips, expansionChip{apu.NewFds(cpuClockRate), fdsRegisters})
+	}
+	if expansion&NSF_EXPANSION_MMC5 > 0 {
+		chips = append(chips, expansionChip{apu.NewMmc5Audio(), mmc5Registers})
+	}
+	if expansion&NSF_EXPANSION_N163 > 0 {
+		chips = append(chips, expansionChip{apu.NewNamco163(), n163Registers})
+	}
+	if expansion&NSF_EXPANSION_SUNSOFT5B > 0 {
+		chips = append(chips, expansionChip{apu.NewSunsoft5b(), sunsoft5bRegisters})
+	}
+
+	return chips
+}
+
+func (chips chips) ReadByte(address uint16) (byte, error) {
+	for _, chip := range chips {
+		if !chip.decodes(address) {
+			continue
+		}
+		if data, err := chip.ReadByte(address); err == nil {
+			return data, nil
+		}
+	}
+	return 0x00, fmt.Errorf("chips.ReadByte(): Unmapped memory address 0x%x.", address)
+}
+
+func (chips chips) WriteByte(address uint16, data byte) error {
+	for _, chip := range chips {
+		if !chip.decodes(address) {
+			continue
+		}
+		err := chip.WriteByte(address, data)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (chips chips) Clock() {
+	for _, chip := range chips {
+		chip.Clock()
+	}
+}
+
+func (chips chips) Channels() []string {
+	var channels []string
+	for _, chip := range chips {
+		channels = append(channels, chip.Channels()...)
+	}
+	return channels
+}
+
+func (chips chips) ChannelOutput(channel int) float64 {
+	for _, chip := range chips {
+		if channel < len(chip.Channels()) {
+			return chip.ChannelOutput(channel)
+		}
+		channel -= len(chip.Channels())
+	}
+	return 0
+}
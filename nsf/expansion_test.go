@@ -0,0 +1,22 @@
+package nsf
+
+import "testing"
+
+func TestChipsDecodeTheirOwnRegisters(t *testing.T) {
+	chips := newChips(NSF_EXPANSION_VRC6|NSF_EXPANSION_VRC7, 1789773)
+
+	chips.WriteByte(0x9002, 0x80) // VRC6 pulse 1 on
+	chips.WriteByte(0x9010, 0x8F) // VRC7 register select
+	chips.WriteByte(0x9030, 0x8F) // VRC7 register data
+
+	// Both writes would set pulse 1 to a constant volume of 15 if the VRC6
+	// saw them
+	if level := chips.ChannelOutput(0); level != 0 {
+		t.Errorf("VRC6 pulse 1 at %g after VRC7 writes, want 0", level)
+	}
+
+	chips.WriteByte(0x9000, 0x8F)
+	if level := chips.ChannelOutput(0); level == 0 {
+		t.Error("VRC6 pulse 1 silent after a write to $9000")
+	}
+}
@@ -0,0 +1,102 @@
+package nsf
+
+import "fmt"
+
+const NSF_BANK_SZ int = 4096
+
+// memory is the cartridge side of the CPU bus while an NSF plays: 8KB of
+// RAM at $6000-$7FFF, eight 4KB windows onto the driver at $8000-$FFFF and
+// the registers of any expansion chips.
+//
+// The FDS has RAM from $6000 to $DFFF instead, which the driver is loaded
+// into. Its banks are switched through $5FF6-$5FFF, with $6000-$7FFF
+// starting out with the same banks as $E000-$FFFF.
+type memory struct {
+	rom   []byte
+	banks [10]int // Offset into rom of the bank in each window from $6000
+	ram   [8192]byte
+
+	expansion chips
+	fds       bool
+}
+
+func newMemory(nsf *Nsf, expansion chips) *memory {
+	mem := &memory{expansion: expansion, fds: nsf.Expansion&NSF_EXPANSION_FDS > 0}
+
+	if nsf.Bankswitched() {
+		// The first bank starts at the 4KB boundary below the load address
+		padding := int(nsf.LoadAddress) % NSF_BANK_SZ
+		size := (padding + len(nsf.Data) + NSF_BANK_SZ - 1) / NSF_BANK_SZ * NSF_BANK_SZ
+		mem.rom = make([]byte, size)
+		copy(mem.rom[padding:], nsf.Data)
+
+		for window, bank := range nsf.Bankswitch {
+			mem.selectBank(window+2, bank)
+		}
+		mem.selectBank(0, nsf.Bankswitch[6])
+		mem.selectBank(1, nsf.Bankswitch[7])
+	} else {
+		mem.rom = make([]byte, 0xA000)
+		copy(mem.rom[nsf.LoadAddress-0x6000:], nsf.Data)
+
+		for window := range mem.banks {
+			mem.banks[window] = window * NSF_BANK_SZ
+		}
+	}
+
+	return mem
+}
+
+func (mem *memory) selectBank(window int, bank byte) {
+	mem.banks[window] = int(bank) * NSF_BANK_SZ % len(mem.rom)
+}
+
+func (mem *memory) romOffset(address uint16) int {
+	window := int(address-0x6000) / NSF_BANK_SZ
+	return mem.banks[window] + int(address)%NSF_BANK_SZ
+}
+
+func (mem *memory) ReadByte(address uint16) (byte, error) {
+	switch {
+	case address >= 0x8000 || (address >= 0x6000 && mem.fds):
+		return mem.rom[mem.romOffset(address)], nil
+	case address >= 0x6000:
+		return mem.ram[address-0x6000], nil
+	case address >= 0x4020:
+		if data, err := mem.expansion.ReadByte(address); err == nil {
+			return data, nil
+		}
+		return 0x00, nil // Open bus on a real player
+	default:
+		return 0x00, fmt.Errorf("memory.ReadByte(): Unmapped memory address 0x%x.", address)
+	}
+}
+
+func (mem *memory) WriteByte(address uint16, data byte) error {
+	// Expansion registers sit in the unused area and over ROM
+	if address >= 0x8000 || (address >= 0x4020 && address < 0x6000) {
+		err := mem.expansion.WriteByte(address, data)
+		if err != nil {
+			return err
+		}
+	}
+
+	switch {
+	case address >= 0x6000 && mem.fds:
+		if address < 0xE000 {
+			mem.rom[mem.romOffset(address)] = data
+		}
+	case address >= 0x8000:
+		// ROM
+	case address >= 0x6000:
+		mem.ram[address-0x6000] = data
+	case address >= 0x5FF8 || (address >= 0x5FF6 && mem.fds):
+		mem.selectBank(int(address-0x5FF6), data)
+	case address >= 0x4020:
+		// Expansion registers, passed on above
+	default:
+		return fmt.Errorf("memory.WriteByte(): Unmapped memory address 0x%x.", address)
+	}
+
+	return nil
+}
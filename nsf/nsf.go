@@ -0,0 +1,187 @@
+package nsf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+const NSF_HEADER_SZ int = 128
+
+const (
+	NSF_REGION_PAL  byte = 0x01
+	NSF_REGION_DUAL byte = 0x02
+)
+
+// NSF2 header flags
+const (
+	NSF2_IRQ               byte = 0x10 // Uses the $401B-$401D IRQ timer, which isn't emulated
+	NSF2_NON_RETURNING     byte = 0x20
+	NSF2_NO_PLAY           byte = 0x40
+	NSF2_METADATA_REQUIRED byte = 0x80
+)
+
+// Track holds what an NSFe or NSF2 file says about one of its songs.
+type Track struct {
+	Title  string
+	Author string
+	Length float64 // Seconds before the fade starts, or 0 if unknown
+	Fade   float64 // Seconds, or -1 if unknown
+}
+
+// Nsf holds an NSF music file: a sound driver and its data, with the
+// addresses the player calls into.
+type Nsf struct {
+	Version      byte
+	Songs        int
+	StartingSong int // From 1
+	LoadAddress  uint16
+	InitAddress  uint16
+	PlayAddress  uint16
+
+	Title, Artist, Copyright, Ripper string
+
+	NtscSpeed, PalSpeed uint16 // Microseconds between play calls
+	Bankswitch          [8]byte
+	Region              byte
+	Expansion           byte
+	Flags               byte // NSF2 only
+
+	Tracks   []Track // Empty for files without metadata
+	Playlist []int   // Tracks in the order to play them, from 1
+
+	Data []byte
+}
+
+func headerString(field []byte) string {
+	if end := bytes.IndexByte(field, 0); end >= 0 {
+		field = field[:end]
+	}
+	return strings.TrimSpace(string(field))
+}
+
+// Bankswitched reports whether the driver maps 4KB banks into $8000-$FFFF
+// through $5FF8-$5FFF rather than loading at a fixed address.
+func (nsf *Nsf) Bankswitched() bool {
+	for _, bank := range nsf.Bankswitch {
+		if bank != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Track returns the metadata for a track, counted from 1.
+func (nsf *Nsf) Track(track int) Track {
+	if track >= 1 && track <= len(nsf.Tracks) {
+		return nsf.Tracks[track-1]
+	}
+	return Track{Fade: -1}
+}
+
+// TrackOrder lists the tracks to play, following the playlist if the file
+// has one.
+func (nsf *Nsf) TrackOrder() []int {
+	if len(nsf.Playlist) > 0 {
+		return nsf.Playlist
+	}
+
+	order := make([]int, nsf.Songs)
+	for i := range order {
+		order[i] = i + 1
+	}
+	return order
+}
+
+// NsfFromFile loads a file in NSF, NSF2 or NSFe format.
+func NsfFromFile(filename string) (*Nsf, error) {
+	contents, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var nsf *Nsf
+	switch {
+	case bytes.HasPrefix(contents, []byte{'N', 'E', 'S', 'M', 0x1A}):
+		nsf, err = parseNsf(contents)
+	case bytes.HasPrefix(contents, []byte("NSFE")):
+		nsf, err = parseNsfe(contents[4:])
+	default:
+		err = fmt.Errorf("Unrecognized file format")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", filename, err)
+	}
+
+	if len(nsf.Data) == 0 {
+		return nil, fmt.Errorf("%s: No program data", filename)
+	}
+
+	if nsf.StartingSong < 1 || nsf.StartingSong > nsf.Songs {
+		nsf.StartingSong = 1
+	}
+	nsf.finishTracks()
+
+	// The FDS has RAM to load into from $6000
+	lowest := uint16(0x8000)
+	if nsf.Expansion&NSF_EXPANSION_FDS > 0 {
+		lowest = 0x6000
+	}
+	if !nsf.Bankswitched() && nsf.LoadAddress < lowest {
+		return nil, fmt.Errorf("%s: Load address 0x%x is below $%X", filename, nsf.LoadAddress, lowest)
+	}
+
+	return nsf, nil
+}
+
+func parseNsf(contents []byte) (*Nsf, error) {
+	if len(contents) < NSF_HEADER_SZ {
+		return nil, io.ErrUnexpectedEOF
+	}
+	header := contents[:NSF_HEADER_SZ]
+
+	nsf := &Nsf{
+		Version:      header[5],
+		Songs:        int(header[6]),
+		StartingSong: int(header[7]),
+		LoadAddress:  binary.LittleEndian.Uint16(header[0x08:]),
+		InitAddress:  binary.LittleEndian.Uint16(header[0x0A:]),
+		PlayAddress:  binary.LittleEndian.Uint16(header[0x0C:]),
+		Title:        headerString(header[0x0E:0x2E]),
+		Artist:       headerString(header[0x2E:0x4E]),
+		Copyright:    headerString(header[0x4E:0x6E]),
+		NtscSpeed:    binary.LittleEndian.Uint16(header[0x6E:]),
+		PalSpeed:     binary.LittleEndian.Uint16(header[0x78:]),
+		Region:       header[0x7A],
+		Expansion:    header[0x7B],
+	}
+	copy(nsf.Bankswitch[:], header[0x70:0x78])
+	nsf.Data = contents[NSF_HEADER_SZ:]
+
+	if nsf.Version < 2 {
+		return nsf, nil
+	}
+
+	// NSF2 gives the length of the program data, and NSFe metadata chunks
+	// may follow it
+	nsf.Flags = header[0x7C]
+	length := int(header[0x7D]) | int(header[0x7E])<<8 | int(header[0x7F])<<16
+	if length == 0 {
+		return nsf, nil
+	}
+	if length > len(nsf.Data) {
+		return nil, fmt.Errorf("Program data length %d runs past the end of the file", length)
+	}
+
+	metadata := nsf.Data[length:]
+	nsf.Data = nsf.Data[:length]
+
+	err := nsf.readChunks(metadata)
+	if err != nil && nsf.Flags&NSF2_METADATA_REQUIRED > 0 {
+		return nil, err
+	}
+	return nsf, nil
+}
@@ -0,0 +1,166 @@
+package nsf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+)
+
+// NSFe time and fade entries of -1 leave the player's default
+const NSFE_DEFAULT_TIME int32 = -1
+
+// parseNsfe reads an NSFe file after its "NSFE" signature. The header fields
+// of a plain NSF come from the INFO, BANK and RATE chunks.
+func parseNsfe(contents []byte) (*Nsf, error) {
+	nsf := &Nsf{Songs: 1, StartingSong: 1}
+
+	err := nsf.readChunks(contents)
+	if err != nil {
+		return nil, err
+	}
+
+	if nsf.InitAddress == 0 {
+		return nil, fmt.Errorf("Missing INFO chunk")
+	}
+	if nsf.Data == nil {
+		return nil, fmt.Errorf("Missing DATA chunk")
+	}
+	return nsf, nil
+}
+
+// chunkStrings splits a chunk of null terminated strings.
+func chunkStrings(body []byte) []string {
+	body = bytes.TrimSuffix(body, []byte{0})
+	if len(body) == 0 {
+		return nil
+	}
+
+	var fields []string
+	for _, field := range bytes.Split(body, []byte{0}) {
+		fields = append(fields, headerString(field))
+	}
+	return fields
+}
+
+// chunkTimes reads a chunk of millisecond times as seconds, passing each to
+// set along with its track index.
+func chunkTimes(body []byte, set func(index int, seconds float64)) {
+	for index := 0; index+4 <= len(body); index += 4 {
+		milliseconds := int32(binary.LittleEndian.Uint32(body[index:]))
+		if milliseconds != NSFE_DEFAULT_TIME && milliseconds >= 0 {
+			set(index/4, float64(milliseconds)/1000)
+		}
+	}
+}
+
+// track returns the metadata of a track counted from 0, adding defaults for
+// tracks that haven't been mentioned yet.
+func (nsf *Nsf) track(index int) *Track {
+	for len(nsf.Tracks) <= index {
+		nsf.Tracks = append(nsf.Tracks, Track{Fade: -1})
+	}
+	return &nsf.Tracks[index]
+}
+
+// finishTracks trims metadata to the number of songs and drops playlist
+// entries for songs that don't exist.
+func (nsf *Nsf) finishTracks() {
+	if len(nsf.Tracks) > nsf.Songs {
+		nsf.Tracks = nsf.Tracks[:nsf.Songs]
+	} else if len(nsf.Tracks) > 0 {
+		nsf.track(nsf.Songs - 1)
+	}
+
+	playlist := nsf.Playlist[:0]
+	for _, track := range nsf.Playlist {
+		if track >= 1 && track <= nsf.Songs {
+			playlist = append(playlist, track)
+		}
+	}
+	nsf.Playlist = playlist
+}
+
+// readChunks reads NSFe chunks, each a 32 bit length and four character ID
+// followed by the data, up to NEND or the end of the file. Chunks with an ID
+// starting in upper case must be understood to play the file, the rest can
+// be skipped.
+func (nsf *Nsf) readChunks(contents []byte) error {
+	for len(contents) > 0 {
+		if len(contents) < 8 {
+			return fmt.Errorf("Truncated NSFe chunk header")
+		}
+
+		size := binary.LittleEndian.Uint32(contents)
+		id := string(contents[4:8])
+		if uint64(size) > uint64(len(contents)-8) {
+			return fmt.Errorf("NSFe chunk %q runs past the end of the file", id)
+		}
+		body := contents[8 : 8+size]
+		contents = contents[8+size:]
+
+		switch id {
+		case "INFO":
+			if len(body) < 8 {
+				return fmt.Errorf("NSFe INFO chunk is too short")
+			}
+			nsf.LoadAddress = binary.LittleEndian.Uint16(body[0:])
+			nsf.InitAddress = binary.LittleEndian.Uint16(body[2:])
+			nsf.PlayAddress = binary.LittleEndian.Uint16(body[4:])
+			nsf.Region = body[6]
+			nsf.Expansion = body[7]
+			if len(body) > 8 {
+				nsf.Songs = int(body[8])
+			}
+			if len(body) > 9 {
+				nsf.StartingSong = int(body[9]) + 1
+			}
+		case "DATA":
+			nsf.Data = body
+		case "BANK":
+			copy(nsf.Bankswitch[:], body)
+		case "RATE":
+			if len(body) >= 2 {
+				nsf.NtscSpeed = binary.LittleEndian.Uint16(body[0:])
+			}
+			if len(body) >= 4 {
+				nsf.PalSpeed = binary.LittleEndian.Uint16(body[2:])
+			}
+		case "auth":
+			fields := chunkStrings(body)
+			for i, field := range []*string{&nsf.Title, &nsf.Artist, &nsf.Copyright, &nsf.Ripper} {
+				if i < len(fields) {
+					*field = fields[i]
+				}
+			}
+		case "tlbl":
+			for index, title := range chunkStrings(body) {
+				nsf.track(index).Title = title
+			}
+		case "taut":
+			for index, author := range chunkStrings(body) {
+				nsf.track(index).Author = author
+			}
+		case "time":
+			chunkTimes(body, func(index int, seconds float64) {
+				nsf.track(index).Length = seconds
+			})
+		case "fade":
+			chunkTimes(body, func(index int, seconds float64) {
+				nsf.track(index).Fade = seconds
+			})
+		case "plst":
+			nsf.Playlist = nsf.Playlist[:0]
+			for _, track := range body {
+				nsf.Playlist = append(nsf.Playlist, int(track)+1)
+			}
+		case "NEND":
+			return nil
+		default:
+			if id[0] >= 'A' && id[0] <= 'Z' {
+				return fmt.Errorf("Unsupported NSFe chunk %q", id)
+			}
+		}
+	}
+
+	return nil
+}
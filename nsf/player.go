@@ -0,0 +1,193 @@
+package nsf
+
+import (
+	"fmt"
+	"github.com/tjarjoura/nes-emulator/apu"
+	"github.com/tjarjoura/nes-emulator/cpu"
+	"github.com/tjarjoura/nes-emulator/region"
+)
+
+// Calls to init and play that run longer than this are assumed to be stuck.
+const MAX_CALL_SECONDS float64 = 1
+
+// Player runs an NSF's driver on the CPU and APU, calling play at the rate
+// the file asks for.
+type Player struct {
+	Nsf *Nsf
+	Cpu *cpu.Cpu
+	Apu *apu.Apu
+
+	track         int
+	region        region.Region
+	timing        *region.Timing
+	cyclesPerPlay float64
+	playCycles    float64 // When the next play call is due
+	apuCycles     uint64
+
+	// An NSF2 init that doesn't return keeps running between play calls,
+	// which interrupt it like an NMI
+	initRunning bool
+}
+
+// DefaultRegion is the region the NSF was written for, preferring NTSC for
+// files that support both.
+func (nsf *Nsf) DefaultRegion() region.Region {
+	if nsf.Region&NSF_REGION_PAL > 0 && nsf.Region&NSF_REGION_DUAL == 0 {
+		return region.PAL
+	}
+	return region.NTSC
+}
+
+func NewPlayer(nsf *Nsf, playerRegion region.Region, sampleRate int) (*Player, error) {
+	player := &Player{Nsf: nsf, region: playerRegion, timing: playerRegion.Timing()}
+
+	speed := nsf.NtscSpeed
+	if playerRegion == region.PAL {
+		speed = nsf.PalSpeed
+	}
+	if speed == 0 {
+		player.cyclesPerPlay = player.timing.CpuClockRate / playerRegion.FrameRate()
+	} else {
+		player.cyclesPerPlay = player.timing.CpuClockRate * float64(speed) / 1e6
+	}
+
+	err := player.reset(sampleRate)
+	if err != nil {
+		return nil, err
+	}
+	return player, nil
+}
+
+// reset builds a fresh console, keeping the APU's mute and stem settings.
+func (player *Player) reset(sampleRate int) error {
+	previous := player.Apu
+	player.Cpu = new(cpu.Cpu)
+	player.Apu = apu.NewApu()
+	player.Apu.SetRegion(player.region)
+	err := player.Apu.SetSampleRate(sampleRate)
+	if err != nil {
+		return err
+	}
+	player.apuCycles = 0
+	player.playCycles = 0
+	player.initRunning = false
+
+	expansion := newChips(player.Nsf.Expansion, player.timing.CpuClockRate)
+	player.Cpu.LoadProgram(newMemory(player.Nsf, expansion))
+	player.Cpu.ConnectApu(player.Apu)
+	if len(expansion) > 0 {
+		player.Apu.SetExpansion(expansion)
+	}
+	if previous != nil {
+		for _, channel := range previous.Channels() {
+			player.Apu.SetMuted(channel, previous.Muted(channel))
+		}
+		player.Apu.SetStems(previous.Stems())
+	}
+	player.Apu.ConnectMemory(player.Cpu.ReadMemory, player.Cpu.Stall)
+
+	// Keep the APU in step with every instruction, and with the exact cycle
+	// of APU register accesses, so writes land at the right time
+	player.Cpu.SetExecuteHook(func(address uint16) {
+		player.catchUp(player.Cpu.Cycles())
+	})
+	player.Cpu.SetBusHook(player.catchUp)
+	return nil
+}
+
+func (player *Player) catchUp(cycle uint64) {
+	for player.apuCycles < cycle {
+		player.Apu.Clock()
+		player.apuCycles++
+	}
+}
+
+func (player *Player) maxCallCycles() uint64 {
+	return uint64(player.timing.CpuClockRate * MAX_CALL_SECONDS)
+}
+
+// InitTrack resets the console and calls init for a track, counted from 1.
+func (player *Player) InitTrack(track int) error {
+	if track < 1 || track > player.Nsf.Songs {
+		return fmt.Errorf("Track %d out of range 1-%d", track, player.Nsf.Songs)
+	}
+
+	err := player.reset(player.Apu.SampleRate())
+	if err != nil {
+		return err
+	}
+	player.track = track
+
+	// The APU starts with every channel enabled, silent, and the frame
+	// counter's IRQ inhibited as the driver has no IRQ handler
+	for address := uint16(0x4000); address <= 0x4013; address++ {
+		player.Apu.WriteByte(address, 0x00)
+	}
+	player.Apu.WriteByte(apu.APU_STATUS, 0x0F)
+	player.Apu.WriteByte(apu.APU_FRAME_COUNTER, apu.FRAME_COUNTER_IRQ_INHIBIT)
+
+	var x byte
+	if player.region == region.PAL {
+		x = 1
+	}
+
+	if player.Nsf.Flags&NSF2_NON_RETURNING > 0 {
+		// Init gets until the first play call to set up
+		player.Cpu.Start(player.Nsf.InitAddress, byte(track-1), x)
+		var returned bool
+		returned, err = player.Cpu.RunUntil(player.Cpu.Cycles() + uint64(player.cyclesPerPlay))
+		player.initRunning = !returned
+	} else {
+		err = player.Cpu.Call(player.Nsf.InitAddress, byte(track-1), x, player.maxCallCycles())
+	}
+	player.catchUp(player.Cpu.Cycles())
+	player.playCycles = float64(player.Cpu.Cycles())
+	return err
+}
+
+// Track returns the metadata of the track last started with InitTrack.
+func (player *Player) Track() Track {
+	return player.Nsf.Track(player.track)
+}
+
+// PlayFrame calls play once and runs the APU until the next call is due,
+// returning the audio produced. NSF2 files can ask for play not to be
+// called, leaving the work to an init that never returns.
+func (player *Player) PlayFrame() ([]int16, error) {
+	var err error
+	switch {
+	case player.Nsf.Flags&NSF2_NO_PLAY > 0:
+	case player.initRunning:
+		err = player.Cpu.Interrupt(player.Nsf.PlayAddress, player.maxCallCycles())
+	default:
+		err = player.Cpu.Call(player.Nsf.PlayAddress, 0, 0, player.maxCallCycles())
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	player.playCycles += player.cyclesPerPlay
+	if player.initRunning {
+		returned, err := player.Cpu.RunUntil(uint64(player.playCycles))
+		if err != nil {
+			return nil, err
+		}
+		player.initRunning = !returned
+	}
+	for float64(player.apuCycles) < player.playCycles {
+		player.Apu.Clock()
+		player.apuCycles++
+	}
+
+	// The CPU idles until the next call
+	if player.apuCycles > player.Cpu.Cycles() {
+		player.Cpu.Stall(int(player.apuCycles - player.Cpu.Cycles()))
+	}
+
+	return player.Apu.Samples(), nil
+}
+
+// FrameRate is how many times a second play is called.
+func (player *Player) FrameRate() float64 {
+	return player.timing.CpuClockRate / player.cyclesPerPlay
+}
@@ -0,0 +1,279 @@
+package nsf
+
+import (
+	"encoding/binary"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/tjarjoura/nes-emulator/region"
+)
+
+// fixture describes a small NSF written out by writeFixture.
+type fixture struct {
+	version   byte
+	load      uint16
+	init      uint16
+	play      uint16
+	expansion byte
+	flags     byte // NSF2 only
+	banks     [8]byte
+	data      []byte
+}
+
+func writeFixture(t *testing.T, f fixture) string {
+	t.Helper()
+
+	header := make([]byte, NSF_HEADER_SZ)
+	copy(header, "NESM\x1A")
+	header[5] = f.version
+	header[6] = 1 // Songs
+	header[7] = 1 // Starting song
+	binary.LittleEndian.PutUint16(header[0x08:], f.load)
+	binary.LittleEndian.PutUint16(header[0x0A:], f.init)
+	binary.LittleEndian.PutUint16(header[0x0C:], f.play)
+	copy(header[0x0E:], "Fixture")
+	binary.LittleEndian.PutUint16(header[0x6E:], 16639) // 60.1Hz
+	copy(header[0x70:], f.banks[:])
+	binary.LittleEndian.PutUint16(header[0x78:], 19997)
+	header[0x7B] = f.expansion
+	if f.version >= 2 {
+		header[0x7C] = f.flags
+	}
+
+	filename := filepath.Join(t.TempDir(), "fixture.nsf")
+	err := os.WriteFile(filename, append(header, f.data...), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return filename
+}
+
+// toneDriver starts a 440Hz square wave on pulse 1 from init at $8000 and
+// counts play calls at $6001 from play at $8020.
+func toneDriver() []byte {
+	code := make([]byte, 0x30)
+	copy(code, []byte{
+		0xA9, 0x42, // LDA #$42
+		0x8D, 0x00, 0x60, // STA $6000
+		0xA9, 0xBF, // LDA #$BF; 50% duty, constant volume 15
+		0x8D, 0x00, 0x40, // STA $4000
+		0xA9, 0xFD, // LDA #$FD
+		0x8D, 0x02, 0x40, // STA $4002
+		0xA9, 0x00, // LDA #$00
+		0x8D, 0x03, 0x40, // STA $4003
+		0x60, // RTS
+	})
+	copy(code[0x20:], []byte{
+		0xEE, 0x01, 0x60, // INC $6001
+		0x60, // RTS
+	})
+	return code
+}
+
+func loadFixture(t *testing.T, f fixture) *Player {
+	t.Helper()
+
+	music, err := NsfFromFile(writeFixture(t, f))
+	if err != nil {
+		t.Fatalf("NsfFromFile(): %s", err)
+	}
+
+	player, err := NewPlayer(music, region.NTSC, 44100)
+	if err != nil {
+		t.Fatalf("NewPlayer(): %s", err)
+	}
+	if err := player.InitTrack(1); err != nil {
+		t.Fatalf("InitTrack(): %s", err)
+	}
+	return player
+}
+
+// goertzel returns the power of samples at frequency Hz.
+func goertzel(samples []int16, frequency float64, sampleRate float64) float64 {
+	coefficient := 2 * math.Cos(2*math.Pi*frequency/sampleRate)
+	var s1, s2 float64
+	for _, sample := range samples {
+		s1, s2 = float64(sample)+coefficient*s1-s2, s1
+	}
+	return s1*s1 + s2*s2 - coefficient*s1*s2
+}
+
+func TestRenderFixture(t *testing.T) {
+	player := loadFixture(t, fixture{version: 1, load: 0x8000, init: 0x8000, play: 0x8020, data: toneDriver()})
+
+	if data := player.Cpu.ReadMemory(0x6000); data != 0x42 {
+		t.Errorf("$6000 = %02X after init, want 42", data)
+	}
+
+	var samples []int16
+	err := player.Render(RenderOptions{Seconds: 1}, func(frame []int16, stems [][]int16) error {
+		samples = append(samples, frame...)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Render(): %s", err)
+	}
+
+	if len(samples) != 44100 {
+		t.Errorf("Rendered %d samples, want 44100", len(samples))
+	}
+	if calls := player.Cpu.ReadMemory(0x6001); calls < 59 || calls > 61 {
+		t.Errorf("Play was called %d times in a second, want 60", calls)
+	}
+
+	// The period of $0FD plays 1789773 / (16 * 254) = 440.4Hz
+	tone := goertzel(samples, 440.4, 44100)
+	off := goertzel(samples, 523.3, 44100)
+	if tone < 100*off {
+		t.Errorf("Power at 440Hz is %g against %g at 523Hz, want a clear 440Hz tone", tone, off)
+	}
+}
+
+func TestNoData(t *testing.T) {
+	f := fixture{version: 1, load: 0x8000, init: 0x8000, play: 0x8000, banks: [8]byte{0, 1}}
+	if _, err := NsfFromFile(writeFixture(t, f)); err == nil {
+		t.Error("NsfFromFile() loaded a bankswitched file with no program data")
+	}
+}
+
+func TestFdsLoadAddress(t *testing.T) {
+	f := fixture{version: 1, load: 0x6000, init: 0x6000, play: 0x6020, data: toneDriver()}
+	if _, err := NsfFromFile(writeFixture(t, f)); err == nil {
+		t.Error("NsfFromFile() loaded at $6000 without the FDS")
+	}
+
+	f.expansion = NSF_EXPANSION_FDS
+	player := loadFixture(t, f)
+	if data := player.Cpu.ReadMemory(0x6000); data != 0x42 {
+		t.Errorf("$6000 = %02X after init, want 42", data)
+	}
+}
+
+func TestFdsBanks(t *testing.T) {
+	data := make([]byte, 3*NSF_BANK_SZ)
+	copy(data, []byte{
+		0xAD, 0x00, 0x60, // LDA $6000
+		0x8D, 0xF0, 0xDF, // STA $DFF0
+		0xAD, 0x00, 0x70, // LDA $7000
+		0x8D, 0xF1, 0xDF, // STA $DFF1
+		0xA9, 0x02, // LDA #$02
+		0x8D, 0xF6, 0x5F, // STA $5FF6
+		0x60, // RTS
+	})
+	for i := NSF_BANK_SZ; i < len(data); i++ {
+		data[i] = 0xB0 + byte(i/NSF_BANK_SZ)
+	}
+
+	// $6000 and $7000 start with the banks for $E000 and $F000
+	player := loadFixture(t, fixture{
+		version:   1,
+		load:      0x8000,
+		init:      0x8000,
+		play:      0x8000,
+		expansion: NSF_EXPANSION_FDS,
+		banks:     [8]byte{0, 0, 0, 0, 0, 0, 1, 2},
+		data:      data,
+	})
+
+	read := player.Cpu.ReadMemory
+	if read(0xDFF0) != 0xB1 || read(0xDFF1) != 0xB2 {
+		t.Errorf("$6000 = %02X and $7000 = %02X at init, want B1 and B2", read(0xDFF0), read(0xDFF1))
+	}
+	if read(0x6000) != 0xB2 {
+		t.Errorf("$6000 = %02X after selecting bank 2 through $5FF6, want B2", read(0x6000))
+	}
+}
+
+// render plays a second of the loaded track.
+func render(t *testing.T, player *Player) {
+	t.Helper()
+
+	err := player.Render(RenderOptions{Seconds: 1}, func(frame []int16, stems [][]int16) error {
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Render(): %s", err)
+	}
+}
+
+func TestNonReturningInit(t *testing.T) {
+	code := make([]byte, 0x30)
+	copy(code, []byte{
+		0xA2, 0x05, // LDX #$05
+		0x8E, 0x03, 0x60, // STX $6003
+		0xEE, 0x02, 0x60, // INC $6002
+		0x4C, 0x02, 0x80, // JMP to the STX
+	})
+	copy(code[0x20:], []byte{
+		0xA2, 0x00, // LDX #$00
+		0xEE, 0x01, 0x60, // INC $6001
+		0x60, // RTS
+	})
+	f := fixture{version: 2, load: 0x8000, init: 0x8000, play: 0x8020, data: code}
+
+	music, err := NsfFromFile(writeFixture(t, f))
+	if err != nil {
+		t.Fatalf("NsfFromFile(): %s", err)
+	}
+	player, err := NewPlayer(music, region.NTSC, 44100)
+	if err != nil {
+		t.Fatalf("NewPlayer(): %s", err)
+	}
+	if err := player.InitTrack(1); err == nil {
+		t.Error("InitTrack() returned without an error for an init that never returns")
+	}
+
+	f.flags = NSF2_NON_RETURNING
+	player = loadFixture(t, f)
+	render(t, player)
+
+	read := player.Cpu.ReadMemory
+	if calls := read(0x6001); calls < 59 || calls > 61 {
+		t.Errorf("Play was called %d times in a second, want 60", calls)
+	}
+	if read(0x6002) == 0 {
+		t.Error("Init stopped running after the first play call")
+	}
+	if read(0x6003) != 0x05 {
+		t.Errorf("$6003 = %02X, want init's X of 05 kept across play calls", read(0x6003))
+	}
+}
+
+func TestNoPlay(t *testing.T) {
+	player := loadFixture(t, fixture{version: 2, load: 0x8000, init: 0x8000, play: 0x8020, flags: NSF2_NO_PLAY, data: toneDriver()})
+	render(t, player)
+
+	if calls := player.Cpu.ReadMemory(0x6001); calls != 0 {
+		t.Errorf("Play was called %d times, want none", calls)
+	}
+}
+
+func TestTrackOptions(t *testing.T) {
+	player := loadFixture(t, fixture{version: 1, load: 0x8000, init: 0x8000, play: 0x8020, data: toneDriver()})
+	defaults := RenderOptions{Seconds: 150, Fade: 5, Silence: 3}
+
+	tests := []struct {
+		track     Track
+		overrides Overrides
+		want      RenderOptions
+	}{
+		{Track{Length: 0, Fade: -1}, Overrides{}, RenderOptions{150, 5, 3}},
+		{Track{Length: 60, Fade: 2}, Overrides{}, RenderOptions{62, 2, 0}},
+		{Track{Length: 60, Fade: -1}, Overrides{}, RenderOptions{65, 5, 0}},
+		{Track{Length: 0, Fade: 2}, Overrides{}, RenderOptions{150, 2, 3}},
+		// Each override replaces only its own setting
+		{Track{Length: 60, Fade: 2}, Overrides{Seconds: true}, RenderOptions{150, 2, 3}},
+		{Track{Length: 60, Fade: 2}, Overrides{Fade: true}, RenderOptions{65, 5, 0}},
+		{Track{Length: 60, Fade: 2}, Overrides{Silence: true}, RenderOptions{62, 2, 3}},
+		{Track{Length: 60, Fade: 2}, Overrides{true, true, true}, RenderOptions{150, 5, 3}},
+	}
+
+	for _, test := range tests {
+		player.Nsf.Tracks = []Track{test.track}
+		if got := player.TrackOptions(defaults, test.overrides); got != test.want {
+			t.Errorf("TrackOptions() for %+v with %+v = %+v, want %+v", test.track, test.overrides, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package nsf
+
+import "math"
+
+// Samples quieter than this count as silence.
+const SILENCE_THRESHOLD int16 = 16
+
+type RenderOptions struct {
+	Seconds float64 // Length of the track, including the fade
+	Fade    float64 // Seconds to fade out over at the end
+	Silence float64 // Stop after this many seconds of silence, or 0 to play on
+}
+
+// Overrides marks the options that were set by the user, which the file's
+// metadata doesn't replace.
+type Overrides struct {
+	Seconds, Fade, Silence bool
+}
+
+// TrackOptions fills in the length and fade of the current track from the
+// file's metadata, keeping the defaults for anything it doesn't give or that
+// is overridden. Tracks with a known length play out in full rather than
+// stopping at silence, unless the silence is overridden.
+func (player *Player) TrackOptions(defaults RenderOptions, overrides Overrides) RenderOptions {
+	options := defaults
+	track := player.Track()
+
+	if track.Fade >= 0 && !overrides.Fade {
+		options.Fade = track.Fade
+	}
+	if track.Length > 0 && !overrides.Seconds {
+		options.Seconds = track.Length + options.Fade
+		if !overrides.Silence {
+			options.Silence = 0
+		}
+	}
+
+	return options
+}
+
+// Render plays the current track, passing each frame's audio to output along
+// with the APU's stems, which are cut and faded the same way. stems is empty
+// unless the APU is recording them. Render stops at the end of the fade, or
+// earlier if the track falls silent.
+func (player *Player) Render(options RenderOptions, output func(samples []int16, stems [][]int16) error) error {
+	sampleRate := float64(player.Apu.SampleRate())
+	total := int(options.Seconds * sampleRate)
+	fadeStart := total - int(options.Fade*sampleRate)
+	silenceLimit := int(options.Silence * sampleRate)
+
+	rendered, silent := 0, 0
+	for rendered < total {
+		samples, err := player.PlayFrame()
+		if err != nil {
+			return err
+		}
+		stems := player.Apu.StemSamples()
+
+		if len(samples) > total-rendered {
+			samples = samples[:total-rendered]
+			for channel := range stems {
+				stems[channel] = stems[channel][:len(samples)]
+			}
+		}
+
+		for i, sample := range samples {
+			if sample > -SILENCE_THRESHOLD && sample < SILENCE_THRESHOLD {
+				silent++
+			} else {
+				silent = 0
+			}
+
+			if position := rendered + i; position >= fadeStart {
+				gain := float64(total-position) / float64(total-fadeStart)
+				samples[i] = fadeSample(sample, gain)
+				for _, stem := range stems {
+					stem[i] = fadeSample(stem[i], gain)
+				}
+			}
+		}
+
+		err = output(samples, stems)
+		if err != nil {
+			return err
+		}
+		rendered += len(samples)
+
+		if silenceLimit > 0 && silent >= silenceLimit {
+			break
+		}
+	}
+
+	return nil
+}
+
+func fadeSample(sample int16, gain float64) int16 {
+	return int16(math.Round(float64(sample) * gain))
+}
@@ -0,0 +1,224 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/tjarjoura/nes-emulator/nsf"
+	"github.com/tjarjoura/nes-emulator/record"
+	"github.com/tjarjoura/nes-emulator/region"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// trackFilename names a track's WAV file after its number and title,
+// leaving out characters that aren't allowed in filenames.
+func trackFilename(music *nsf.Nsf, track int) string {
+	title := strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`/\:*?"<>|`, r) || r < ' ' {
+			return -1
+		}
+		return r
+	}, music.Track(track).Title)
+
+	if title == "" {
+		return fmt.Sprintf("%02d.wav", track)
+	}
+	return fmt.Sprintf("%02d %s.wav", track, title)
+}
+
+func formatSeconds(seconds float64) string {
+	total := int(seconds + 0.5)
+	return fmt.Sprintf("%d:%02d", total/60, total%60)
+}
+
+// listTracks prints the tracks in playlist order with their titles, authors
+// and lengths, marking the starting track.
+func listTracks(music *nsf.Nsf) {
+	for _, track := range music.TrackOrder() {
+		info := music.Track(track)
+
+		marker := " "
+		if track == music.StartingSong {
+			marker = "*"
+		}
+
+		length := "    ?"
+		if info.Length > 0 {
+			length = fmt.Sprintf("%5s", formatSeconds(info.Length))
+		}
+		if info.Fade >= 0 {
+			length += fmt.Sprintf(" +%.1fs", info.Fade)
+		} else {
+			length += "      "
+		}
+
+		line := fmt.Sprintf("%s%3d  %s  %s", marker, track, length, info.Title)
+		if info.Author != "" {
+			line += " (" + info.Author + ")"
+		}
+		fmt.Println(strings.TrimRight(line, " "))
+	}
+}
+
+// renderTrack writes a track to a WAV file, taking its length and fade from
+// the file's metadata where they aren't overridden. If the APU is recording
+// stems, they're written to stemsDir as <track>.<channel>.wav.
+func renderTrack(player *nsf.Player, track int, filename string, stemsDir string, defaults nsf.RenderOptions, overrides nsf.Overrides) error {
+	err := player.InitTrack(track)
+	if err != nil {
+		return err
+	}
+
+	options := player.TrackOptions(defaults, overrides)
+
+	wav, err := record.NewWavRecorder(filename, player.Apu.SampleRate())
+	if err != nil {
+		return err
+	}
+
+	var stems *stemRecorder
+	if player.Apu.Stems() {
+		prefix := strings.TrimSuffix(filepath.Base(filename), filepath.Ext(filename)) + "."
+		stems, err = newStemRecorder(stemsDir, prefix, player.Apu.Channels(), player.Apu.SampleRate())
+		if err != nil {
+			wav.Close()
+			return err
+		}
+	}
+
+	samples := 0
+	err = player.Render(options, func(frame []int16, stemFrames [][]int16) error {
+		samples += len(frame)
+		if stems != nil {
+			if err := stems.AddAudio(stemFrames); err != nil {
+				return err
+			}
+		}
+		return wav.AddAudio(frame)
+	})
+	if err != nil {
+		wav.Close()
+		if stems != nil {
+			stems.Close()
+		}
+		return err
+	}
+
+	err = wav.Close()
+	if err != nil {
+		return err
+	}
+	if stems != nil {
+		err = stems.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	fmt.Printf("Track %d: %s (%s)\n", track, filename, formatSeconds(float64(samples)/float64(player.Apu.SampleRate())))
+	return nil
+}
+
+func nsfMain(args []string) {
+	flags := flag.NewFlagSet("nsf", flag.ExitOnError)
+	output := flags.String("o", "track.wav", "WAV file to write")
+	track := flags.Int("track", 0, "track to render, from 1 (default the file's starting track)")
+	all := flags.Bool("all", false, "render every track in playlist order, named after their titles")
+	dir := flags.String("dir", ".", "directory -all writes tracks to")
+	list := flags.Bool("list", false, "list the tracks with their titles and lengths, and exit")
+	seconds := flags.Float64("seconds", 150, "length of the track in seconds, including the fade, for tracks without a known length")
+	fade := flags.Float64("fade", 5, "seconds to fade out over at the end, for tracks without a known fade")
+	silence := flags.Float64("silence", 3, "stop after this many seconds of silence, 0 to never stop early, for tracks without a known length")
+	sampleRate := flags.Int("sample-rate", 44100, "sample rate in Hz")
+	regionName := flags.String("region", "auto", "ntsc, pal, or auto to follow the file")
+	stemsDir := flags.String("stems", "", "also write each sound channel of each track to its own .wav file in this directory, ignoring -mute and -solo")
+	mute := flags.String("mute", "", "comma separated list of sound channels to leave out, e.g. \"triangle,noise\"")
+	solo := flags.String("solo", "", "comma separated list of the only sound channels to keep")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s nsf [flags] FILENAME\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Plays NSF, NSF2 and NSFe files. Setting -seconds, -fade or -silence overrides that one setting of the file's metadata.\n")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if flags.NArg() < 1 {
+		flags.Usage()
+		os.Exit(1)
+	}
+
+	if *sampleRate <= 0 {
+		log.Fatalf("-sample-rate: %d Hz isn't above 0\n", *sampleRate)
+	}
+
+	music, err := nsf.NsfFromFile(flags.Arg(0))
+	if err != nil {
+		log.Fatalf("NsfFromFile(): %s\n", err)
+	}
+
+	playerRegion := music.DefaultRegion()
+	if *regionName != "auto" {
+		playerRegion, err = region.RegionFromString(*regionName)
+		if err != nil {
+			log.Fatalf("-region: %s\n", err)
+		}
+	}
+
+	fmt.Printf("Title: %s\nArtist: %s\nCopyright: %s\n", music.Title, music.Artist, music.Copyright)
+	if music.Ripper != "" {
+		fmt.Printf("Ripper: %s\n", music.Ripper)
+	}
+	fmt.Printf("Tracks: %d\nRegion: %s\n", music.Songs, playerRegion)
+	if music.Flags&nsf.NSF2_IRQ > 0 {
+		fmt.Fprintf(os.Stderr, "Warning: The file uses the NSF2 IRQ timer at $401B-$401D, which isn't emulated, so it may not play right\n")
+	}
+
+	if *list {
+		listTracks(music)
+		return
+	}
+
+	var overrides nsf.Overrides
+	flags.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "seconds":
+			overrides.Seconds = true
+		case "fade":
+			overrides.Fade = true
+		case "silence":
+			overrides.Silence = true
+		}
+	})
+	defaults := nsf.RenderOptions{Seconds: *seconds, Fade: *fade, Silence: *silence}
+
+	tracks := []int{*track}
+	if *track == 0 {
+		tracks[0] = music.StartingSong
+	}
+	if *all {
+		tracks = music.TrackOrder()
+	}
+
+	player, err := nsf.NewPlayer(music, playerRegion, *sampleRate)
+	if err != nil {
+		log.Fatalf("NewPlayer(): %s\n", err)
+	}
+	err = setMutes(player.Apu, *mute, *solo)
+	if err != nil {
+		log.Fatalf("setMutes(): %s\n", err)
+	}
+	player.Apu.SetStems(*stemsDir != "")
+
+	for _, track := range tracks {
+		filename := *output
+		if *all {
+			filename = filepath.Join(*dir, trackFilename(music, track))
+		}
+
+		err = renderTrack(player, track, filename, *stemsDir, defaults, overrides)
+		if err != nil {
+			log.Fatalf("renderTrack(): %s\n", err)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package palette
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"os"
+)
+
+const (
+	COLORS          int = 64
+	EMPHASIS_COLORS int = 512
+)
+
+// Emphasising a channel darkens the other two by roughly this much
+const EMPHASIS_ATTENUATION float64 = 0.746
+
+// Palette maps a PPU framebuffer pixel, a 6-bit palette index with the three
+// emphasis bits above it, to an RGB colour.
+type Palette [EMPHASIS_COLORS]color.RGBA
+
+// A 2C02 palette as measured from an NTSC NES
+var builtinColors = [COLORS]uint32{
+	0x666666, 0x002A88, 0x1412A7, 0x3B00A4, 0x5C007E, 0x6E0040, 0x6C0600, 0x561D00,
+	0x333500, 0x0B4800, 0x005200, 0x004F08, 0x00404D, 0x000000, 0x000000, 0x000000,
+	0xADADAD, 0x155FD9, 0x4240FF, 0x7527FE, 0xA01ACC, 0xB71E7B, 0xB53120, 0x994E00,
+	0x6B6D00, 0x388700, 0x0C9300, 0x008F32, 0x007C8D, 0x000000, 0x000000, 0x000000,
+	0xFFFEFF, 0x64B0FF, 0x9290FF, 0xC676FF, 0xF36AFF, 0xFE6ECC, 0xFE8170, 0xEA9E22,
+	0xBCBE00, 0x88D800, 0x5CE430, 0x45E082, 0x48CDDE, 0x4F4F4F, 0x000000, 0x000000,
+	0xFFFEFF, 0xC0DFFF, 0xD3D2FF, 0xE8C8FF, 0xFBC2FF, 0xFEC4EA, 0xFECCC5, 0xF7D8A5,
+	0xE4E594, 0xCFEF96, 0xBDF4AB, 0xB3F3CC, 0xB5EBF2, 0xB8B8B8, 0x000000, 0x000000,
+}
+
+func attenuate(channel uint8, darken bool) uint8 {
+	if darken {
+		return uint8(float64(channel)*EMPHASIS_ATTENUATION + 0.5)
+	}
+	return channel
+}
+
+// emphasize derives the emphasis variants of the first 64 colours, for
+// palettes that don't provide their own.
+func (palette *Palette) emphasize() {
+	for emphasis := 1; emphasis < 8; emphasis++ {
+		red := emphasis&0x01 > 0
+		green := emphasis&0x02 > 0
+		blue := emphasis&0x04 > 0
+
+		for index := 0; index < COLORS; index++ {
+			base := palette[index]
+
+			// Columns $E and $F are black and unaffected by emphasis
+			if index%16 >= 0x0E {
+				palette[emphasis*COLORS+index] = base
+				continue
+			}
+
+			palette[emphasis*COLORS+index] = color.RGBA{
+				attenuate(base.R, green || blue),
+				attenuate(base.G, red || blue),
+				attenuate(base.B, red || green),
+				0xFF,
+			}
+		}
+	}
+}
+
+// NewPalette returns the built-in 2C02 palette.
+func NewPalette() *Palette {
+	palette := new(Palette)
+
+	for index, rgb := range builtinColors {
+		palette[index] = color.RGBA{uint8(rgb >> 16), uint8(rgb >> 8), uint8(rgb), 0xFF}
+	}
+	palette.emphasize()
+
+	return palette
+}
+
+// PaletteFromFile loads a .pal file holding either 64 colours, or all 512
+// colours with the emphasis variants in order of the emphasis bits.
+func PaletteFromFile(filename string) (*Palette, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) != COLORS*3 && len(data) != EMPHASIS_COLORS*3 {
+		return nil, fmt.Errorf("%s: Palette files must be %d or %d bytes long, not %d", filename, COLORS*3, EMPHASIS_COLORS*3, len(data))
+	}
+
+	palette := new(Palette)
+	for index := 0; index < len(data)/3; index++ {
+		palette[index] = color.RGBA{data[index*3], data[index*3+1], data[index*3+2], 0xFF}
+	}
+
+	if len(data) == COLORS*3 {
+		palette.emphasize()
+	}
+
+	return palette, nil
+}
+
+func (palette *Palette) Color(pixel uint16) color.RGBA {
+	return palette[pixel%uint16(EMPHASIS_COLORS)]
+}
+
+// Image converts a frame of PPU pixels that is width pixels wide into an
+// image.
+func (palette *Palette) Image(frame []uint16, width int) *image.RGBA {
+	height := len(frame) / width
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+
+	for i, pixel := range frame[:width*height] {
+		rgba := palette.Color(pixel)
+		copy(img.Pix[i*4:i*4+4], []uint8{rgba.R, rgba.G, rgba.B, rgba.A})
+	}
+
+	return img
+}
@@ -0,0 +1,234 @@
+package ppu
+
+import (
+	"github.com/tjarjoura/nes-emulator/region"
+	"github.com/tjarjoura/nes-emulator/types"
+)
+
+const DOTS_PER_SCANLINE int = 341
+
+type Ppu struct {
+	ctrl, mask, status, oamAddr byte
+	openBus, readBuffer         byte
+
+	// Internal scroll registers, named after loopy's documentation: v is the
+	// current VRAM address, t the temporary address, x the fine X scroll and
+	// w the shared write toggle of $2005/$2006.
+	v, t uint16
+	x    byte
+	w    bool
+
+	oam        [256]byte
+	nametables [2048]byte
+	addressBus uint16 // Last address the PPU put on its bus, for A12 edges
+	palette    [32]byte
+
+	bg               background
+	sp               spriteState
+	unlimitedSprites bool
+	framebuffer      [SCREEN_WIDTH * SCREEN_HEIGHT]uint16
+	completedFrame   [SCREEN_WIDTH * SCREEN_HEIGHT]uint16
+
+	cartridge  types.Cartridge
+	nmiHandler func()
+	region     region.Region
+	timing     *region.Timing
+
+	scanline, dot int
+	frame         uint64
+	oddFrame      bool
+}
+
+func NewPpu(cartridge types.Cartridge) *Ppu {
+	return &Ppu{cartridge: cartridge, timing: region.NTSC.Timing()}
+}
+
+// SetRegion selects the frame timing of the NTSC, PAL or Dendy PPU.
+func (ppu *Ppu) SetRegion(region region.Region) {
+	ppu.region = region
+	ppu.timing = region.Timing()
+}
+
+// The pre-render line is always the last one of the frame
+func (ppu *Ppu) prerenderScanline() int {
+	return ppu.timing.Scanlines - 1
+}
+
+// SetNmiHandler registers the function called when the PPU raises NMI at
+// the start of vertical blank.
+func (ppu *Ppu) SetNmiHandler(handler func()) {
+	ppu.nmiHandler = handler
+}
+
+// Frame returns the number of frames completed since power on.
+func (ppu *Ppu) Frame() uint64 {
+	return ppu.frame
+}
+
+func (ppu *Ppu) raiseNmi() {
+	if ppu.nmiHandler != nil {
+		ppu.nmiHandler()
+	}
+}
+
+func paletteIndex(address uint16) uint16 {
+	index := address % 32
+
+	// The backdrop entries of the sprite palettes mirror the background ones
+	if index >= 16 && index%4 == 0 {
+		index -= 16
+	}
+
+	return index
+}
+
+// nametableIndex maps an address in $2000-$2FFF, or its mirror at
+// $3000-$3EFF, to the console's nametable RAM following the cartridge's
+// mirroring.
+func (ppu *Ppu) nametableIndex(address uint16) uint16 {
+	table := (address >> 10) & 0x03
+	offset := address & 0x03FF
+
+	switch ppu.cartridge.Mirroring() {
+	case types.MIRROR_HORIZONTAL:
+		table >>= 1
+	case types.MIRROR_VERTICAL:
+		table &= 0x01
+	case types.MIRROR_SINGLE_A:
+		table = 0
+	case types.MIRROR_SINGLE_B:
+		table = 1
+	}
+
+	return (table*0x400 + offset) % 0x800
+}
+
+func (ppu *Ppu) cartridgeNametables() bool {
+	return ppu.cartridge.Mirroring() == types.MIRROR_FOUR_SCREEN
+}
+
+// setAddressBus tracks the address lines so the cartridge can see A12 rise.
+// Palette accesses stay inside the PPU and never reach the bus.
+func (ppu *Ppu) setAddressBus(address uint16) {
+	if address >= 0x3F00 {
+		return
+	}
+
+	if address&0x1000 > 0 && ppu.addressBus&0x1000 == 0 {
+		ppu.cartridge.A12Rise()
+	}
+	ppu.addressBus = address
+}
+
+// vramByte reads the PPU address space, taking cartridge data from read.
+func (ppu *Ppu) vramByte(address uint16, read func(address uint16) byte) byte {
+	address %= 0x4000
+
+	if address < 0x2000 {
+		return read(address)
+	} else if address < 0x3F00 {
+		if ppu.cartridgeNametables() {
+			return read(0x2000 | address&0x0FFF)
+		}
+		return ppu.nametables[ppu.nametableIndex(address)]
+	} else {
+		return ppu.palette[paletteIndex(address)]
+	}
+}
+
+func (ppu *Ppu) readVram(address uint16) byte {
+	ppu.setAddressBus(address % 0x4000)
+	return ppu.vramByte(address, ppu.cartridge.ReadPpu)
+}
+
+func (ppu *Ppu) writeVram(address uint16, data byte) {
+	address %= 0x4000
+	ppu.setAddressBus(address)
+
+	if address < 0x2000 {
+		ppu.cartridge.WritePpu(address, data)
+	} else if address < 0x3F00 {
+		if ppu.cartridgeNametables() {
+			ppu.cartridge.WritePpu(0x2000|address&0x0FFF, data)
+		} else {
+			ppu.nametables[ppu.nametableIndex(address)] = data
+		}
+	} else {
+		ppu.palette[paletteIndex(address)] = data & 0x3F
+	}
+}
+
+// PeekVram reads the PPU address space without the side effects of a
+// PPUDATA access or a fetch, for debuggers.
+func (ppu *Ppu) PeekVram(address uint16) byte {
+	return ppu.vramByte(address, ppu.cartridge.PeekPpu)
+}
+
+// Clock advances the PPU by a single dot.
+func (ppu *Ppu) Clock() {
+	if ppu.scanline < SCREEN_HEIGHT || ppu.scanline == ppu.prerenderScanline() {
+		ppu.renderDot()
+	}
+
+	if ppu.dot == 1 {
+		if ppu.scanline == ppu.timing.VblankScanline {
+			ppu.completedFrame = ppu.framebuffer
+			ppu.status |= STATUS_VBLANK
+			if ppu.ctrl&CTRL_NMI_ENABLE > 0 {
+				ppu.raiseNmi()
+			}
+		} else if ppu.scanline == ppu.prerenderScanline() {
+			ppu.status &^= STATUS_VBLANK | STATUS_SPRITE_ZERO_HIT | STATUS_SPRITE_OVERFLOW
+		}
+	}
+
+	ppu.dot++
+
+	// NTSC odd frames skip the last dot of the pre-render line while rendering
+	if ppu.timing.SkipOddDot && ppu.oddFrame && ppu.scanline == ppu.prerenderScanline() &&
+		ppu.dot == DOTS_PER_SCANLINE-1 && ppu.renderingEnabled() {
+		ppu.dot++
+	}
+
+	if ppu.dot == DOTS_PER_SCANLINE {
+		ppu.dot = 0
+		ppu.scanline++
+
+		if ppu.scanline == ppu.timing.Scanlines {
+			ppu.scanline = 0
+			ppu.frame++
+			ppu.oddFrame = !ppu.oddFrame
+		}
+	}
+}
+
+// Oam returns the 256 bytes of sprite memory.
+func (ppu *Ppu) Oam() []byte {
+	return ppu.oam[:]
+}
+
+// Scroll returns the top left corner of the screen within the 512x480 area
+// of the four nametables, as last set through PPUCTRL, PPUSCROLL and PPUADDR.
+func (ppu *Ppu) Scroll() (int, int) {
+	x := int(ppu.t&0x001F)*8 + int(ppu.x) + int((ppu.t>>10)&0x01)*256
+	y := int((ppu.t>>5)&0x001F)*8 + int((ppu.t>>12)&0x07) + int((ppu.t>>11)&0x01)*240
+	return x, y
+}
+
+func (ppu *Ppu) BackgroundTable() uint16 {
+	if ppu.ctrl&CTRL_BACKGROUND_TABLE > 0 {
+		return 0x1000
+	}
+	return 0x0000
+}
+
+func (ppu *Ppu) SpriteTable() uint16 {
+	if ppu.ctrl&CTRL_SPRITE_TABLE > 0 {
+		return 0x1000
+	}
+	return 0x0000
+}
+
+func (ppu *Ppu) SpriteHeight() int {
+	return ppu.spriteHeight()
+}
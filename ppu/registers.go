@@ -0,0 +1,130 @@
+package ppu
+
+import "fmt"
+
+const (
+	PPUCTRL   uint16 = 0x2000
+	PPUMASK   uint16 = 0x2001
+	PPUSTATUS uint16 = 0x2002
+	OAMADDR   uint16 = 0x2003
+	OAMDATA   uint16 = 0x2004
+	PPUSCROLL uint16 = 0x2005
+	PPUADDR   uint16 = 0x2006
+	PPUDATA   uint16 = 0x2007
+)
+
+const (
+	CTRL_NAMETABLE         byte = 0x03
+	CTRL_INCREMENT_32      byte = 0x04
+	CTRL_SPRITE_TABLE      byte = 0x08
+	CTRL_BACKGROUND_TABLE  byte = 0x10
+	CTRL_SPRITE_SIZE       byte = 0x20
+	CTRL_NMI_ENABLE        byte = 0x80
+	STATUS_SPRITE_OVERFLOW byte = 0x20
+	STATUS_SPRITE_ZERO_HIT byte = 0x40
+	STATUS_VBLANK          byte = 0x80
+)
+
+func (ppu *Ppu) incrementAddress() {
+	if ppu.ctrl&CTRL_INCREMENT_32 > 0 {
+		ppu.v += 32
+	} else {
+		ppu.v += 1
+	}
+	ppu.v &= 0x7FFF
+}
+
+// ReadByte handles CPU reads of $2000-$3FFF. The eight registers are mirrored
+// throughout that range. Write-only registers return the value left on the
+// PPU's data bus by the last register access.
+func (ppu *Ppu) ReadByte(address uint16) (byte, error) {
+	if address < 0x2000 || address > 0x3FFF {
+		return 0x00, fmt.Errorf("Ppu.ReadByte(): Unmapped memory address 0x%x.", address)
+	}
+
+	switch 0x2000 + address%8 {
+	case PPUSTATUS:
+		ppu.openBus = ppu.status | (ppu.openBus & 0x1F)
+		ppu.status &^= STATUS_VBLANK
+		ppu.w = false
+
+	case OAMDATA:
+		ppu.openBus = ppu.oam[ppu.oamAddr]
+		if ppu.oamAddr%4 == 2 {
+			// Bits 2-4 of the sprite attribute byte don't exist
+			ppu.openBus &= 0xE3
+		}
+
+	case PPUDATA:
+		address := ppu.v & 0x3FFF
+
+		if address < 0x3F00 {
+			ppu.openBus = ppu.readBuffer
+			ppu.readBuffer = ppu.readVram(address)
+		} else {
+			// Palette reads aren't buffered, but still fill the buffer with
+			// the nametable byte underneath them
+			ppu.openBus = (ppu.openBus & 0xC0) | ppu.readVram(address)
+			ppu.readBuffer = ppu.readVram(address - 0x1000)
+		}
+
+		ppu.incrementAddress()
+	}
+
+	return ppu.openBus, nil
+}
+
+// WriteByte handles CPU writes to $2000-$3FFF.
+func (ppu *Ppu) WriteByte(address uint16, data byte) error {
+	if address < 0x2000 || address > 0x3FFF {
+		return fmt.Errorf("Ppu.WriteByte(): Unmapped memory address 0x%x.", address)
+	}
+
+	ppu.openBus = data
+
+	switch 0x2000 + address%8 {
+	case PPUCTRL:
+		// Enabling NMI during vertical blank triggers one immediately
+		if ppu.ctrl&CTRL_NMI_ENABLE == 0 && data&CTRL_NMI_ENABLE > 0 && ppu.status&STATUS_VBLANK > 0 {
+			ppu.raiseNmi()
+		}
+
+		ppu.ctrl = data
+		ppu.t = (ppu.t & 0xF3FF) | uint16(data&CTRL_NAMETABLE)<<10
+
+	case PPUMASK:
+		ppu.mask = data
+
+	case OAMADDR:
+		ppu.oamAddr = data
+
+	case OAMDATA:
+		ppu.oam[ppu.oamAddr] = data
+		ppu.oamAddr++
+
+	case PPUSCROLL:
+		if !ppu.w {
+			ppu.t = (ppu.t & 0xFFE0) | uint16(data)>>3
+			ppu.x = data & 0x07
+		} else {
+			ppu.t = (ppu.t & 0x8C1F) | uint16(data&0x07)<<12 | uint16(data&0xF8)<<2
+		}
+		ppu.w = !ppu.w
+
+	case PPUADDR:
+		if !ppu.w {
+			ppu.t = (ppu.t & 0x80FF) | uint16(data&0x3F)<<8
+		} else {
+			ppu.t = (ppu.t & 0xFF00) | uint16(data)
+			ppu.v = ppu.t
+			ppu.setAddressBus(ppu.v & 0x3FFF)
+		}
+		ppu.w = !ppu.w
+
+	case PPUDATA:
+		ppu.writeVram(ppu.v&0x3FFF, data)
+		ppu.incrementAddress()
+	}
+
+	return nil
+}
@@ -0,0 +1,251 @@
+package ppu
+
+import "github.com/tjarjoura/nes-emulator/region"
+
+const (
+	SCREEN_WIDTH  int = 256
+	SCREEN_HEIGHT int = 240
+)
+
+const (
+	MASK_GREYSCALE       byte = 0x01
+	MASK_BACKGROUND_LEFT byte = 0x02
+	MASK_SPRITES_LEFT    byte = 0x04
+	MASK_BACKGROUND      byte = 0x08
+	MASK_SPRITES         byte = 0x10
+	MASK_EMPHASIS        byte = 0xE0
+)
+
+// Background fetch latches and shift registers. The shifters hold two tiles,
+// the one being drawn in the high byte and the next one in the low byte.
+type background struct {
+	nametableByte, attributeByte byte
+	patternLo, patternHi         byte
+
+	patternShiftLo, patternShiftHi     uint16
+	attributeShiftLo, attributeShiftHi uint16
+}
+
+func (ppu *Ppu) renderingEnabled() bool {
+	return ppu.mask&(MASK_BACKGROUND|MASK_SPRITES) > 0
+}
+
+// Framebuffer returns the last completed frame, row by row. Each pixel holds
+// the palette index in bits 0-5 and the PPUMASK colour emphasis bits in 6-8.
+func (ppu *Ppu) Framebuffer() []uint16 {
+	return ppu.completedFrame[:]
+}
+
+func (ppu *Ppu) incrementScrollX() {
+	if ppu.v&0x001F == 31 {
+		ppu.v &^= 0x001F
+		ppu.v ^= 0x0400 // Switch horizontal nametable
+	} else {
+		ppu.v++
+	}
+}
+
+func (ppu *Ppu) incrementScrollY() {
+	if ppu.v&0x7000 != 0x7000 {
+		ppu.v += 0x1000
+		return
+	}
+
+	ppu.v &^= 0x7000
+	coarseY := (ppu.v & 0x03E0) >> 5
+
+	if coarseY == 29 {
+		coarseY = 0
+		ppu.v ^= 0x0800 // Switch vertical nametable
+	} else if coarseY == 31 {
+		coarseY = 0 // Attribute rows wrap without switching nametables
+	} else {
+		coarseY++
+	}
+
+	ppu.v = (ppu.v &^ 0x03E0) | coarseY<<5
+}
+
+func (ppu *Ppu) transferAddressX() {
+	ppu.v = (ppu.v &^ 0x041F) | (ppu.t & 0x041F)
+}
+
+func (ppu *Ppu) transferAddressY() {
+	ppu.v = (ppu.v &^ 0x7BE0) | (ppu.t & 0x7BE0)
+}
+
+func (ppu *Ppu) fetchNametableByte() {
+	ppu.bg.nametableByte = ppu.readVram(0x2000 | (ppu.v & 0x0FFF))
+}
+
+func (ppu *Ppu) fetchAttributeByte() {
+	address := 0x23C0 | (ppu.v & 0x0C00) | ((ppu.v >> 4) & 0x38) | ((ppu.v >> 2) & 0x07)
+	attribute := ppu.readVram(address)
+
+	// Each attribute byte covers 4x4 tiles, two bits per 2x2 quadrant
+	if ppu.v&0x0040 > 0 {
+		attribute >>= 4
+	}
+	if ppu.v&0x0002 > 0 {
+		attribute >>= 2
+	}
+
+	ppu.bg.attributeByte = attribute & 0x03
+}
+
+func (ppu *Ppu) backgroundPatternAddress() uint16 {
+	var table uint16
+	if ppu.ctrl&CTRL_BACKGROUND_TABLE > 0 {
+		table = 0x1000
+	}
+
+	fineY := (ppu.v >> 12) & 0x07
+	return table + uint16(ppu.bg.nametableByte)*16 + fineY
+}
+
+func (ppu *Ppu) loadBackgroundShifters() {
+	ppu.bg.patternShiftLo = (ppu.bg.patternShiftLo & 0xFF00) | uint16(ppu.bg.patternLo)
+	ppu.bg.patternShiftHi = (ppu.bg.patternShiftHi & 0xFF00) | uint16(ppu.bg.patternHi)
+
+	// Attributes apply to whole tiles, so spread them over all eight bits
+	var attributeLo, attributeHi uint16
+	if ppu.bg.attributeByte&0x01 > 0 {
+		attributeLo = 0xFF
+	}
+	if ppu.bg.attributeByte&0x02 > 0 {
+		attributeHi = 0xFF
+	}
+	ppu.bg.attributeShiftLo = (ppu.bg.attributeShiftLo & 0xFF00) | attributeLo
+	ppu.bg.attributeShiftHi = (ppu.bg.attributeShiftHi & 0xFF00) | attributeHi
+}
+
+func (ppu *Ppu) shiftBackground() {
+	ppu.bg.patternShiftLo <<= 1
+	ppu.bg.patternShiftHi <<= 1
+	ppu.bg.attributeShiftLo <<= 1
+	ppu.bg.attributeShiftHi <<= 1
+}
+
+// backgroundPixel returns the palette (0-3) and colour (0-3) of the
+// background at the current dot, with colour 0 meaning transparent.
+func (ppu *Ppu) backgroundPixel() (byte, byte) {
+	if ppu.mask&MASK_BACKGROUND == 0 {
+		return 0, 0
+	}
+	if ppu.dot <= 8 && ppu.mask&MASK_BACKGROUND_LEFT == 0 {
+		return 0, 0
+	}
+
+	bit := uint16(0x8000) >> ppu.x
+
+	var color, palette byte
+	if ppu.bg.patternShiftLo&bit > 0 {
+		color |= 0x01
+	}
+	if ppu.bg.patternShiftHi&bit > 0 {
+		color |= 0x02
+	}
+	if ppu.bg.attributeShiftLo&bit > 0 {
+		palette |= 0x01
+	}
+	if ppu.bg.attributeShiftHi&bit > 0 {
+		palette |= 0x02
+	}
+
+	return palette, color
+}
+
+func (ppu *Ppu) renderPixel() {
+	var index byte
+
+	if ppu.renderingEnabled() {
+		palette, color := ppu.backgroundPixel()
+		spritePalette, spriteColor, behind, zero := ppu.spritePixel()
+
+		if color > 0 && spriteColor > 0 && zero && ppu.dot != 256 {
+			ppu.status |= STATUS_SPRITE_ZERO_HIT
+		}
+
+		if spriteColor > 0 && (color == 0 || !behind) {
+			palette, color = spritePalette, spriteColor
+		}
+
+		if color > 0 {
+			index = ppu.readVram(0x3F00 + uint16(palette)<<2 + uint16(color))
+		} else {
+			index = ppu.readVram(0x3F00)
+		}
+	} else if ppu.v&0x3F00 == 0x3F00 {
+		// With rendering off, pointing v at palette RAM shows that colour
+		index = ppu.readVram(ppu.v)
+	} else {
+		index = ppu.readVram(0x3F00)
+	}
+
+	if ppu.mask&MASK_GREYSCALE > 0 {
+		index &= 0x30
+	}
+
+	emphasis := ppu.mask & MASK_EMPHASIS
+	if ppu.region != region.NTSC {
+		// The PAL PPU swaps the red and green emphasis bits
+		emphasis = emphasis&0x80 | (emphasis&0x20)<<1 | (emphasis&0x40)>>1
+	}
+
+	pixel := uint16(index) | uint16(emphasis)<<1
+	ppu.framebuffer[ppu.scanline*SCREEN_WIDTH+ppu.dot-1] = pixel
+}
+
+// fetchBackground runs the background fetch pipeline for the current dot,
+// following the timing of the real PPU's memory accesses.
+func (ppu *Ppu) fetchBackground() {
+	if (ppu.dot >= 2 && ppu.dot <= 257) || (ppu.dot >= 322 && ppu.dot <= 337) {
+		ppu.shiftBackground()
+	}
+
+	if (ppu.dot >= 1 && ppu.dot <= 256) || (ppu.dot >= 321 && ppu.dot <= 336) {
+		switch ppu.dot % 8 {
+		case 1:
+			if ppu.dot >= 9 {
+				ppu.loadBackgroundShifters()
+			}
+			ppu.fetchNametableByte()
+		case 3:
+			ppu.fetchAttributeByte()
+		case 5:
+			ppu.bg.patternLo = ppu.readVram(ppu.backgroundPatternAddress())
+		case 7:
+			ppu.bg.patternHi = ppu.readVram(ppu.backgroundPatternAddress() + 8)
+		case 0:
+			ppu.incrementScrollX()
+		}
+	}
+
+	switch {
+	case ppu.dot == 256:
+		ppu.incrementScrollY()
+	case ppu.dot == 257:
+		ppu.loadBackgroundShifters()
+		ppu.transferAddressX()
+	case ppu.dot == 337:
+		ppu.loadBackgroundShifters()
+		ppu.fetchNametableByte()
+	case ppu.dot == 339:
+		ppu.fetchNametableByte()
+	case ppu.scanline == ppu.prerenderScanline() && ppu.dot >= 280 && ppu.dot <= 304:
+		ppu.transferAddressY()
+	}
+}
+
+// renderDot performs the work of the current dot on a visible or pre-render
+// scanline.
+func (ppu *Ppu) renderDot() {
+	if ppu.renderingEnabled() {
+		ppu.fetchBackground()
+		ppu.updateSprites()
+	}
+
+	if ppu.scanline < SCREEN_HEIGHT && ppu.dot >= 1 && ppu.dot <= 256 {
+		ppu.renderPixel()
+	}
+}
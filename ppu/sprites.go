@@ -0,0 +1,216 @@
+package ppu
+
+const MAX_SPRITES_PER_LINE int = 8
+
+const (
+	SPRITE_PALETTE         byte = 0x03
+	SPRITE_BEHIND          byte = 0x20
+	SPRITE_FLIP_HORIZONTAL byte = 0x40
+	SPRITE_FLIP_VERTICAL   byte = 0x80
+)
+
+type oamEntry struct {
+	y, tile, attributes, x byte
+	index                  int
+}
+
+// A sprite as loaded into the sprite output units at the end of a scanline.
+type lineSprite struct {
+	patternLo, patternHi byte
+	attributes, x        byte
+	zero                 bool
+}
+
+type spriteState struct {
+	secondary []oamEntry // Sprites found for the next scanline
+	extra     []oamEntry // Sprites past the hardware limit, when it's lifted
+	line      []lineSprite
+}
+
+// SetSpriteLimit turns the eight sprites per scanline limit on or off. With
+// it off every sprite is drawn, but evaluation, the overflow flag and the
+// fetches seen by the cartridge still behave as if the limit were there.
+func (ppu *Ppu) SetSpriteLimit(limit bool) {
+	ppu.unlimitedSprites = !limit
+}
+
+func (ppu *Ppu) spriteHeight() int {
+	if ppu.ctrl&CTRL_SPRITE_SIZE > 0 {
+		return 16
+	}
+	return 8
+}
+
+func (ppu *Ppu) spriteInRange(y byte, scanline int) bool {
+	row := scanline - int(y)
+	return row >= 0 && row < ppu.spriteHeight()
+}
+
+// evaluateSprites fills secondary OAM with the sprites that appear on the
+// line after the current one. Once eight sprites have been found the
+// hardware keeps scanning for overflow but wrongly increments both the
+// sprite and byte index on a miss, which is reproduced here.
+func (ppu *Ppu) evaluateSprites() {
+	ppu.sp.secondary = ppu.sp.secondary[:0]
+
+	n, m := 0, 0
+	for n < 64 {
+		if len(ppu.sp.secondary) < MAX_SPRITES_PER_LINE {
+			entry := ppu.oam[n*4 : n*4+4]
+			if ppu.spriteInRange(entry[0], ppu.scanline) {
+				ppu.sp.secondary = append(ppu.sp.secondary, oamEntry{entry[0], entry[1], entry[2], entry[3], n})
+			}
+			n++
+			continue
+		}
+
+		if ppu.spriteInRange(ppu.oam[n*4+m], ppu.scanline) {
+			ppu.status |= STATUS_SPRITE_OVERFLOW
+			break
+		}
+
+		n++
+		m = (m + 1) % 4
+	}
+
+	ppu.sp.extra = ppu.sp.extra[:0]
+	if ppu.unlimitedSprites {
+		found := 0
+		for n := 0; n < 64; n++ {
+			entry := ppu.oam[n*4 : n*4+4]
+			if !ppu.spriteInRange(entry[0], ppu.scanline) {
+				continue
+			}
+
+			found++
+			if found > MAX_SPRITES_PER_LINE {
+				ppu.sp.extra = append(ppu.sp.extra, oamEntry{entry[0], entry[1], entry[2], entry[3], n})
+			}
+		}
+	}
+}
+
+func reverseBits(data byte) byte {
+	data = (data&0xF0)>>4 | (data&0x0F)<<4
+	data = (data&0xCC)>>2 | (data&0x33)<<2
+	data = (data&0xAA)>>1 | (data&0x55)<<1
+	return data
+}
+
+func (ppu *Ppu) spritePatternAddress(tile byte, attributes byte, row int) uint16 {
+	if attributes&SPRITE_FLIP_VERTICAL > 0 {
+		row = ppu.spriteHeight() - 1 - row
+	}
+
+	if ppu.spriteHeight() == 8 {
+		var table uint16
+		if ppu.ctrl&CTRL_SPRITE_TABLE > 0 {
+			table = 0x1000
+		}
+		return table + uint16(tile)*16 + uint16(row)
+	}
+
+	// 8x16 sprites select their pattern table with bit 0 of the tile number
+	table := uint16(tile&0x01) * 0x1000
+	tile &= 0xFE
+	if row >= 8 {
+		tile++
+		row -= 8
+	}
+	return table + uint16(tile)*16 + uint16(row)
+}
+
+// fetchSprite loads one of the eight sprite slots during dots 257-320. Empty
+// slots still fetch tile $FF, as mappers watching the address bus rely on it.
+func (ppu *Ppu) fetchSprite(slot int) {
+	if slot >= len(ppu.sp.secondary) {
+		address := ppu.spritePatternAddress(0xFF, 0x00, 0)
+		ppu.readVram(address)
+		ppu.readVram(address + 8)
+		return
+	}
+
+	ppu.loadSprite(ppu.sp.secondary[slot], ppu.readVram)
+}
+
+func (ppu *Ppu) loadSprite(entry oamEntry, read func(address uint16) byte) {
+	address := ppu.spritePatternAddress(entry.tile, entry.attributes, ppu.scanline-int(entry.y))
+	patternLo := read(address)
+	patternHi := read(address + 8)
+
+	if entry.attributes&SPRITE_FLIP_HORIZONTAL > 0 {
+		patternLo = reverseBits(patternLo)
+		patternHi = reverseBits(patternHi)
+	}
+
+	ppu.sp.line = append(ppu.sp.line, lineSprite{patternLo, patternHi, entry.attributes, entry.x, entry.index == 0})
+}
+
+// loadExtraSprites adds the sprites beyond the hardware limit after the
+// real fetches. They're read with PeekVram so the cartridge never sees them.
+func (ppu *Ppu) loadExtraSprites() {
+	for _, entry := range ppu.sp.extra {
+		ppu.loadSprite(entry, ppu.PeekVram)
+	}
+}
+
+// spritePixel returns the palette (4-7), colour (0-3), priority and sprite
+// zero flag of the frontmost opaque sprite pixel at the current dot.
+func (ppu *Ppu) spritePixel() (byte, byte, bool, bool) {
+	if ppu.mask&MASK_SPRITES == 0 {
+		return 0, 0, false, false
+	}
+	if ppu.dot <= 8 && ppu.mask&MASK_SPRITES_LEFT == 0 {
+		return 0, 0, false, false
+	}
+
+	x := ppu.dot - 1
+	for _, sprite := range ppu.sp.line {
+		column := x - int(sprite.x)
+		if column < 0 || column > 7 {
+			continue
+		}
+
+		bit := byte(0x80) >> uint(column)
+		var color byte
+		if sprite.patternLo&bit > 0 {
+			color |= 0x01
+		}
+		if sprite.patternHi&bit > 0 {
+			color |= 0x02
+		}
+
+		if color > 0 {
+			palette := 4 + sprite.attributes&SPRITE_PALETTE
+			return palette, color, sprite.attributes&SPRITE_BEHIND > 0, sprite.zero
+		}
+	}
+
+	return 0, 0, false, false
+}
+
+// updateSprites runs sprite evaluation and fetching for the current dot.
+func (ppu *Ppu) updateSprites() {
+	if ppu.dot >= 257 && ppu.dot <= 320 {
+		ppu.oamAddr = 0
+	}
+
+	if ppu.scanline == ppu.prerenderScanline() {
+		ppu.sp.secondary = ppu.sp.secondary[:0]
+		ppu.sp.extra = ppu.sp.extra[:0]
+	} else if ppu.dot == 257 {
+		ppu.evaluateSprites()
+	}
+
+	if ppu.dot == 257 {
+		ppu.sp.line = ppu.sp.line[:0]
+	}
+
+	if ppu.dot >= 257 && ppu.dot <= 320 && (ppu.dot-257)%8 == 7 {
+		ppu.fetchSprite((ppu.dot - 257) / 8)
+	}
+
+	if ppu.dot == 320 {
+		ppu.loadExtraSprites()
+	}
+}
@@ -0,0 +1,239 @@
+package record
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"io"
+	"math"
+	"os"
+)
+
+const (
+	AVIF_HASINDEX       uint32 = 0x10
+	AVIF_ISINTERLEAVED  uint32 = 0x100
+	AVIIF_KEYFRAME      uint32 = 0x10
+	AVI_MAX_SZ          int64  = 1 << 31 // Without OpenDML extensions, players give up at 2GB
+	AVI_VIDEO_CHUNK     string = "00db"
+	AVI_AUDIO_CHUNK     string = "01wb"
+	AVI_STREAM_HEADERS  int64  = 8 + 56 + 8 + 40 + 12 // strh, strf and LIST headers of the video stream
+	AVI_AUDIO_HEADERS   int64  = 8 + 56 + 8 + 18 + 12
+	AVI_MAIN_HEADERS    int64  = 12 + 12 + 8 + 56 // RIFF, LIST hdrl and avih
+	AVI_MOVI_LIST_SZ    int64  = 12
+	AVI_INDEX_ENTRY_SZ  int64  = 16
+	AVI_BITS_PER_PIXEL  int    = 24
+	AVI_BYTES_PER_AUDIO int    = 2 // 16 bit mono
+)
+
+type aviIndexEntry struct {
+	id     string
+	offset uint32 // From the "movi" list type
+	size   uint32
+}
+
+// AviRecorder writes an AVI 1.0 file of uncompressed 24 bit frames with PCM
+// audio interleaved between them. The headers need the frame count and size,
+// so space is reserved for them and they're written by Close.
+type AviRecorder struct {
+	file       *os.File
+	w          *bufio.Writer
+	frameRate  float64
+	sampleRate int
+	width      int
+	height     int
+
+	moviStart int64
+	size      int64
+	index     []aviIndexEntry
+	frames    uint32
+	samples   uint32
+}
+
+func NewAviRecorder(filename string, frameRate float64, sampleRate int) (*AviRecorder, error) {
+	file, err := os.Create(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	recorder := &AviRecorder{file: file, w: bufio.NewWriter(file), frameRate: frameRate, sampleRate: sampleRate}
+
+	recorder.moviStart = AVI_MAIN_HEADERS + AVI_STREAM_HEADERS
+	if sampleRate > 0 {
+		recorder.moviStart += AVI_AUDIO_HEADERS
+	}
+
+	// Placeholder headers, then the start of the "movi" list
+	_, err = recorder.w.Write(make([]byte, recorder.moviStart+AVI_MOVI_LIST_SZ))
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	recorder.size = recorder.moviStart + AVI_MOVI_LIST_SZ
+
+	return recorder, nil
+}
+
+func (recorder *AviRecorder) frameSize() int {
+	stride := (recorder.width*AVI_BITS_PER_PIXEL/8 + 3) &^ 3
+	return stride * recorder.height
+}
+
+func (recorder *AviRecorder) writeChunk(id string, data []byte) error {
+	if recorder.size+8+int64(len(data)) > AVI_MAX_SZ {
+		return fmt.Errorf("AviRecorder: Recording is larger than %d bytes.", AVI_MAX_SZ)
+	}
+
+	header := make([]byte, 8)
+	copy(header, id)
+	binary.LittleEndian.PutUint32(header[4:], uint32(len(data)))
+
+	offset := recorder.size - recorder.moviStart - 8
+	recorder.index = append(recorder.index, aviIndexEntry{id, uint32(offset), uint32(len(data))})
+
+	_, err := recorder.w.Write(header)
+	if err != nil {
+		return err
+	}
+	_, err = recorder.w.Write(data)
+	if err != nil {
+		return err
+	}
+
+	recorder.size += 8 + int64(len(data))
+	if len(data)%2 == 1 {
+		recorder.size++
+		return recorder.w.WriteByte(0) // Chunks are padded to an even length
+	}
+	return nil
+}
+
+func (recorder *AviRecorder) AddFrame(img *image.RGBA) error {
+	bounds := img.Bounds()
+
+	if recorder.width == 0 {
+		recorder.width, recorder.height = bounds.Dx(), bounds.Dy()
+	} else if bounds.Dx() != recorder.width || bounds.Dy() != recorder.height {
+		return fmt.Errorf("AviRecorder.AddFrame(): Frame size changed from %dx%d to %dx%d.",
+			recorder.width, recorder.height, bounds.Dx(), bounds.Dy())
+	}
+
+	// Bottom-up rows of BGR pixels, each row padded to four bytes
+	data := make([]byte, recorder.frameSize())
+	stride := len(data) / recorder.height
+	for y := 0; y < recorder.height; y++ {
+		row := data[(recorder.height-1-y)*stride:]
+		for x := 0; x < recorder.width; x++ {
+			offset := img.PixOffset(bounds.Min.X+x, bounds.Min.Y+y)
+			row[x*3], row[x*3+1], row[x*3+2] = img.Pix[offset+2], img.Pix[offset+1], img.Pix[offset]
+		}
+	}
+
+	recorder.frames++
+	return recorder.writeChunk(AVI_VIDEO_CHUNK, data)
+}
+
+func (recorder *AviRecorder) AddAudio(samples []int16) error {
+	if recorder.sampleRate == 0 || len(samples) == 0 {
+		return nil
+	}
+
+	data := make([]byte, len(samples)*AVI_BYTES_PER_AUDIO)
+	for i, sample := range samples {
+		binary.LittleEndian.PutUint16(data[i*2:], uint16(sample))
+	}
+
+	recorder.samples += uint32(len(samples))
+	return recorder.writeChunk(AVI_AUDIO_CHUNK, data)
+}
+
+func writeLe(w io.Writer, values ...interface{}) {
+	for _, value := range values {
+		binary.Write(w, binary.LittleEndian, value)
+	}
+}
+
+func (recorder *AviRecorder) headers() []byte {
+	var buf bytes.Buffer
+
+	streams := uint32(1)
+	if recorder.sampleRate > 0 {
+		streams = 2
+	}
+
+	rate := uint32(math.Round(recorder.frameRate * 1000))
+	microsPerFrame := uint32(math.Round(1e6 / recorder.frameRate))
+	frameSize := uint32(recorder.frameSize())
+	bytesPerSecond := uint32(float64(frameSize)*recorder.frameRate) + uint32(recorder.sampleRate*AVI_BYTES_PER_AUDIO)
+	indexSize := int64(len(recorder.index)) * AVI_INDEX_ENTRY_SZ
+
+	buf.WriteString("RIFF")
+	writeLe(&buf, uint32(recorder.size+8+indexSize-8))
+	buf.WriteString("AVI LIST")
+	writeLe(&buf, uint32(recorder.moviStart-20))
+	buf.WriteString("hdrlavih")
+	writeLe(&buf, uint32(56), microsPerFrame, bytesPerSecond, uint32(0), AVIF_HASINDEX|AVIF_ISINTERLEAVED,
+		recorder.frames, uint32(0), streams, frameSize, uint32(recorder.width), uint32(recorder.height), [4]uint32{})
+
+	buf.WriteString("LIST")
+	writeLe(&buf, uint32(AVI_STREAM_HEADERS-8))
+	buf.WriteString("strlstrh")
+	writeLe(&buf, uint32(56))
+	buf.WriteString("vidsDIB ")
+	writeLe(&buf, uint32(0), uint16(0), uint16(0), uint32(0), uint32(1000), rate, uint32(0), recorder.frames,
+		frameSize, int32(-1), uint32(0), [4]int16{0, 0, int16(recorder.width), int16(recorder.height)})
+	buf.WriteString("strf")
+	writeLe(&buf, uint32(40), uint32(40), int32(recorder.width), int32(recorder.height), uint16(1),
+		uint16(AVI_BITS_PER_PIXEL), uint32(0), frameSize, [4]uint32{})
+
+	if recorder.sampleRate > 0 {
+		blockAlign := uint32(AVI_BYTES_PER_AUDIO)
+		buf.WriteString("LIST")
+		writeLe(&buf, uint32(AVI_AUDIO_HEADERS-8))
+		buf.WriteString("strlstrh")
+		writeLe(&buf, uint32(56))
+		buf.WriteString("auds")
+		writeLe(&buf, uint32(0), uint32(0), uint16(0), uint16(0), uint32(0), blockAlign,
+			uint32(recorder.sampleRate)*blockAlign, uint32(0), recorder.samples, uint32(recorder.sampleRate)*blockAlign,
+			int32(-1), blockAlign, [4]int16{})
+		buf.WriteString("strf")
+		writeLe(&buf, uint32(18), uint16(1), uint16(1), uint32(recorder.sampleRate),
+			uint32(recorder.sampleRate)*blockAlign, uint16(blockAlign), uint16(16), uint16(0))
+	}
+
+	buf.WriteString("LIST")
+	writeLe(&buf, uint32(recorder.size-recorder.moviStart-8))
+	buf.WriteString("movi")
+
+	return buf.Bytes()
+}
+
+func (recorder *AviRecorder) writeIndex() error {
+	var buf bytes.Buffer
+
+	buf.WriteString("idx1")
+	writeLe(&buf, uint32(int64(len(recorder.index))*AVI_INDEX_ENTRY_SZ))
+	for _, entry := range recorder.index {
+		buf.WriteString(entry.id)
+		writeLe(&buf, AVIIF_KEYFRAME, entry.offset, entry.size)
+	}
+
+	_, err := recorder.w.Write(buf.Bytes())
+	return err
+}
+
+func (recorder *AviRecorder) Close() error {
+	err := recorder.writeIndex()
+	if err == nil {
+		err = recorder.w.Flush()
+	}
+	if err == nil {
+		_, err = recorder.file.WriteAt(recorder.headers(), 0)
+	}
+
+	if closeErr := recorder.file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
@@ -0,0 +1,115 @@
+package record
+
+import (
+	"encoding/binary"
+	"image"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type aviChunk struct {
+	id     string
+	offset int // Of the chunk's data
+	size   int
+}
+
+// aviChunks lists the chunks in data[start:end], without descending into
+// lists.
+func aviChunks(t *testing.T, data []byte, start int, end int) []aviChunk {
+	var chunks []aviChunk
+	for offset := start; offset < end; {
+		if offset+8 > end {
+			t.Fatalf("Chunk header at %d runs past %d", offset, end)
+		}
+		size := int(binary.LittleEndian.Uint32(data[offset+4:]))
+		chunks = append(chunks, aviChunk{string(data[offset : offset+4]), offset + 8, size})
+		offset += 8 + size + size%2
+		if offset > end {
+			t.Fatalf("%s chunk of %d bytes runs past %d", chunks[len(chunks)-1].id, size, end)
+		}
+	}
+	return chunks
+}
+
+func TestAviChunks(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "video.avi")
+	recorder, err := NewAviRecorder(filename, 60, 44100)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Rows of 3 pixels are padded from 9 bytes to 12
+	img := image.NewRGBA(image.Rect(0, 0, 3, 2))
+	img.Pix[0], img.Pix[1], img.Pix[2] = 0x11, 0x22, 0x33
+	for frame := 0; frame < 2; frame++ {
+		if err := recorder.AddFrame(img); err != nil {
+			t.Fatal(err)
+		}
+		if err := recorder.AddAudio(make([]int16, 735+frame)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := recorder.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	riff := aviChunks(t, data, 0, len(data))
+	if len(riff) != 1 || riff[0].id != "RIFF" || string(data[8:12]) != "AVI " {
+		t.Fatalf("File holds %v, want a single RIFF AVI chunk", riff)
+	}
+
+	top := aviChunks(t, data, 12, len(data))
+	if len(top) != 3 || top[0].id != "LIST" || top[1].id != "LIST" || top[2].id != "idx1" {
+		t.Fatalf("RIFF holds %v, want hdrl and movi lists then idx1", top)
+	}
+	if got := string(data[top[0].offset : top[0].offset+4]); got != "hdrl" {
+		t.Errorf("First list is %q, want hdrl", got)
+	}
+
+	if frames := binary.LittleEndian.Uint32(data[top[0].offset+4+8+16:]); frames != 2 {
+		t.Errorf("avih has %d frames, want 2", frames)
+	}
+
+	moviStart := top[1].offset
+	if got := string(data[moviStart : moviStart+4]); got != "movi" {
+		t.Fatalf("Second list is %q, want movi", got)
+	}
+	movi := aviChunks(t, data, moviStart+4, moviStart+top[1].size)
+
+	want := []aviChunk{{"00db", 0, 24}, {"01wb", 0, 1470}, {"00db", 0, 24}, {"01wb", 0, 1472}}
+	if len(movi) != len(want) {
+		t.Fatalf("movi holds %v, want %d chunks", movi, len(want))
+	}
+	for i := range want {
+		if movi[i].id != want[i].id || movi[i].size != want[i].size {
+			t.Errorf("movi chunk %d is %s of %d bytes, want %s of %d", i, movi[i].id, movi[i].size, want[i].id, want[i].size)
+		}
+	}
+
+	// Bottom-up BGR, so the top left pixel starts the second row
+	if got := data[movi[0].offset+12 : movi[0].offset+15]; got[0] != 0x33 || got[1] != 0x22 || got[2] != 0x11 {
+		t.Errorf("Top left pixel = % X, want 33 22 11", got)
+	}
+
+	index := data[top[2].offset : top[2].offset+top[2].size]
+	if len(index) != len(movi)*16 {
+		t.Fatalf("idx1 is %d bytes, want %d", len(index), len(movi)*16)
+	}
+	for i, chunk := range movi {
+		entry := index[i*16:]
+		id := string(entry[:4])
+		offset := int(binary.LittleEndian.Uint32(entry[8:]))
+		size := int(binary.LittleEndian.Uint32(entry[12:]))
+
+		// Offsets count from the "movi" list type to the chunk header
+		if id != chunk.id || moviStart+offset != chunk.offset-8 || size != chunk.size {
+			t.Errorf("idx1 entry %d is %s at %d of %d bytes, want %s at %d of %d", i, id, offset, size, chunk.id, chunk.offset-8-moviStart, chunk.size)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package record
+
+import (
+	"image"
+	"image/color"
+	"image/color/palette"
+	"image/draw"
+	"image/gif"
+	"math"
+	"os"
+)
+
+// GifRecorder keeps every frame in memory and encodes the animation on Close,
+// as the GIF encoder needs them all at once. It's meant for short clips.
+type GifRecorder struct {
+	file      *os.File
+	frameRate float64
+	animation gif.GIF
+	frames    int
+}
+
+func NewGifRecorder(filename string, frameRate float64) (*GifRecorder, error) {
+	file, err := os.Create(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	return &GifRecorder{file: file, frameRate: frameRate}, nil
+}
+
+// quantize converts a frame to a paletted image. Unfiltered frames rarely use
+// more than a few dozen colours and keep them exactly; anything with more than
+// 256 is dithered to a fixed palette.
+func quantize(img *image.RGBA) *image.Paletted {
+	bounds := img.Bounds()
+	colors := make(map[color.RGBA]uint8)
+	var colorPalette color.Palette
+
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			pixel := img.RGBAAt(x, y)
+			if _, ok := colors[pixel]; ok {
+				continue
+			}
+			if len(colors) == 256 {
+				paletted := image.NewPaletted(bounds, palette.Plan9)
+				draw.FloydSteinberg.Draw(paletted, bounds, img, bounds.Min)
+				return paletted
+			}
+			colors[pixel] = uint8(len(colors))
+			colorPalette = append(colorPalette, pixel)
+		}
+	}
+
+	paletted := image.NewPaletted(bounds, colorPalette)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			paletted.SetColorIndex(x, y, colors[img.RGBAAt(x, y)])
+		}
+	}
+	return paletted
+}
+
+func (recorder *GifRecorder) AddFrame(img *image.RGBA) error {
+	// Delays are in hundredths of a second, so spread the rounding error over
+	// the frames rather than let the clip drift
+	start := math.Round(float64(recorder.frames) * 100 / recorder.frameRate)
+	end := math.Round(float64(recorder.frames+1) * 100 / recorder.frameRate)
+	recorder.frames++
+
+	recorder.animation.Image = append(recorder.animation.Image, quantize(img))
+	recorder.animation.Delay = append(recorder.animation.Delay, int(end-start))
+	return nil
+}
+
+// AddAudio discards the audio, which GIF can't hold.
+func (recorder *GifRecorder) AddAudio(samples []int16) error {
+	return nil
+}
+
+func (recorder *GifRecorder) Close() error {
+	if len(recorder.animation.Image) > 0 {
+		err := gif.EncodeAll(recorder.file, &recorder.animation)
+		if err != nil {
+			recorder.file.Close()
+			return err
+		}
+	}
+
+	return recorder.file.Close()
+}
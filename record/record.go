@@ -0,0 +1,38 @@
+package record
+
+import (
+	"fmt"
+	"image"
+	"path/filepath"
+	"strings"
+)
+
+// Recorder captures a run frame by frame. Audio is 16 bit mono PCM and should
+// be added after the frame it was generated with, so that formats which
+// interleave the two keep them in step.
+type Recorder interface {
+	AddFrame(img *image.RGBA) error
+	AddAudio(samples []int16) error
+	Close() error
+}
+
+// RecorderFromFile picks the recorder for the format named by the file
+// extension: .gif, .y4m, .avi or .wav for audio alone. A sampleRate of 0
+// records no audio.
+func RecorderFromFile(filename string, frameRate float64, sampleRate int) (Recorder, error) {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".gif":
+		return NewGifRecorder(filename, frameRate)
+	case ".y4m":
+		return NewY4mRecorder(filename, frameRate, sampleRate)
+	case ".avi":
+		return NewAviRecorder(filename, frameRate, sampleRate)
+	case ".wav":
+		if sampleRate == 0 {
+			return nil, fmt.Errorf("%s: No sample rate for the audio", filename)
+		}
+		return NewWavRecorder(filename, sampleRate)
+	default:
+		return nil, fmt.Errorf("%s: Unrecognized format, expected .gif, .y4m, .avi or .wav", filename)
+	}
+}
@@ -0,0 +1,110 @@
+package record
+
+import (
+	"encoding/binary"
+	"image"
+	"io"
+	"os"
+)
+
+const WAV_HEADER_SZ int64 = 44
+
+// WavWriter writes 16 bit PCM audio to a RIFF WAVE file. The sizes in the
+// header are filled in by Close, so the output must be seekable.
+type WavWriter struct {
+	w          io.WriteSeeker
+	sampleRate int
+	channels   int
+	dataSize   int64
+}
+
+func NewWavWriter(w io.WriteSeeker, sampleRate int, channels int) (*WavWriter, error) {
+	wav := &WavWriter{w: w, sampleRate: sampleRate, channels: channels}
+	return wav, wav.writeHeader()
+}
+
+func (wav *WavWriter) writeHeader() error {
+	blockAlign := wav.channels * 2
+
+	header := make([]byte, WAV_HEADER_SZ)
+	copy(header[0:], "RIFF")
+	binary.LittleEndian.PutUint32(header[4:], uint32(WAV_HEADER_SZ-8+wav.dataSize))
+	copy(header[8:], "WAVEfmt ")
+	binary.LittleEndian.PutUint32(header[16:], 16)
+	binary.LittleEndian.PutUint16(header[20:], 1) // PCM
+	binary.LittleEndian.PutUint16(header[22:], uint16(wav.channels))
+	binary.LittleEndian.PutUint32(header[24:], uint32(wav.sampleRate))
+	binary.LittleEndian.PutUint32(header[28:], uint32(wav.sampleRate*blockAlign))
+	binary.LittleEndian.PutUint16(header[32:], uint16(blockAlign))
+	binary.LittleEndian.PutUint16(header[34:], 16)
+	copy(header[36:], "data")
+	binary.LittleEndian.PutUint32(header[40:], uint32(wav.dataSize))
+
+	_, err := wav.w.Write(header)
+	return err
+}
+
+// WriteSamples appends samples, interleaved by channel.
+func (wav *WavWriter) WriteSamples(samples []int16) error {
+	data := make([]byte, len(samples)*2)
+	for i, sample := range samples {
+		binary.LittleEndian.PutUint16(data[i*2:], uint16(sample))
+	}
+
+	_, err := wav.w.Write(data)
+	wav.dataSize += int64(len(data))
+	return err
+}
+
+// Close fills in the header sizes. It doesn't close the underlying writer.
+func (wav *WavWriter) Close() error {
+	_, err := wav.w.Seek(0, io.SeekStart)
+	if err != nil {
+		return err
+	}
+
+	err = wav.writeHeader()
+	if err != nil {
+		return err
+	}
+
+	_, err = wav.w.Seek(0, io.SeekEnd)
+	return err
+}
+
+// WavRecorder is a Recorder that keeps only the audio.
+type WavRecorder struct {
+	file *os.File
+	wav  *WavWriter
+}
+
+func NewWavRecorder(filename string, sampleRate int) (*WavRecorder, error) {
+	file, err := os.Create(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	wav, err := NewWavWriter(file, sampleRate, 1)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	return &WavRecorder{file, wav}, nil
+}
+
+func (recorder *WavRecorder) AddFrame(img *image.RGBA) error {
+	return nil
+}
+
+func (recorder *WavRecorder) AddAudio(samples []int16) error {
+	return recorder.wav.WriteSamples(samples)
+}
+
+func (recorder *WavRecorder) Close() error {
+	err := recorder.wav.Close()
+	if closeErr := recorder.file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
@@ -0,0 +1,59 @@
+package record
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWavHeader(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "audio.wav")
+	recorder, err := NewWavRecorder(filename, 48000)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, samples := range [][]int16{{1, -1, 2}, {-2, 3}} {
+		if err := recorder.AddAudio(samples); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := recorder.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data) != int(WAV_HEADER_SZ)+10 {
+		t.Fatalf("File is %d bytes, want %d", len(data), WAV_HEADER_SZ+10)
+	}
+
+	tests := []struct {
+		name   string
+		offset int
+		want   uint32
+	}{
+		{"RIFF size", 4, 36 + 10},
+		{"fmt size", 16, 16},
+		{"sample rate", 24, 48000},
+		{"byte rate", 28, 96000},
+		{"data size", 40, 10},
+	}
+	for _, test := range tests {
+		if got := binary.LittleEndian.Uint32(data[test.offset:]); got != test.want {
+			t.Errorf("%s = %d, want %d", test.name, got, test.want)
+		}
+	}
+
+	for offset, want := range map[int]string{0: "RIFF", 8: "WAVE", 12: "fmt ", 36: "data"} {
+		if got := string(data[offset : offset+4]); got != want {
+			t.Errorf("%q at %d, want %q", got, offset, want)
+		}
+	}
+	if got := int16(binary.LittleEndian.Uint16(data[WAV_HEADER_SZ+8:])); got != 3 {
+		t.Errorf("Last sample = %d, want 3", got)
+	}
+}
@@ -0,0 +1,124 @@
+package record
+
+import (
+	"bufio"
+	"fmt"
+	"image"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Y4mRecorder writes raw YUV4MPEG2 video with full resolution (4:4:4) chroma.
+// Audio goes to a WAV file of the same name alongside it, which is left out
+// when the sample rate is 0.
+type Y4mRecorder struct {
+	file      *os.File
+	w         *bufio.Writer
+	frameRate float64
+	width     int
+	height    int
+
+	wavFile *os.File
+	wav     *WavWriter
+}
+
+func NewY4mRecorder(filename string, frameRate float64, sampleRate int) (*Y4mRecorder, error) {
+	file, err := os.Create(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	recorder := &Y4mRecorder{file: file, w: bufio.NewWriter(file), frameRate: frameRate}
+
+	if sampleRate > 0 {
+		wavName := strings.TrimSuffix(filename, filepath.Ext(filename)) + ".wav"
+		recorder.wavFile, err = os.Create(wavName)
+		if err != nil {
+			file.Close()
+			return nil, err
+		}
+
+		recorder.wav, err = NewWavWriter(recorder.wavFile, sampleRate, 1)
+		if err != nil {
+			file.Close()
+			recorder.wavFile.Close()
+			return nil, err
+		}
+	}
+
+	return recorder, nil
+}
+
+// rgbToYcbcr converts to studio range BT.601, which players assume for Y4M.
+func rgbToYcbcr(r uint8, g uint8, b uint8) (uint8, uint8, uint8) {
+	rf, gf, bf := float64(r), float64(g), float64(b)
+
+	y := 16 + (65.481*rf+128.553*gf+24.966*bf)/255
+	cb := 128 + (-37.797*rf-74.203*gf+112.0*bf)/255
+	cr := 128 + (112.0*rf-93.786*gf-18.214*bf)/255
+	return uint8(y + 0.5), uint8(cb + 0.5), uint8(cr + 0.5)
+}
+
+func (recorder *Y4mRecorder) AddFrame(img *image.RGBA) error {
+	bounds := img.Bounds()
+
+	if recorder.width == 0 {
+		recorder.width, recorder.height = bounds.Dx(), bounds.Dy()
+
+		// Frame rates are given as a fraction; millihertz is precise enough
+		_, err := fmt.Fprintf(recorder.w, "YUV4MPEG2 W%d H%d F%d:1000 Ip A1:1 C444\n",
+			recorder.width, recorder.height, int(math.Round(recorder.frameRate*1000)))
+		if err != nil {
+			return err
+		}
+	} else if bounds.Dx() != recorder.width || bounds.Dy() != recorder.height {
+		return fmt.Errorf("Y4mRecorder.AddFrame(): Frame size changed from %dx%d to %dx%d.",
+			recorder.width, recorder.height, bounds.Dx(), bounds.Dy())
+	}
+
+	size := recorder.width * recorder.height
+	planes := make([]byte, size*3)
+
+	for y := 0; y < recorder.height; y++ {
+		for x := 0; x < recorder.width; x++ {
+			offset := img.PixOffset(bounds.Min.X+x, bounds.Min.Y+y)
+			i := y*recorder.width + x
+			planes[i], planes[size+i], planes[2*size+i] = rgbToYcbcr(img.Pix[offset], img.Pix[offset+1], img.Pix[offset+2])
+		}
+	}
+
+	_, err := recorder.w.WriteString("FRAME\n")
+	if err != nil {
+		return err
+	}
+
+	_, err = recorder.w.Write(planes)
+	return err
+}
+
+func (recorder *Y4mRecorder) AddAudio(samples []int16) error {
+	if recorder.wav == nil {
+		return nil
+	}
+	return recorder.wav.WriteSamples(samples)
+}
+
+func (recorder *Y4mRecorder) Close() error {
+	err := recorder.w.Flush()
+	if closeErr := recorder.file.Close(); err == nil {
+		err = closeErr
+	}
+
+	if recorder.wav != nil {
+		if wavErr := recorder.wav.Close(); err == nil {
+			err = wavErr
+		}
+		if closeErr := recorder.wavFile.Close(); err == nil {
+			err = closeErr
+		}
+	}
+
+	return err
+}
@@ -0,0 +1,48 @@
+package record
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestY4mAudioFile(t *testing.T) {
+	dir := t.TempDir()
+
+	tests := []struct {
+		filename   string
+		sampleRate int
+		wav        string
+	}{
+		{"video.y4m", 44100, "video.wav"},
+		{"upper.Y4M", 44100, "upper.wav"},
+		{"silent.y4m", 0, ""},
+	}
+	for _, test := range tests {
+		recorder, err := NewY4mRecorder(filepath.Join(dir, test.filename), 60, test.sampleRate)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := recorder.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := make(map[string]bool)
+	for _, entry := range entries {
+		files[entry.Name()] = true
+	}
+
+	for _, test := range tests {
+		if test.wav != "" && !files[test.wav] {
+			t.Errorf("%s at %d Hz wrote no %s", test.filename, test.sampleRate, test.wav)
+		}
+	}
+	if len(files) != 5 {
+		t.Errorf("Recordings wrote %d files, want 5", len(files))
+	}
+}
@@ -0,0 +1,122 @@
+package region
+
+import (
+	"fmt"
+	"strings"
+)
+
+type Region int
+
+const (
+	NTSC Region = iota
+	PAL
+	DENDY
+)
+
+// Timing holds everything that differs between the console variants.
+type Timing struct {
+	CpuClockRate float64 // In Hz
+
+	// The PPU runs Dots dots for every Cycles CPU cycles, 3 per cycle on
+	// NTSC and Dendy and 3.2 on PAL.
+	Dots, Cycles int
+
+	Scanlines      int // Including the pre-render line, which is the last
+	VblankScanline int // Where the vblank flag is set and NMI raised
+	SkipOddDot     bool
+
+	// CPU cycles at which the APU frame counter steps in 4-step and 5-step
+	// mode. The last entry of each row is where the sequence restarts.
+	FrameCounterSteps [2][6]int
+	NoisePeriods      [16]uint16
+	DmcRates          [16]uint16
+}
+
+var ntscNoisePeriods = [16]uint16{4, 8, 16, 32, 64, 96, 128, 160, 202, 254, 380, 508, 762, 1016, 2034, 4068}
+var ntscDmcRates = [16]uint16{428, 380, 340, 320, 286, 254, 226, 214, 190, 160, 142, 128, 106, 84, 72, 54}
+var ntscFrameCounterSteps = [2][6]int{
+	{7457, 14913, 22371, 29828, 29829, 29830},
+	{7457, 14913, 22371, 29829, 37281, 37282},
+}
+
+var timings = map[Region]*Timing{
+	NTSC: {
+		CpuClockRate:      1789773,
+		Dots:              3,
+		Cycles:            1,
+		Scanlines:         262,
+		VblankScanline:    241,
+		SkipOddDot:        true,
+		FrameCounterSteps: ntscFrameCounterSteps,
+		NoisePeriods:      ntscNoisePeriods,
+		DmcRates:          ntscDmcRates,
+	},
+	PAL: {
+		CpuClockRate:   1662607,
+		Dots:           16,
+		Cycles:         5,
+		Scanlines:      312,
+		VblankScanline: 241,
+		FrameCounterSteps: [2][6]int{
+			{8313, 16627, 24939, 33252, 33253, 33254},
+			{8313, 16627, 24939, 33253, 41565, 41566},
+		},
+		NoisePeriods: [16]uint16{4, 8, 14, 30, 60, 88, 118, 148, 188, 236, 354, 472, 708, 944, 1890, 3778},
+		DmcRates:     [16]uint16{398, 354, 316, 298, 276, 236, 210, 198, 176, 148, 132, 118, 98, 78, 66, 50},
+	},
+	// The Dendy pairs PAL video with a CPU clocked close to NTSC speed. Its
+	// APU keeps the NTSC periods, and vblank starts 50 lines late so that it
+	// is still 20 lines long.
+	DENDY: {
+		CpuClockRate:      1773448,
+		Dots:              3,
+		Cycles:            1,
+		Scanlines:         312,
+		VblankScanline:    291,
+		FrameCounterSteps: ntscFrameCounterSteps,
+		NoisePeriods:      ntscNoisePeriods,
+		DmcRates:          ntscDmcRates,
+	},
+}
+
+func (region Region) Timing() *Timing {
+	return timings[region]
+}
+
+// FrameCycles returns the number of CPU cycles in a frame, rounded to the
+// nearest cycle. Odd frames on NTSC are a third of a cycle shorter.
+func (timing *Timing) FrameCycles() int {
+	dots := 341 * timing.Scanlines * timing.Cycles
+	return (dots + timing.Dots/2) / timing.Dots
+}
+
+// FrameRate returns the number of frames per second the PPU produces.
+func (region Region) FrameRate() float64 {
+	timing := region.Timing()
+	dotsPerSecond := timing.CpuClockRate * float64(timing.Dots) / float64(timing.Cycles)
+	return dotsPerSecond / float64(341*timing.Scanlines)
+}
+
+func (region Region) String() string {
+	switch region {
+	case PAL:
+		return "PAL"
+	case DENDY:
+		return "Dendy"
+	default:
+		return "NTSC"
+	}
+}
+
+func RegionFromString(name string) (Region, error) {
+	switch strings.ToLower(name) {
+	case "ntsc":
+		return NTSC, nil
+	case "pal":
+		return PAL, nil
+	case "dendy":
+		return DENDY, nil
+	default:
+		return NTSC, fmt.Errorf("Unknown region %q", name)
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"github.com/tjarjoura/nes-emulator/apu"
+	"github.com/tjarjoura/nes-emulator/record"
+	"path/filepath"
+	"strings"
+)
+
+func splitChannels(list string) []string {
+	var channels []string
+	for _, field := range strings.Split(list, ",") {
+		if field = strings.TrimSpace(field); field != "" {
+			channels = append(channels, field)
+		}
+	}
+	return channels
+}
+
+// setMutes applies the -mute and -solo flags, both comma separated lists of
+// channels. Soloing mutes every channel that isn't listed.
+func setMutes(a *apu.Apu, mute string, solo string) error {
+	if soloed := splitChannels(solo); len(soloed) > 0 {
+		if err := a.Solo(soloed...); err != nil {
+			return fmt.Errorf("%s Channels are %s", err, strings.Join(a.Channels(), ", "))
+		}
+	}
+	for _, channel := range splitChannels(mute) {
+		if err := a.SetMuted(channel, true); err != nil {
+			return fmt.Errorf("%s Channels are %s", err, strings.Join(a.Channels(), ", "))
+		}
+	}
+
+	return nil
+}
+
+// stemRecorder writes each of the APU's channels to its own WAV file, named
+// <dir>/<prefix><channel>.wav.
+type stemRecorder struct {
+	wavs []*record.WavRecorder
+}
+
+func newStemRecorder(dir string, prefix string, channels []string, sampleRate int) (*stemRecorder, error) {
+	stems := &stemRecorder{}
+	for _, channel := range channels {
+		wav, err := record.NewWavRecorder(filepath.Join(dir, prefix+channel+".wav"), sampleRate)
+		if err != nil {
+			stems.Close()
+			return nil, err
+		}
+		stems.wavs = append(stems.wavs, wav)
+	}
+	return stems, nil
+}
+
+// AddAudio takes the samples of every channel, as returned by
+// Apu.StemSamples.
+func (stems *stemRecorder) AddAudio(samples [][]int16) error {
+	for channel, wav := range stems.wavs {
+		err := wav.AddAudio(samples[channel])
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (stems *stemRecorder) Close() error {
+	var firstErr error
+	for _, wav := range stems.wavs {
+		if err := wav.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
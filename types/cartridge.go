@@ -0,0 +1,22 @@
+package types
+
+type Cartridge interface {
+	MappedHardware
+	PpuBus
+
+	// PrgOffset translates a CPU address into an offset into PRG ROM using
+	// the currently selected banks. ok is false for addresses not backed by
+	// PRG ROM.
+	PrgOffset(address uint16) (offset int, ok bool)
+
+	// ExpansionAudio returns the board's sound chip, or nil for boards
+	// without one.
+	ExpansionAudio() ExpansionAudio
+
+	// Clock advances the board by a single CPU cycle, for boards with
+	// their own timers.
+	Clock()
+
+	// Irq reports whether the board is holding the CPU's IRQ line low.
+	Irq() bool
+}
@@ -0,0 +1,20 @@
+package types
+
+// ExpansionAudio is a sound chip on the cartridge. The console clocks it
+// along with the APU and mixes its output in.
+type ExpansionAudio interface {
+	// Register reads and writes, passed on by the board. Writes to
+	// addresses that aren't the chip's registers are ignored, so boards can
+	// pass on every write to a range that decodes loosely.
+	MappedHardware
+
+	// Clock advances the chip by a single CPU cycle.
+	Clock()
+
+	// Channels names the chip's channels, for muting them one at a time.
+	Channels() []string
+
+	// ChannelOutput returns a channel's level on the same scale as the APU's
+	// mixer. The chip's output is the sum of its channels.
+	ChannelOutput(channel int) float64
+}
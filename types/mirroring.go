@@ -0,0 +1,30 @@
+package types
+
+// Mirroring describes how the four logical nametables at PPU $2000-$2FFF map
+// onto the console's 2KB of nametable RAM.
+type Mirroring int
+
+const (
+	MIRROR_HORIZONTAL  Mirroring = iota // $2000=$2400, $2800=$2C00; for vertical scrolling
+	MIRROR_VERTICAL                     // $2000=$2800, $2400=$2C00; for horizontal scrolling
+	MIRROR_SINGLE_A                     // Every nametable shows the first 1KB
+	MIRROR_SINGLE_B                     // Every nametable shows the second 1KB
+	MIRROR_FOUR_SCREEN                  // The cartridge adds 2KB so all four are distinct
+)
+
+func (mirroring Mirroring) String() string {
+	switch mirroring {
+	case MIRROR_HORIZONTAL:
+		return "horizontal"
+	case MIRROR_VERTICAL:
+		return "vertical"
+	case MIRROR_SINGLE_A:
+		return "single screen A"
+	case MIRROR_SINGLE_B:
+		return "single screen B"
+	case MIRROR_FOUR_SCREEN:
+		return "four screen"
+	default:
+		return "unknown"
+	}
+}
@@ -0,0 +1,22 @@
+package types
+
+// PpuBus is the cartridge's side of the PPU address bus. The PPU keeps its
+// own 2KB of nametable RAM and the palette, and only asks the cartridge for
+// pattern tables at $0000-$1FFF and, when Mirroring is MIRROR_FOUR_SCREEN,
+// for nametables at $2000-$2FFF.
+type PpuBus interface {
+	ReadPpu(address uint16) byte
+	WritePpu(address uint16, data byte)
+
+	// PeekPpu reads like ReadPpu but without the side effects mappers may
+	// attach to fetches, for debuggers.
+	PeekPpu(address uint16) byte
+
+	// Mirroring returns the current nametable layout, which some mappers
+	// switch at runtime.
+	Mirroring() Mirroring
+
+	// A12Rise is called whenever PPU address line 12 goes from low to high,
+	// which mappers such as MMC3 use to count scanlines.
+	A12Rise()
+}